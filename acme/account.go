@@ -0,0 +1,85 @@
+package acme
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// NewAccount registers a new account with the acme server, or returns the existing account if the key is already registered
+func (c *Client) NewAccount(key crypto.Signer, contact []string, termsOfServiceAgreed bool) (Account, error) {
+	req := struct {
+		Contact              []string `json:"contact,omitempty"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed,omitempty"`
+	}{
+		Contact:              contact,
+		TermsOfServiceAgreed: termsOfServiceAgreed,
+	}
+	return c.newAccount(key, req)
+}
+
+// FetchAccount looks up the existing account for a key, returning an error if no account exists
+func (c *Client) FetchAccount(key crypto.Signer) (Account, error) {
+	req := struct {
+		OnlyReturnExisting bool `json:"onlyReturnExisting"`
+	}{
+		OnlyReturnExisting: true,
+	}
+	return c.newAccount(key, req)
+}
+
+func (c *Client) newAccount(key crypto.Signer, req interface{}) (Account, error) {
+	if key == nil {
+		return Account{}, errors.New("no account key provided")
+	}
+	var acct Account
+	resp, _, err := c.post(key, "", c.Directory.NewAccount, req, &acct, http.StatusOK, http.StatusCreated)
+	if err != nil {
+		return Account{}, fmt.Errorf("error creating account: %w", err)
+	}
+	acct.URL = resp.Header.Get("Location")
+	if acct.URL == "" {
+		return Account{}, errors.New("no account url returned from server")
+	}
+	acct.PrivateKey = key
+	return acct, nil
+}
+
+// UpdateAccount updates the contacts for an existing account
+func (c *Client) UpdateAccount(acct Account, contact []string) (Account, error) {
+	req := struct {
+		Contact []string `json:"contact"`
+	}{
+		Contact: contact,
+	}
+	if req.Contact == nil {
+		// an empty list removes all contacts from the account
+		req.Contact = []string{}
+	}
+	return c.updateAccount(acct, req)
+}
+
+// DeactivateAccount deactivates an account, as per https://tools.ietf.org/html/rfc8555#section-7.3.6
+// A deactivated account can no longer be used
+func (c *Client) DeactivateAccount(acct Account) (Account, error) {
+	req := struct {
+		Status string `json:"status"`
+	}{
+		Status: StatusDeactivated,
+	}
+	return c.updateAccount(acct, req)
+}
+
+func (c *Client) updateAccount(acct Account, req interface{}) (Account, error) {
+	if acct.URL == "" {
+		return Account{}, errors.New("no account url")
+	}
+	var updated Account
+	if _, _, err := c.post(acct.PrivateKey, acct.URL, acct.URL, req, &updated, http.StatusOK); err != nil {
+		return Account{}, fmt.Errorf("error updating account: %w", err)
+	}
+	updated.URL = acct.URL
+	updated.PrivateKey = acct.PrivateKey
+	return updated, nil
+}
@@ -0,0 +1,48 @@
+package acme
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// FetchAuthorization fetches the current state of an authorization
+func (c *Client) FetchAuthorization(acct Account, url string) (Authorization, error) {
+	var authz Authorization
+	if _, _, err := c.postAsGet(acct, url, &authz, http.StatusOK); err != nil {
+		return Authorization{}, fmt.Errorf("error fetching authorization %s: %w", url, err)
+	}
+	authz.URL = url
+	return authz, nil
+}
+
+// UpdateChallenge tells the acme server the challenge is ready to be validated,
+// as per https://tools.ietf.org/html/rfc8555#section-7.5.1
+func (c *Client) UpdateChallenge(acct Account, chal Challenge) (Challenge, error) {
+	var updated Challenge
+	if _, _, err := c.post(acct.PrivateKey, acct.URL, chal.URL, struct{}{}, &updated, http.StatusOK); err != nil {
+		return Challenge{}, fmt.Errorf("error updating challenge %s: %w", chal.URL, err)
+	}
+	return updated, nil
+}
+
+// WaitAuthorization polls an authorization until it is no longer pending
+// An error is returned if the authorization did not become valid
+func (c *Client) WaitAuthorization(acct Account, url string) (Authorization, error) {
+	var authz Authorization
+	if err := c.poll(acct, url, &authz, func() bool {
+		return authz.Status != StatusPending
+	}); err != nil {
+		return authz, fmt.Errorf("error waiting for authorization %s: %w", url, err)
+	}
+	authz.URL = url
+
+	if authz.Status != StatusValid {
+		for _, chal := range authz.Challenges {
+			if chal.Error != nil {
+				return authz, fmt.Errorf("authorization for %s is %s: %w", authz.Identifier.Value, authz.Status, *chal.Error)
+			}
+		}
+		return authz, fmt.Errorf("authorization for %s is %s", authz.Identifier.Value, authz.Status)
+	}
+	return authz, nil
+}
@@ -0,0 +1,242 @@
+package acme
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eggsampler/certgot/log"
+)
+
+const (
+	contentTypeJOSE    = "application/jose+json"
+	contentTypeProblem = "application/problem+json"
+
+	defaultPollInterval = 3 * time.Second
+	defaultPollTimeout  = 90 * time.Second
+)
+
+// Client is an acme client for a single acme server
+type Client struct {
+	// Directory is the directory fetched from the acme server when creating the client
+	Directory Directory
+
+	// HTTPClient is used to make all requests to the acme server
+	HTTPClient *http.Client
+
+	// UserAgent is sent with every request to the acme server
+	UserAgent string
+
+	// PollInterval is how long to wait between polling an order or authorization, if the server doesn't specify
+	PollInterval time.Duration
+
+	// PollTimeout is how long to poll an order or authorization before giving up
+	PollTimeout time.Duration
+
+	nonces   []string
+	noncesMu sync.Mutex
+}
+
+// NewClient creates a new acme client and fetches the directory from the provided url
+// If httpClient is nil, http.DefaultClient is used
+func NewClient(directoryURL string, httpClient *http.Client) (*Client, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	c := &Client{
+		HTTPClient:   httpClient,
+		UserAgent:    "certgot",
+		PollInterval: defaultPollInterval,
+		PollTimeout:  defaultPollTimeout,
+	}
+
+	resp, body, err := c.get(directoryURL, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching directory %s: %w", directoryURL, err)
+	}
+	c.saveNonce(resp)
+	if err := json.Unmarshal(body, &c.Directory); err != nil {
+		return nil, fmt.Errorf("error parsing directory %s: %v", directoryURL, err)
+	}
+	if c.Directory.NewNonce == "" || c.Directory.NewAccount == "" || c.Directory.NewOrder == "" {
+		return nil, fmt.Errorf("directory %s is missing required endpoints", directoryURL)
+	}
+	c.Directory.URL = directoryURL
+
+	return c, nil
+}
+
+func (c *Client) saveNonce(resp *http.Response) {
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return
+	}
+	c.noncesMu.Lock()
+	c.nonces = append(c.nonces, nonce)
+	c.noncesMu.Unlock()
+}
+
+func (c *Client) nonce() (string, error) {
+	c.noncesMu.Lock()
+	if len(c.nonces) > 0 {
+		nonce := c.nonces[len(c.nonces)-1]
+		c.nonces = c.nonces[:len(c.nonces)-1]
+		c.noncesMu.Unlock()
+		return nonce, nil
+	}
+	c.noncesMu.Unlock()
+
+	req, err := http.NewRequest(http.MethodHead, c.Directory.NewNonce, nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating nonce request: %v", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching nonce: %v", err)
+	}
+	_ = resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("no nonce returned from server")
+	}
+	return nonce, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	log.WithFields("method", req.Method, "url", req.URL).Trace("acme request")
+	return c.HTTPClient.Do(req)
+}
+
+func (c *Client) get(url string, expectedStatus ...int) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating request: %v", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return readResponse(resp, expectedStatus)
+}
+
+// post makes a signed request with the provided key, using the account url as the key id if provided,
+// retrying once if the server returns a bad nonce error
+// If out is not nil, the response body is decoded into it
+func (c *Client) post(key crypto.Signer, kid, url string, payload, out interface{}, expectedStatus ...int) (*http.Response, []byte, error) {
+	resp, body, err := c.postOnce(key, kid, url, payload, expectedStatus)
+	var prob Problem
+	if errors.As(err, &prob) && prob.Type == ProblemBadNonce {
+		log.WithField("url", url).Debug("retrying request after bad nonce")
+		resp, body, err = c.postOnce(key, kid, url, payload, expectedStatus)
+	}
+	if err != nil {
+		return resp, body, err
+	}
+	if out != nil {
+		if err := json.Unmarshal(body, out); err != nil {
+			return resp, body, fmt.Errorf("error parsing response from %s: %v", url, err)
+		}
+	}
+	return resp, body, nil
+}
+
+func (c *Client) postOnce(key crypto.Signer, kid, url string, payload interface{}, expectedStatus []int) (*http.Response, []byte, error) {
+	nonce, err := c.nonce()
+	if err != nil {
+		return nil, nil, err
+	}
+	msg, err := signJWS(key, kid, nonce, url, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", contentTypeJOSE)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.saveNonce(resp)
+	return readResponse(resp, expectedStatus)
+}
+
+// postAsGet fetches a resource with an empty signed payload, as per https://tools.ietf.org/html/rfc8555#section-6.3
+func (c *Client) postAsGet(acct Account, url string, out interface{}, expectedStatus ...int) (*http.Response, []byte, error) {
+	return c.post(acct.PrivateKey, acct.URL, url, nil, out, expectedStatus...)
+}
+
+// readResponse reads and closes the response body
+// If the response status code isn't one of the expected codes, an error is returned, preferring an acme Problem
+func readResponse(resp *http.Response, expectedStatus []int) (*http.Response, []byte, error) {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	for _, s := range expectedStatus {
+		if resp.StatusCode == s {
+			return resp, body, nil
+		}
+	}
+	if len(expectedStatus) == 0 && resp.StatusCode < 400 {
+		return resp, body, nil
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), contentTypeProblem) {
+		var prob Problem
+		if err := json.Unmarshal(body, &prob); err == nil {
+			if prob.Status == 0 {
+				prob.Status = resp.StatusCode
+			}
+			return resp, body, prob
+		}
+	}
+	return resp, body, fmt.Errorf("unexpected status code %d from %s: %s",
+		resp.StatusCode, resp.Request.URL, strings.TrimSpace(string(body)))
+}
+
+// retryAfter returns how long the server asked the client to wait before polling again, or the client default
+func (c *Client) retryAfter(resp *http.Response) time.Duration {
+	if resp != nil {
+		if s := resp.Header.Get("Retry-After"); s != "" {
+			if secs, err := strconv.Atoi(s); err == nil && secs >= 0 {
+				return time.Duration(secs) * time.Second
+			}
+			if t, err := http.ParseTime(s); err == nil {
+				return time.Until(t)
+			}
+		}
+	}
+	return c.PollInterval
+}
+
+// poll fetches a resource until the done function returns true, or the poll timeout is exceeded
+func (c *Client) poll(acct Account, url string, out interface{}, done func() bool) error {
+	deadline := time.Now().Add(c.PollTimeout)
+	for {
+		resp, _, err := c.postAsGet(acct, url, out, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		if done() {
+			return nil
+		}
+		wait := c.retryAfter(resp)
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("timed out polling %s", url)
+		}
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,330 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, ts *testServer) *Client {
+	c, err := NewClient(ts.DirectoryURL(), ts.Client())
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	c.PollInterval = time.Millisecond
+	c.PollTimeout = time.Second
+	return c
+}
+
+func newTestAccount(t *testing.T, c *Client, key crypto.Signer) Account {
+	if key == nil {
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("error generating account key: %v", err)
+		}
+	}
+	acct, err := c.NewAccount(key, []string{"mailto:hello@example.com"}, true)
+	if err != nil {
+		t.Fatalf("error creating account: %v", err)
+	}
+	return acct
+}
+
+func TestNewClient(t *testing.T) {
+	ts := newTestServer(t)
+
+	c := newTestClient(t, ts)
+	if c.Directory.NewOrder != ts.URL+"/order" {
+		t.Errorf("unexpected new order url: %s", c.Directory.NewOrder)
+	}
+	if c.Directory.Meta.TermsOfService != ts.URL+"/terms" {
+		t.Errorf("unexpected terms of service: %s", c.Directory.Meta.TermsOfService)
+	}
+	if c.Directory.URL != ts.DirectoryURL() {
+		t.Errorf("unexpected directory url: %s", c.Directory.URL)
+	}
+
+	if _, err := NewClient(ts.URL+"/nope", ts.Client()); err == nil {
+		t.Errorf("expected error fetching invalid directory")
+	}
+}
+
+func TestClient_NewAccount(t *testing.T) {
+	ts := newTestServer(t)
+	c := newTestClient(t, ts)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []crypto.Signer{rsaKey, p384Key} {
+		acct := newTestAccount(t, c, key)
+		if acct.URL == "" || acct.Status != StatusValid {
+			t.Fatalf("unexpected account: %+v", acct)
+		}
+
+		existing, err := c.FetchAccount(key)
+		if err != nil {
+			t.Fatalf("error fetching existing account: %v", err)
+		}
+		if existing.URL != acct.URL {
+			t.Errorf("expected account url %s, got: %s", acct.URL, existing.URL)
+		}
+	}
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = c.FetchAccount(otherKey)
+	var prob Problem
+	if !errors.As(err, &prob) || prob.Type != ProblemAccountDoesNotExist {
+		t.Errorf("expected account does not exist problem, got: %v", err)
+	}
+
+	if _, err := c.NewAccount(otherKey, nil, false); err == nil {
+		t.Errorf("expected error creating account without agreeing to terms of service")
+	}
+}
+
+func TestClient_UpdateAccount(t *testing.T) {
+	ts := newTestServer(t)
+	c := newTestClient(t, ts)
+	acct := newTestAccount(t, c, nil)
+
+	updated, err := c.UpdateAccount(acct, []string{"mailto:world@example.com"})
+	if err != nil {
+		t.Fatalf("error updating account: %v", err)
+	}
+	if !reflect.DeepEqual(updated.Contact, []string{"mailto:world@example.com"}) {
+		t.Errorf("unexpected contacts: %+v", updated.Contact)
+	}
+	if updated.URL != acct.URL || updated.PrivateKey != acct.PrivateKey {
+		t.Errorf("account url and key not preserved")
+	}
+
+	deactivated, err := c.DeactivateAccount(acct)
+	if err != nil {
+		t.Fatalf("error deactivating account: %v", err)
+	}
+	if deactivated.Status != StatusDeactivated {
+		t.Errorf("expected deactivated account, got: %s", deactivated.Status)
+	}
+	if _, err := c.NewOrder(acct, []Identifier{{Type: IdentifierDNS, Value: "example.com"}}); err == nil {
+		t.Errorf("expected error using deactivated account")
+	}
+}
+
+func TestClient_badNonce(t *testing.T) {
+	ts := newTestServer(t)
+	c := newTestClient(t, ts)
+
+	// a single bad nonce is retried
+	ts.badNonces = 1
+	newTestAccount(t, c, nil)
+
+	// but not more than once
+	ts.badNonces = 2
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err := c.NewAccount(key, nil, true)
+	var prob Problem
+	if !errors.As(err, &prob) || prob.Type != ProblemBadNonce {
+		t.Errorf("expected bad nonce problem, got: %v", err)
+	}
+}
+
+func issue(t *testing.T, c *Client, acct Account, names ...string) (Order, error) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return issueWithKey(t, c, acct, key, names...)
+}
+
+func issueWithKey(t *testing.T, c *Client, acct Account, key crypto.Signer, names ...string) (Order, error) {
+	var idents []Identifier
+	for _, n := range names {
+		idents = append(idents, Identifier{Type: IdentifierDNS, Value: n})
+	}
+	order, err := c.NewOrder(acct, idents)
+	if err != nil {
+		return order, err
+	}
+	for _, authzURL := range order.Authorizations {
+		authz, err := c.FetchAuthorization(acct, authzURL)
+		if err != nil {
+			return order, err
+		}
+		chal, ok := authz.Challenge(ChallengeTypeHTTP01)
+		if !ok {
+			t.Fatalf("no http-01 challenge in authorization: %+v", authz)
+		}
+		if _, err := c.UpdateChallenge(acct, chal); err != nil {
+			return order, err
+		}
+		if _, err := c.WaitAuthorization(acct, authzURL); err != nil {
+			return order, err
+		}
+	}
+
+	csrDer, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: names[0]},
+		DNSNames: names,
+	}, key)
+	if err != nil {
+		t.Fatalf("error creating csr: %v", err)
+	}
+	csr, _ := x509.ParseCertificateRequest(csrDer)
+
+	return c.FinalizeOrder(acct, order, csr)
+}
+
+func TestClient_issue(t *testing.T) {
+	ts := newTestServer(t)
+	ts.processing = 2
+	c := newTestClient(t, ts)
+	acct := newTestAccount(t, c, nil)
+
+	order, err := issue(t, c, acct, "example.com", "www.example.com")
+	if err != nil {
+		t.Fatalf("error issuing: %v", err)
+	}
+	if order.Status != StatusValid || order.Certificate == "" {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+
+	fetched, err := c.FetchOrder(acct, order.URL)
+	if err != nil {
+		t.Fatalf("error fetching order: %v", err)
+	}
+	if fetched.Certificate != order.Certificate {
+		t.Errorf("expected certificate %s, got: %s", order.Certificate, fetched.Certificate)
+	}
+
+	certs, err := c.FetchCertificates(acct, order.Certificate)
+	if err != nil {
+		t.Fatalf("error fetching certificates: %v", err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 certificates, got: %d", len(certs))
+	}
+	if !reflect.DeepEqual(certs[0].DNSNames, []string{"example.com", "www.example.com"}) {
+		t.Errorf("unexpected certificate names: %+v", certs[0].DNSNames)
+	}
+	if err := certs[0].CheckSignatureFrom(certs[1]); err != nil {
+		t.Errorf("certificate not signed by chain: %v", err)
+	}
+}
+
+func TestClient_invalidChallenge(t *testing.T) {
+	ts := newTestServer(t)
+	ts.validate = func(authz Authorization, chal Challenge) *Problem {
+		return &Problem{
+			Type:   ProblemUnauthorized,
+			Detail: "invalid response from " + authz.Identifier.Value,
+		}
+	}
+	c := newTestClient(t, ts)
+	acct := newTestAccount(t, c, nil)
+
+	_, err := issue(t, c, acct, "example.com")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	var prob Problem
+	if !errors.As(err, &prob) || prob.Type != ProblemUnauthorized {
+		t.Errorf("expected unauthorized problem, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "invalid response from example.com") {
+		t.Errorf("expected problem detail in error, got: %v", err)
+	}
+}
+
+func TestClient_RevokeCertificate(t *testing.T) {
+	ts := newTestServer(t)
+	c := newTestClient(t, ts)
+	acct := newTestAccount(t, c, nil)
+
+	revoke := func(key crypto.Signer, useAccount bool) error {
+		order, err := issueWithKey(t, c, acct, key, "example.com")
+		if err != nil {
+			t.Fatalf("error issuing: %v", err)
+		}
+		certs, err := c.FetchCertificates(acct, order.Certificate)
+		if err != nil {
+			t.Fatalf("error fetching certificates: %v", err)
+		}
+		if useAccount {
+			err = c.RevokeCertificate(acct, certs[0], ReasonKeyCompromise)
+		} else {
+			err = c.RevokeCertificateWithKey(key, certs[0], ReasonSuperseded)
+		}
+		if err != nil {
+			return err
+		}
+		if ts.revoked[certs[0].SerialNumber.String()] == ReasonUnspecified {
+			t.Errorf("expected revocation reason to be set")
+		}
+		// revoking again fails
+		err = c.RevokeCertificate(acct, certs[0], ReasonUnspecified)
+		var prob Problem
+		if !errors.As(err, &prob) || prob.Type != ProblemAlreadyRevoked {
+			t.Errorf("expected already revoked problem, got: %v", err)
+		}
+		return nil
+	}
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err := revoke(ecKey, true); err != nil {
+		t.Errorf("error revoking with account: %v", err)
+	}
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if err := revoke(rsaKey, false); err != nil {
+		t.Errorf("error revoking with certificate key: %v", err)
+	}
+}
+
+func TestProblem_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		prob Problem
+		want string
+	}{
+		{
+			name: "simple",
+			prob: Problem{Type: ProblemMalformed, Detail: "bad"},
+			want: "acme: malformed: bad",
+		},
+		{
+			name: "subproblems",
+			prob: Problem{
+				Type:   problemPrefix + "rejectedIdentifier",
+				Detail: "some identifiers rejected",
+				Subproblems: []Subproblem{
+					{
+						Type:       problemPrefix + "rejectedIdentifier",
+						Detail:     "blocked",
+						Identifier: &Identifier{Type: IdentifierDNS, Value: "example.org"},
+					},
+				},
+			},
+			want: "acme: rejectedIdentifier: some identifiers rejected; rejectedIdentifier (example.org): blocked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.prob.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// JWK is a json web key, as per https://tools.ietf.org/html/rfc7517
+// Only the public key parts of rsa and ecdsa keys are included
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	E   string `json:"e,omitempty"`
+	N   string `json:"n,omitempty"`
+}
+
+type jwsHeader struct {
+	Alg   string `json:"alg"`
+	Nonce string `json:"nonce,omitempty"`
+	URL   string `json:"url"`
+	JWK   *JWK   `json:"jwk,omitempty"`
+	KID   string `json:"kid,omitempty"`
+}
+
+type jwsMessage struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// NewJWK returns the json web key for the public part of the provided key
+func NewJWK(pub crypto.PublicKey) (*JWK, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return &JWK{
+			Kty: "RSA",
+			E:   b64(big.NewInt(int64(k.E)).Bytes()),
+			N:   b64(k.N.Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return &JWK{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   b64(padBytes(k.X.Bytes(), size)),
+			Y:   b64(padBytes(k.Y.Bytes(), size)),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %T", pub)
+}
+
+// Thumbprint returns the jwk thumbprint, as per https://tools.ietf.org/html/rfc7638
+func (jwk JWK) Thumbprint() string {
+	// the members must be in lexicographic order with no whitespace
+	var s string
+	switch jwk.Kty {
+	case "RSA":
+		s = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk.E, jwk.Kty, jwk.N)
+	default:
+		s = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, jwk.Crv, jwk.Kty, jwk.X, jwk.Y)
+	}
+	sum := sha256.Sum256([]byte(s))
+	return b64(sum[:])
+}
+
+// KeyAuthorization returns the key authorization for a challenge token signed by the account key,
+// as per https://tools.ietf.org/html/rfc8555#section-8.1
+func (acct Account) KeyAuthorization(token string) (string, error) {
+	if acct.PrivateKey == nil {
+		return "", errors.New("no account private key")
+	}
+	jwk, err := NewJWK(acct.PrivateKey.Public())
+	if err != nil {
+		return "", err
+	}
+	return token + "." + jwk.Thumbprint(), nil
+}
+
+// DNS01Value returns the value of the TXT record to be provisioned for a dns-01 key authorization
+func DNS01Value(keyAuth string) string {
+	sum := sha256.Sum256([]byte(keyAuth))
+	return b64(sum[:])
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+func signingAlg(key crypto.Signer) (string, crypto.Hash, error) {
+	switch k := key.Public().(type) {
+	case *rsa.PublicKey:
+		return "RS256", crypto.SHA256, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return "ES256", crypto.SHA256, nil
+		case 384:
+			return "ES384", crypto.SHA384, nil
+		case 521:
+			return "ES512", crypto.SHA512, nil
+		}
+		return "", 0, fmt.Errorf("unsupported curve: %s", k.Curve.Params().Name)
+	}
+	return "", 0, fmt.Errorf("unsupported key type: %T", key.Public())
+}
+
+// signJWS signs a payload with the provided key
+// If kid is empty, the public key will be embedded in the protected header as a jwk instead
+// A nil payload results in an empty payload being signed, ie a POST-as-GET request
+func signJWS(key crypto.Signer, kid, nonce, url string, payload interface{}) ([]byte, error) {
+	alg, hash, err := signingAlg(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := jwsHeader{
+		Alg:   alg,
+		Nonce: nonce,
+		URL:   url,
+		KID:   kid,
+	}
+	if kid == "" {
+		header.JWK, err = NewJWK(key.Public())
+		if err != nil {
+			return nil, err
+		}
+	}
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding jws header: %v", err)
+	}
+
+	var payloadStr string
+	if payload != nil {
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding jws payload: %v", err)
+		}
+		payloadStr = b64(payloadBytes)
+	}
+
+	msg := jwsMessage{
+		Protected: b64(headerBytes),
+		Payload:   payloadStr,
+	}
+
+	h := hash.New()
+	_, _ = h.Write([]byte(msg.Protected + "." + msg.Payload))
+	digest := h.Sum(nil)
+
+	sig, err := key.Sign(rand.Reader, digest, hash)
+	if err != nil {
+		return nil, fmt.Errorf("error signing jws: %v", err)
+	}
+
+	// ecdsa signatures are asn.1 encoded, but jws requires the raw r and s values concatenated
+	if pub, ok := key.Public().(*ecdsa.PublicKey); ok {
+		sig, err = rawECDSASignature(sig, pub)
+		if err != nil {
+			return nil, err
+		}
+	}
+	msg.Signature = b64(sig)
+
+	return json.Marshal(msg)
+}
+
+func rawECDSASignature(sig []byte, pub *ecdsa.PublicKey) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(sig, &parsed); err != nil {
+		return nil, fmt.Errorf("error decoding ecdsa signature: %v", err)
+	}
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	return append(padBytes(parsed.R.Bytes(), size), padBytes(parsed.S.Bytes(), size)...), nil
+}
@@ -0,0 +1,110 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestJWK_Thumbprint(t *testing.T) {
+	// example from https://tools.ietf.org/html/rfc7638#section-3.1
+	n := "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw"
+	jwk := JWK{Kty: "RSA", E: "AQAB", N: n}
+	want := "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+	if got := jwk.Thumbprint(); got != want {
+		t.Errorf("Thumbprint() = %v, want %v", got, want)
+	}
+}
+
+func TestNewJWK(t *testing.T) {
+	// an ecdsa key with a small x coordinate should still be padded to the full curve size
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.PublicKey.X = big.NewInt(1)
+	jwk, err := NewJWK(k.Public())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+	if len(x) != 32 {
+		t.Errorf("expected 32 byte x coordinate, got: %d", len(x))
+	}
+	if jwk.Crv != "P-256" || jwk.Kty != "EC" {
+		t.Errorf("unexpected jwk: %+v", jwk)
+	}
+
+	if _, err := NewJWK("nope"); err == nil {
+		t.Errorf("expected error for unsupported key")
+	}
+}
+
+func Test_signJWS(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		alg     string
+		kid     string
+		payload interface{}
+	}{
+		{name: "ecdsa jwk", key: ecKey, alg: "ES512", payload: map[string]string{"hello": "world"}},
+		{name: "rsa kid", key: rsaKey, alg: "RS256", kid: "http://example.com/acct/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgBytes, err := signJWS(tt.key, tt.kid, "nonce", "http://example.com", tt.payload)
+			if err != nil {
+				t.Fatalf("error signing: %v", err)
+			}
+			var msg jwsMessage
+			if err := json.Unmarshal(msgBytes, &msg); err != nil {
+				t.Fatalf("error decoding jws: %v", err)
+			}
+			headerBytes, _ := base64.RawURLEncoding.DecodeString(msg.Protected)
+			var header jwsHeader
+			_ = json.Unmarshal(headerBytes, &header)
+			if header.Alg != tt.alg || header.Nonce != "nonce" || header.URL != "http://example.com" {
+				t.Errorf("unexpected header: %s", headerBytes)
+			}
+			if (tt.kid == "") != (header.JWK != nil) || header.KID != tt.kid {
+				t.Errorf("expected exactly one of jwk or kid: %s", headerBytes)
+			}
+			if tt.payload == nil && msg.Payload != "" {
+				t.Errorf("expected empty payload, got: %s", msg.Payload)
+			}
+			if err := verifySignature(tt.key.Public(), header.Alg, msg.Protected+"."+msg.Payload, msg.Signature); err != nil {
+				t.Errorf("bad signature: %v", err)
+			}
+		})
+	}
+}
+
+func TestAccount_KeyAuthorization(t *testing.T) {
+	if _, err := (Account{}).KeyAuthorization("token"); err == nil {
+		t.Errorf("expected error with no private key")
+	}
+
+	k, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keyAuth, err := Account{PrivateKey: k}.KeyAuthorization("token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	jwk, _ := NewJWK(k.Public())
+	if keyAuth != "token."+jwk.Thumbprint() {
+		t.Errorf("unexpected key authorization: %s", keyAuth)
+	}
+	if v := DNS01Value(keyAuth); strings.ContainsAny(v, "=+/") || len(v) != 43 {
+		t.Errorf("unexpected dns-01 value: %s", v)
+	}
+}
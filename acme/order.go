@@ -0,0 +1,103 @@
+package acme
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// NewOrder creates a new order for the provided identifiers
+func (c *Client) NewOrder(acct Account, identifiers []Identifier) (Order, error) {
+	if len(identifiers) == 0 {
+		return Order{}, errors.New("no identifiers provided for order")
+	}
+	req := struct {
+		Identifiers []Identifier `json:"identifiers"`
+	}{
+		Identifiers: identifiers,
+	}
+	var order Order
+	resp, _, err := c.post(acct.PrivateKey, acct.URL, c.Directory.NewOrder, req, &order, http.StatusCreated)
+	if err != nil {
+		return Order{}, fmt.Errorf("error creating order: %w", err)
+	}
+	order.URL = resp.Header.Get("Location")
+	return order, nil
+}
+
+// FetchOrder fetches the current state of an order
+func (c *Client) FetchOrder(acct Account, url string) (Order, error) {
+	var order Order
+	if _, _, err := c.postAsGet(acct, url, &order, http.StatusOK); err != nil {
+		return Order{}, fmt.Errorf("error fetching order %s: %w", url, err)
+	}
+	order.URL = url
+	return order, nil
+}
+
+// FinalizeOrder finalizes a ready order with a certificate signing request,
+// then polls the order until the certificate has been issued
+func (c *Client) FinalizeOrder(acct Account, order Order, csr *x509.CertificateRequest) (Order, error) {
+	if csr == nil {
+		return Order{}, errors.New("no csr provided")
+	}
+	req := struct {
+		CSR string `json:"csr"`
+	}{
+		CSR: b64(csr.Raw),
+	}
+	url := order.URL
+	if _, _, err := c.post(acct.PrivateKey, acct.URL, order.Finalize, req, &order, http.StatusOK); err != nil {
+		return Order{}, fmt.Errorf("error finalizing order %s: %w", url, err)
+	}
+	order.URL = url
+
+	if err := c.poll(acct, url, &order, func() bool {
+		return order.Status != StatusProcessing && order.Status != StatusReady
+	}); err != nil {
+		return order, fmt.Errorf("error waiting for order %s: %w", url, err)
+	}
+	order.URL = url
+
+	if order.Status != StatusValid {
+		if order.Error != nil {
+			return order, fmt.Errorf("order %s is %s: %w", url, order.Status, *order.Error)
+		}
+		return order, fmt.Errorf("order %s is %s", url, order.Status)
+	}
+	return order, nil
+}
+
+// FetchCertificates downloads the certificate chain for an issued order
+// The first certificate is the leaf certificate, any following certificates are the issuer chain
+func (c *Client) FetchCertificates(acct Account, url string) ([]*x509.Certificate, error) {
+	if url == "" {
+		return nil, errors.New("no certificate url")
+	}
+	_, body, err := c.postAsGet(acct, url, nil, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching certificate %s: %w", url, err)
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, body = pem.Decode(body)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected pem block in certificate chain: %s", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing certificate %s: %v", url, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificates returned from %s", url)
+	}
+	return certs, nil
+}
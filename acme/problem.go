@@ -0,0 +1,45 @@
+package acme
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	problemPrefix = "urn:ietf:params:acme:error:"
+
+	ProblemBadNonce            = problemPrefix + "badNonce"
+	ProblemAccountDoesNotExist = problemPrefix + "accountDoesNotExist"
+	ProblemAlreadyRevoked      = problemPrefix + "alreadyRevoked"
+	ProblemMalformed           = problemPrefix + "malformed"
+	ProblemUnauthorized        = problemPrefix + "unauthorized"
+)
+
+// Problem is an error returned from an acme server, as per https://tools.ietf.org/html/rfc7807
+type Problem struct {
+	Type        string       `json:"type"`
+	Detail      string       `json:"detail,omitempty"`
+	Status      int          `json:"status,omitempty"`
+	Instance    string       `json:"instance,omitempty"`
+	Identifier  *Identifier  `json:"identifier,omitempty"`
+	Subproblems []Subproblem `json:"subproblems,omitempty"`
+}
+
+// Subproblem is a problem relating to a specific identifier, as per https://tools.ietf.org/html/rfc8555#section-6.7.1
+type Subproblem struct {
+	Type       string      `json:"type"`
+	Detail     string      `json:"detail,omitempty"`
+	Identifier *Identifier `json:"identifier,omitempty"`
+}
+
+func (p Problem) Error() string {
+	s := fmt.Sprintf("acme: %s: %s", strings.TrimPrefix(p.Type, problemPrefix), p.Detail)
+	for _, sub := range p.Subproblems {
+		s += fmt.Sprintf("; %s", strings.TrimPrefix(sub.Type, problemPrefix))
+		if sub.Identifier != nil {
+			s += fmt.Sprintf(" (%s)", sub.Identifier.Value)
+		}
+		s += ": " + sub.Detail
+	}
+	return s
+}
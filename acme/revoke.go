@@ -0,0 +1,57 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Revocation reason codes, as per https://tools.ietf.org/html/rfc5280#section-5.3.1
+// Only the reasons accepted by acme servers like Let's Encrypt are included
+const (
+	ReasonUnspecified          = 0
+	ReasonKeyCompromise        = 1
+	ReasonAffiliationChanged   = 3
+	ReasonSuperseded           = 4
+	ReasonCessationOfOperation = 5
+)
+
+// RevokeCertificate revokes a certificate using an account that issued it, or holds authorizations for all of its
+// identifiers, as per https://tools.ietf.org/html/rfc8555#section-7.6
+func (c *Client) RevokeCertificate(acct Account, cert *x509.Certificate, reason int) error {
+	if acct.URL == "" {
+		return errors.New("no account url")
+	}
+	return c.revokeCertificate(acct.PrivateKey, acct.URL, cert, reason)
+}
+
+// RevokeCertificateWithKey revokes a certificate by signing the request with the certificate's own private key,
+// which does not require an account, eg if the account key has been lost or compromised
+func (c *Client) RevokeCertificateWithKey(key crypto.Signer, cert *x509.Certificate, reason int) error {
+	return c.revokeCertificate(key, "", cert, reason)
+}
+
+func (c *Client) revokeCertificate(key crypto.Signer, kid string, cert *x509.Certificate, reason int) error {
+	if key == nil {
+		return errors.New("no key provided")
+	}
+	if cert == nil {
+		return errors.New("no certificate provided")
+	}
+	if c.Directory.RevokeCert == "" {
+		return errors.New("acme server does not support revocation")
+	}
+	req := struct {
+		Certificate string `json:"certificate"`
+		Reason      int    `json:"reason"`
+	}{
+		Certificate: b64(cert.Raw),
+		Reason:      reason,
+	}
+	if _, _, err := c.post(key, kid, c.Directory.RevokeCert, req, nil, http.StatusOK); err != nil {
+		return fmt.Errorf("error revoking certificate %x: %w", cert.SerialNumber, err)
+	}
+	return nil
+}
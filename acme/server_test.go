@@ -0,0 +1,644 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer is a minimal stand-in acme server, used to test the client without a real certificate authority
+// Challenges are considered valid as soon as they are updated, unless a validate func is set
+type testServer struct {
+	*httptest.Server
+
+	// validate is called when a challenge is updated, returning a problem marks the authorization invalid
+	validate func(authz Authorization, chal Challenge) *Problem
+
+	// badNonces is how many requests to reject with a bad nonce error, regardless of nonce
+	badNonces int
+
+	// processing is how many times to return a processing order after finalizing
+	processing int
+
+	mu         sync.Mutex
+	counter    int
+	nonces     map[string]bool
+	accounts   map[string]*Account
+	accountKey map[string]crypto.PublicKey
+	thumbs     map[string]string
+	orders     map[string]*Order
+	orderAcct  map[string]string
+	authzs     map[string]*Authorization
+	chalAuthz  map[string]string
+	certs      map[string][]byte
+	revoked    map[string]int
+
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ts := &testServer{
+		nonces:     map[string]bool{},
+		accounts:   map[string]*Account{},
+		accountKey: map[string]crypto.PublicKey{},
+		thumbs:     map[string]string{},
+		orders:     map[string]*Order{},
+		orderAcct:  map[string]string{},
+		authzs:     map[string]*Authorization{},
+		chalAuthz:  map[string]string{},
+		certs:      map[string][]byte{},
+		revoked:    map[string]int{},
+	}
+
+	var err error
+	ts.caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating ca key: %v", err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "certgot test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, ts.caKey.Public(), ts.caKey)
+	if err != nil {
+		t.Fatalf("error creating ca certificate: %v", err)
+	}
+	ts.caCert, _ = x509.ParseCertificate(caDer)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/directory", ts.handleDirectory)
+	mux.HandleFunc("/nonce", ts.handleNonce)
+	mux.HandleFunc("/account", ts.handleNewAccount)
+	mux.HandleFunc("/account/", ts.handleAccount)
+	mux.HandleFunc("/order", ts.handleNewOrder)
+	mux.HandleFunc("/order/", ts.handleOrder)
+	mux.HandleFunc("/finalize/", ts.handleFinalize)
+	mux.HandleFunc("/authz/", ts.handleAuthz)
+	mux.HandleFunc("/chal/", ts.handleChallenge)
+	mux.HandleFunc("/cert/", ts.handleCert)
+	mux.HandleFunc("/revoke", ts.handleRevoke)
+	ts.Server = httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func (ts *testServer) DirectoryURL() string {
+	return ts.URL + "/directory"
+}
+
+func (ts *testServer) nextID() string {
+	ts.counter++
+	return fmt.Sprintf("%d", ts.counter)
+}
+
+func (ts *testServer) newNonce(w http.ResponseWriter) {
+	ts.mu.Lock()
+	nonce := "nonce" + ts.nextID()
+	ts.nonces[nonce] = true
+	ts.mu.Unlock()
+	w.Header().Set("Replay-Nonce", nonce)
+}
+
+func (ts *testServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	ts.newNonce(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (ts *testServer) writeProblem(w http.ResponseWriter, status int, probType, detail string) {
+	ts.newNonce(w)
+	w.Header().Set("Content-Type", contentTypeProblem)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Problem{Type: probType, Detail: detail, Status: status})
+}
+
+func (ts *testServer) handleDirectory(w http.ResponseWriter, r *http.Request) {
+	d := Directory{
+		NewNonce:   ts.URL + "/nonce",
+		NewAccount: ts.URL + "/account",
+		NewOrder:   ts.URL + "/order",
+		RevokeCert: ts.URL + "/revoke",
+		KeyChange:  ts.URL + "/keychange",
+	}
+	d.Meta.TermsOfService = ts.URL + "/terms"
+	ts.writeJSON(w, http.StatusOK, d)
+}
+
+func (ts *testServer) handleNonce(w http.ResponseWriter, r *http.Request) {
+	ts.newNonce(w)
+	w.WriteHeader(http.StatusOK)
+}
+
+type testRequest struct {
+	payload    []byte
+	accountURL string
+	jwk        *JWK
+	key        crypto.PublicKey
+}
+
+// verify checks the jws signature, nonce and url of a request
+// If it returns nil, a problem has already been written to the response
+func (ts *testServer) verify(w http.ResponseWriter, r *http.Request) *testRequest {
+	if r.Method != http.MethodPost {
+		ts.writeProblem(w, http.StatusMethodNotAllowed, ProblemMalformed, "method not allowed")
+		return nil
+	}
+	if r.Header.Get("Content-Type") != contentTypeJOSE {
+		ts.writeProblem(w, http.StatusUnsupportedMediaType, ProblemMalformed, "bad content type")
+		return nil
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	var msg jwsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemMalformed, "bad jws: "+err.Error())
+		return nil
+	}
+	headerBytes, _ := base64.RawURLEncoding.DecodeString(msg.Protected)
+	var header jwsHeader
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemMalformed, "bad jws header: "+err.Error())
+		return nil
+	}
+
+	ts.mu.Lock()
+	badNonce := ts.badNonces > 0 || !ts.nonces[header.Nonce]
+	if ts.badNonces > 0 {
+		ts.badNonces--
+	}
+	delete(ts.nonces, header.Nonce)
+	req := &testRequest{jwk: header.JWK}
+	if header.KID != "" {
+		req.accountURL = header.KID
+		req.key = ts.accountKey[header.KID]
+	} else if header.JWK != nil {
+		req.key = jwkPublicKey(*header.JWK)
+		req.accountURL = ts.thumbs[header.JWK.Thumbprint()]
+	}
+	ts.mu.Unlock()
+
+	if badNonce {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemBadNonce, "bad nonce")
+		return nil
+	}
+	if header.URL != ts.URL+r.URL.Path {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemUnauthorized, "url mismatch")
+		return nil
+	}
+	if req.key == nil {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemAccountDoesNotExist, "no key")
+		return nil
+	}
+	if err := verifySignature(req.key, header.Alg, msg.Protected+"."+msg.Payload, msg.Signature); err != nil {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemMalformed, err.Error())
+		return nil
+	}
+	req.payload, _ = base64.RawURLEncoding.DecodeString(msg.Payload)
+
+	return req
+}
+
+func jwkPublicKey(jwk JWK) crypto.PublicKey {
+	dec := func(s string) *big.Int {
+		b, _ := base64.RawURLEncoding.DecodeString(s)
+		return new(big.Int).SetBytes(b)
+	}
+	switch jwk.Kty {
+	case "RSA":
+		return &rsa.PublicKey{N: dec(jwk.N), E: int(dec(jwk.E).Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: dec(jwk.X), Y: dec(jwk.Y)}
+	}
+	return nil
+}
+
+func verifySignature(pub crypto.PublicKey, alg, signed, signature string) error {
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return err
+	}
+	var h hash.Hash
+	var hashType crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		h, hashType = sha256.New(), crypto.SHA256
+	case "ES384":
+		h, hashType = sha512.New384(), crypto.SHA384
+	case "ES512":
+		h, hashType = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported alg: %s", alg)
+	}
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, hashType, digest, sig)
+	case *ecdsa.PublicKey:
+		size := len(sig) / 2
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("bad ecdsa signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key: %T", pub)
+}
+
+func (ts *testServer) handleNewAccount(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	var payload struct {
+		Contact              []string `json:"contact"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	}
+	_ = json.Unmarshal(req.payload, &payload)
+
+	ts.mu.Lock()
+	if req.accountURL != "" {
+		acct := *ts.accounts[req.accountURL]
+		ts.mu.Unlock()
+		w.Header().Set("Location", req.accountURL)
+		ts.writeJSON(w, http.StatusOK, acct)
+		return
+	}
+	if payload.OnlyReturnExisting {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusBadRequest, ProblemAccountDoesNotExist, "no account")
+		return
+	}
+	if !payload.TermsOfServiceAgreed {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusBadRequest, ProblemMalformed, "must agree to terms of service")
+		return
+	}
+	url := ts.URL + "/account/" + ts.nextID()
+	acct := &Account{
+		Status:               StatusValid,
+		Contact:              payload.Contact,
+		TermsOfServiceAgreed: true,
+	}
+	ts.accounts[url] = acct
+	ts.accountKey[url] = req.key
+	ts.thumbs[req.jwk.Thumbprint()] = url
+	ts.mu.Unlock()
+
+	w.Header().Set("Location", url)
+	ts.writeJSON(w, http.StatusCreated, acct)
+}
+
+func (ts *testServer) handleAccount(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	url := ts.URL + r.URL.Path
+	if req.accountURL != url {
+		ts.writeProblem(w, http.StatusUnauthorized, ProblemUnauthorized, "wrong account")
+		return
+	}
+	var payload struct {
+		Contact []string `json:"contact"`
+		Status  string   `json:"status"`
+	}
+	_ = json.Unmarshal(req.payload, &payload)
+
+	ts.mu.Lock()
+	acct := ts.accounts[url]
+	if payload.Contact != nil {
+		acct.Contact = payload.Contact
+	}
+	if payload.Status == StatusDeactivated {
+		acct.Status = StatusDeactivated
+		delete(ts.accountKey, url)
+	}
+	resp := *acct
+	ts.mu.Unlock()
+
+	ts.writeJSON(w, http.StatusOK, resp)
+}
+
+func (ts *testServer) handleNewOrder(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	if req.accountURL == "" {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemAccountDoesNotExist, "no account")
+		return
+	}
+	var payload struct {
+		Identifiers []Identifier `json:"identifiers"`
+	}
+	_ = json.Unmarshal(req.payload, &payload)
+
+	ts.mu.Lock()
+	id := ts.nextID()
+	order := &Order{
+		Status:      StatusPending,
+		Expires:     time.Now().Add(time.Hour).UTC(),
+		Identifiers: payload.Identifiers,
+		Finalize:    ts.URL + "/finalize/" + id,
+	}
+	for _, ident := range payload.Identifiers {
+		authzURL := ts.URL + "/authz/" + ts.nextID()
+		authz := &Authorization{
+			Identifier: ident,
+			Status:     StatusPending,
+			Expires:    order.Expires,
+		}
+		for _, typ := range []string{ChallengeTypeHTTP01, ChallengeTypeDNS01, ChallengeTypeTLSALPN01} {
+			chal := Challenge{
+				Type:   typ,
+				URL:    ts.URL + "/chal/" + ts.nextID(),
+				Status: StatusPending,
+				Token:  "token" + ts.nextID(),
+			}
+			authz.Challenges = append(authz.Challenges, chal)
+			ts.chalAuthz[chal.URL] = authzURL
+		}
+		ts.authzs[authzURL] = authz
+		order.Authorizations = append(order.Authorizations, authzURL)
+	}
+	url := ts.URL + "/order/" + id
+	ts.orders[url] = order
+	ts.orderAcct[url] = req.accountURL
+	resp := *order
+	ts.mu.Unlock()
+
+	w.Header().Set("Location", url)
+	ts.writeJSON(w, http.StatusCreated, resp)
+}
+
+// updateOrderStatus must be called with the lock held
+func (ts *testServer) updateOrderStatus(order *Order) {
+	if order.Status != StatusPending {
+		return
+	}
+	for _, authzURL := range order.Authorizations {
+		switch ts.authzs[authzURL].Status {
+		case StatusInvalid:
+			order.Status = StatusInvalid
+			return
+		case StatusPending:
+			return
+		}
+	}
+	order.Status = StatusReady
+}
+
+func (ts *testServer) handleOrder(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	url := ts.URL + r.URL.Path
+	ts.mu.Lock()
+	order, ok := ts.orders[url]
+	if !ok || ts.orderAcct[url] != req.accountURL {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusNotFound, ProblemMalformed, "no order")
+		return
+	}
+	ts.updateOrderStatus(order)
+	if order.Status == StatusProcessing {
+		if ts.processing > 0 {
+			ts.processing--
+		} else {
+			order.Status = StatusValid
+		}
+	}
+	resp := *order
+	ts.mu.Unlock()
+
+	if resp.Status == StatusProcessing {
+		w.Header().Set("Retry-After", "0")
+	}
+	ts.writeJSON(w, http.StatusOK, resp)
+}
+
+func (ts *testServer) handleAuthz(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	ts.mu.Lock()
+	authz, ok := ts.authzs[ts.URL+r.URL.Path]
+	if !ok {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusNotFound, ProblemMalformed, "no authz")
+		return
+	}
+	resp := *authz
+	ts.mu.Unlock()
+	ts.writeJSON(w, http.StatusOK, resp)
+}
+
+func (ts *testServer) handleChallenge(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	url := ts.URL + r.URL.Path
+	ts.mu.Lock()
+	authzURL, ok := ts.chalAuthz[url]
+	if !ok {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusNotFound, ProblemMalformed, "no challenge")
+		return
+	}
+	authz := ts.authzs[authzURL]
+	var chal *Challenge
+	for i := range authz.Challenges {
+		if authz.Challenges[i].URL == url {
+			chal = &authz.Challenges[i]
+		}
+	}
+	validate := ts.validate
+	authzCopy, chalCopy := *authz, *chal
+	ts.mu.Unlock()
+
+	var prob *Problem
+	if validate != nil {
+		prob = validate(authzCopy, chalCopy)
+	}
+
+	ts.mu.Lock()
+	if prob != nil {
+		chal.Status = StatusInvalid
+		chal.Error = prob
+		authz.Status = StatusInvalid
+	} else {
+		chal.Status = StatusValid
+		authz.Status = StatusValid
+	}
+	resp := *chal
+	ts.mu.Unlock()
+
+	ts.writeJSON(w, http.StatusOK, resp)
+}
+
+func (ts *testServer) handleFinalize(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	url := ts.URL + "/order/" + strings.TrimPrefix(r.URL.Path, "/finalize/")
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	_ = json.Unmarshal(req.payload, &payload)
+	csrDer, _ := base64.RawURLEncoding.DecodeString(payload.CSR)
+	csr, err := x509.ParseCertificateRequest(csrDer)
+	if err != nil {
+		ts.writeProblem(w, http.StatusBadRequest, problemPrefix+"badCSR", err.Error())
+		return
+	}
+
+	ts.mu.Lock()
+	order, ok := ts.orders[url]
+	if !ok {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusNotFound, ProblemMalformed, "no order")
+		return
+	}
+	ts.updateOrderStatus(order)
+	if order.Status != StatusReady {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusForbidden, problemPrefix+"orderNotReady", "order not ready")
+		return
+	}
+	var names []string
+	for _, ident := range order.Identifiers {
+		names = append(names, ident.Value)
+	}
+	if strings.Join(names, ",") != strings.Join(csr.DNSNames, ",") {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusBadRequest, problemPrefix+"badCSR", "csr names do not match order")
+		return
+	}
+
+	id := ts.nextID()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(ts.counter) + 100),
+		Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ts.caCert, csr.PublicKey, ts.caKey)
+	if err != nil {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusInternalServerError, problemPrefix+"serverInternal", err.Error())
+		return
+	}
+	certURL := ts.URL + "/cert/" + id
+	ts.certs[certURL] = append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.caCert.Raw})...)
+	order.Status = StatusProcessing
+	order.Certificate = certURL
+	resp := *order
+	ts.mu.Unlock()
+
+	w.Header().Set("Location", url)
+	ts.writeJSON(w, http.StatusOK, resp)
+}
+
+func (ts *testServer) handleCert(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	ts.mu.Lock()
+	chain, ok := ts.certs[ts.URL+r.URL.Path]
+	ts.mu.Unlock()
+	if !ok {
+		ts.writeProblem(w, http.StatusNotFound, ProblemMalformed, "no certificate")
+		return
+	}
+	ts.newNonce(w)
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	_, _ = w.Write(chain)
+}
+
+// handleRevoke revokes a certificate issued by the server, when signed by any account or the certificate key
+func (ts *testServer) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	req := ts.verify(w, r)
+	if req == nil {
+		return
+	}
+	var payload struct {
+		Certificate string `json:"certificate"`
+		Reason      int    `json:"reason"`
+	}
+	_ = json.Unmarshal(req.payload, &payload)
+	der, _ := base64.RawURLEncoding.DecodeString(payload.Certificate)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		ts.writeProblem(w, http.StatusBadRequest, ProblemMalformed, err.Error())
+		return
+	}
+	if err := cert.CheckSignatureFrom(ts.caCert); err != nil {
+		ts.writeProblem(w, http.StatusNotFound, ProblemMalformed, "certificate not issued by this server")
+		return
+	}
+	if pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); req.accountURL == "" && !(ok && pub.Equal(req.key)) {
+		ts.writeProblem(w, http.StatusForbidden, ProblemUnauthorized, "not signed by account or certificate key")
+		return
+	}
+
+	ts.mu.Lock()
+	serial := cert.SerialNumber.String()
+	if _, ok := ts.revoked[serial]; ok {
+		ts.mu.Unlock()
+		ts.writeProblem(w, http.StatusBadRequest, ProblemAlreadyRevoked, "already revoked")
+		return
+	}
+	ts.revoked[serial] = payload.Reason
+	ts.mu.Unlock()
+
+	ts.newNonce(w)
+	w.WriteHeader(http.StatusOK)
+}
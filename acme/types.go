@@ -0,0 +1,112 @@
+package acme
+
+import (
+	"crypto"
+	"time"
+)
+
+const (
+	StatusPending     = "pending"
+	StatusProcessing  = "processing"
+	StatusReady       = "ready"
+	StatusValid       = "valid"
+	StatusInvalid     = "invalid"
+	StatusDeactivated = "deactivated"
+	StatusRevoked     = "revoked"
+	StatusExpired     = "expired"
+
+	ChallengeTypeHTTP01    = "http-01"
+	ChallengeTypeDNS01     = "dns-01"
+	ChallengeTypeTLSALPN01 = "tls-alpn-01"
+
+	IdentifierDNS = "dns"
+	IdentifierIP  = "ip"
+)
+
+// Directory is the acme directory object, as per https://tools.ietf.org/html/rfc8555#section-7.1.1
+type Directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+	NewAuthz   string `json:"newAuthz"`
+	RevokeCert string `json:"revokeCert"`
+	KeyChange  string `json:"keyChange"`
+
+	Meta struct {
+		TermsOfService          string   `json:"termsOfService"`
+		Website                 string   `json:"website"`
+		CAAIdentities           []string `json:"caaIdentities"`
+		ExternalAccountRequired bool     `json:"externalAccountRequired"`
+	} `json:"meta"`
+
+	// URL is the url the directory was fetched from
+	URL string `json:"-"`
+}
+
+// Account is an acme account object, as per https://tools.ietf.org/html/rfc8555#section-7.1.2
+type Account struct {
+	Status               string   `json:"status,omitempty"`
+	Contact              []string `json:"contact,omitempty"`
+	TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed,omitempty"`
+	Orders               string   `json:"orders,omitempty"`
+
+	// URL is the account url (ie, the key id or "kid") returned in the location header when creating the account
+	URL string `json:"-"`
+
+	// PrivateKey is the key the account was created with and is used to sign all requests for the account
+	PrivateKey crypto.Signer `json:"-"`
+}
+
+// Identifier is an acme identifier object, typically a dns name
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Order is an acme order object, as per https://tools.ietf.org/html/rfc8555#section-7.1.3
+type Order struct {
+	Status         string       `json:"status"`
+	Expires        time.Time    `json:"expires"`
+	Identifiers    []Identifier `json:"identifiers"`
+	NotBefore      time.Time    `json:"notBefore"`
+	NotAfter       time.Time    `json:"notAfter"`
+	Error          *Problem     `json:"error,omitempty"`
+	Authorizations []string     `json:"authorizations"`
+	Finalize       string       `json:"finalize"`
+	Certificate    string       `json:"certificate"`
+
+	// URL is the order url returned in the location header when creating the order
+	URL string `json:"-"`
+}
+
+// Authorization is an acme authorization object, as per https://tools.ietf.org/html/rfc8555#section-7.1.4
+type Authorization struct {
+	Identifier Identifier  `json:"identifier"`
+	Status     string      `json:"status"`
+	Expires    time.Time   `json:"expires"`
+	Challenges []Challenge `json:"challenges"`
+	Wildcard   bool        `json:"wildcard"`
+
+	// URL is the url the authorization was fetched from
+	URL string `json:"-"`
+}
+
+// Challenge is an acme challenge object, as per https://tools.ietf.org/html/rfc8555#section-7.1.5
+type Challenge struct {
+	Type      string   `json:"type"`
+	URL       string   `json:"url"`
+	Status    string   `json:"status"`
+	Validated string   `json:"validated,omitempty"`
+	Error     *Problem `json:"error,omitempty"`
+	Token     string   `json:"token"`
+}
+
+// Challenge returns the first challenge of the given type in the authorization, if any
+func (authz Authorization) Challenge(challengeType string) (Challenge, bool) {
+	for _, chal := range authz.Challenges {
+		if chal.Type == challengeType {
+			return chal, true
+		}
+	}
+	return Challenge{}, false
+}
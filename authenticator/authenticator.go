@@ -0,0 +1,32 @@
+package authenticator
+
+// Challenge is a single acme challenge to be set up by an authenticator so the acme server can validate it
+type Challenge struct {
+	// Domain is the identifier being validated, without any wildcard prefix
+	Domain string
+
+	// Type is the acme challenge type, eg http-01
+	Type string
+
+	// Token is the challenge token provided by the acme server
+	Token string
+
+	// KeyAuthorization is the token combined with the account key thumbprint
+	KeyAuthorization string
+}
+
+// Authenticator sets up challenges so they can be validated, and cleans them up afterwards
+type Authenticator interface {
+	// Name is the name of the authenticator, as used in the --authenticator flag
+	Name() string
+
+	// ChallengeTypes is the list of acme challenge types the authenticator can solve, in order of preference
+	ChallengeTypes() []string
+
+	// Perform sets up all the challenges, returning once they are ready to be validated
+	Perform(chals []Challenge) error
+
+	// Cleanup removes anything set up by Perform for the challenges
+	// It is called for every challenge passed to Perform, whether validation succeeded or not
+	Cleanup(chals []Challenge) error
+}
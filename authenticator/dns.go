@@ -0,0 +1,600 @@
+package authenticator
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// This is a minimal implementation of the dns wire format, only supporting what is needed to send dynamic updates
+// and query records as per https://tools.ietf.org/html/rfc1035, https://tools.ietf.org/html/rfc2136
+// and https://tools.ietf.org/html/rfc8945
+
+const (
+	dnsTypeSOA  uint16 = 6
+	dnsTypeTXT  uint16 = 16
+	dnsTypeTSIG uint16 = 250
+
+	dnsClassINET uint16 = 1
+	dnsClassNONE uint16 = 254
+	dnsClassANY  uint16 = 255
+
+	dnsOpcodeQuery  = 0
+	dnsOpcodeUpdate = 5
+
+	dnsRcodeSuccess = 0
+	dnsRcodeRefused = 5
+	dnsRcodeNotAuth = 9
+
+	dnsFlagQR uint16 = 1 << 15
+	dnsFlagAA uint16 = 1 << 10
+	dnsFlagTC uint16 = 1 << 9
+
+	dnsHeaderLen = 12
+
+	tsigAlgorithmSHA256 = "hmac-sha256."
+	tsigAlgorithmSHA512 = "hmac-sha512."
+	tsigFudge           = 300
+
+	// dns queries over udp are limited to 512 bytes without EDNS, so we never need to read more than this
+	dnsMaxUDPSize = 512
+)
+
+var dnsRcodeNames = map[int]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+	16: "BADSIG",
+	17: "BADKEY",
+	18: "BADTIME",
+}
+
+func dnsRcodeName(rcode int) string {
+	if s, ok := dnsRcodeNames[rcode]; ok {
+		return s
+	}
+	return fmt.Sprintf("RCODE%d", rcode)
+}
+
+// dnsMessage is a dns message, for update messages the sections are zone, prerequisite, update and additional
+type dnsMessage struct {
+	ID         uint16
+	Flags      uint16
+	Question   []dnsQuestion
+	Answer     []dnsRR
+	Authority  []dnsRR
+	Additional []dnsRR
+
+	// tsigOffset is the offset of the tsig record in the unpacked message, or 0 if there was none
+	tsigOffset int
+}
+
+type dnsQuestion struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// dnsRR is a resource record, the data is left as raw bytes as any names in it may be compressed
+type dnsRR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+func (m dnsMessage) Opcode() int {
+	return int(m.Flags>>11) & 0xF
+}
+
+func (m dnsMessage) Rcode() int {
+	return int(m.Flags & 0xF)
+}
+
+func newDNSMessage(opcode int) (*dnsMessage, error) {
+	var id [2]byte
+	if _, err := io.ReadFull(rand.Reader, id[:]); err != nil {
+		return nil, fmt.Errorf("error generating dns message id: %v", err)
+	}
+	return &dnsMessage{
+		ID:    binary.BigEndian.Uint16(id[:]),
+		Flags: uint16(opcode&0xF) << 11,
+	}, nil
+}
+
+// fqdn returns the name with a trailing dot
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+func packDNSName(b []byte, name string) ([]byte, error) {
+	name = fqdn(name)
+	if len(name) > 255 {
+		return nil, fmt.Errorf("dns name too long: %s", name)
+	}
+	if name == "." {
+		return append(b, 0), nil
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid dns name: %s", name)
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+func unpackDNSName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	// limit the number of pointers followed to prevent loops
+	for ptrs := 0; ptrs < 64; {
+		if off >= len(msg) {
+			return "", 0, errors.New("dns name overflows message")
+		}
+		c := int(msg[off])
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0 {
+				if end < 0 {
+					end = off + 1
+				}
+				return strings.Join(labels, ".") + ".", end, nil
+			}
+			if off+1+c > len(msg) {
+				return "", 0, errors.New("dns label overflows message")
+			}
+			labels = append(labels, string(msg[off+1:off+1+c]))
+			off += 1 + c
+		case 0xC0:
+			if off+1 >= len(msg) {
+				return "", 0, errors.New("dns pointer overflows message")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+			ptrs++
+		default:
+			return "", 0, fmt.Errorf("unsupported dns label type 0x%x", c)
+		}
+	}
+	return "", 0, errors.New("too many dns name pointers")
+}
+
+func (m dnsMessage) Pack() ([]byte, error) {
+	b := make([]byte, dnsHeaderLen, 512)
+	binary.BigEndian.PutUint16(b[0:], m.ID)
+	binary.BigEndian.PutUint16(b[2:], m.Flags)
+	binary.BigEndian.PutUint16(b[4:], uint16(len(m.Question)))
+	binary.BigEndian.PutUint16(b[6:], uint16(len(m.Answer)))
+	binary.BigEndian.PutUint16(b[8:], uint16(len(m.Authority)))
+	binary.BigEndian.PutUint16(b[10:], uint16(len(m.Additional)))
+
+	var err error
+	for _, q := range m.Question {
+		if b, err = packDNSName(b, q.Name); err != nil {
+			return nil, err
+		}
+		b = appendUint16(b, q.Type)
+		b = appendUint16(b, q.Class)
+	}
+	for _, section := range [][]dnsRR{m.Answer, m.Authority, m.Additional} {
+		for _, rr := range section {
+			if b, err = rr.pack(b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+func (rr dnsRR) pack(b []byte) ([]byte, error) {
+	b, err := packDNSName(b, rr.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(rr.Data) > 0xFFFF {
+		return nil, errors.New("dns record data too long")
+	}
+	b = appendUint16(b, rr.Type)
+	b = appendUint16(b, rr.Class)
+	b = appendUint32(b, rr.TTL)
+	b = appendUint16(b, uint16(len(rr.Data)))
+	return append(b, rr.Data...), nil
+}
+
+func unpackDNSMessage(b []byte) (*dnsMessage, error) {
+	if len(b) < dnsHeaderLen {
+		return nil, errors.New("dns message too short")
+	}
+	m := &dnsMessage{
+		ID:    binary.BigEndian.Uint16(b[0:]),
+		Flags: binary.BigEndian.Uint16(b[2:]),
+	}
+	counts := []int{
+		int(binary.BigEndian.Uint16(b[4:])),
+		int(binary.BigEndian.Uint16(b[6:])),
+		int(binary.BigEndian.Uint16(b[8:])),
+		int(binary.BigEndian.Uint16(b[10:])),
+	}
+
+	off := dnsHeaderLen
+	for i := 0; i < counts[0]; i++ {
+		name, n, err := unpackDNSName(b, off)
+		if err != nil {
+			return nil, err
+		}
+		if n+4 > len(b) {
+			return nil, errors.New("dns question overflows message")
+		}
+		m.Question = append(m.Question, dnsQuestion{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(b[n:]),
+			Class: binary.BigEndian.Uint16(b[n+2:]),
+		})
+		off = n + 4
+	}
+
+	for s, section := range []*[]dnsRR{&m.Answer, &m.Authority, &m.Additional} {
+		for i := 0; i < counts[s+1]; i++ {
+			start := off
+			name, n, err := unpackDNSName(b, off)
+			if err != nil {
+				return nil, err
+			}
+			if n+10 > len(b) {
+				return nil, errors.New("dns record overflows message")
+			}
+			rr := dnsRR{
+				Name:  name,
+				Type:  binary.BigEndian.Uint16(b[n:]),
+				Class: binary.BigEndian.Uint16(b[n+2:]),
+				TTL:   binary.BigEndian.Uint32(b[n+4:]),
+			}
+			l := int(binary.BigEndian.Uint16(b[n+8:]))
+			if n+10+l > len(b) {
+				return nil, errors.New("dns record data overflows message")
+			}
+			rr.Data = append([]byte{}, b[n+10:n+10+l]...)
+			off = n + 10 + l
+			*section = append(*section, rr)
+			if rr.Type == dnsTypeTSIG {
+				m.tsigOffset = start
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// packTXT returns the rdata for a txt record, splitting the value into multiple strings if needed
+func packTXT(value string) []byte {
+	var b []byte
+	for {
+		l := len(value)
+		if l > 255 {
+			l = 255
+		}
+		b = append(b, byte(l))
+		b = append(b, value[:l]...)
+		value = value[l:]
+		if len(value) == 0 {
+			return b
+		}
+	}
+}
+
+// unpackTXT returns the value of a txt record, joining multiple strings together
+func unpackTXT(data []byte) (string, error) {
+	var sb strings.Builder
+	for off := 0; off < len(data); {
+		l := int(data[off])
+		if off+1+l > len(data) {
+			return "", errors.New("txt string overflows record")
+		}
+		sb.Write(data[off+1 : off+1+l])
+		off += 1 + l
+	}
+	return sb.String(), nil
+}
+
+// tsigKey is a shared secret used to sign dns messages as per https://tools.ietf.org/html/rfc8945
+type tsigKey struct {
+	Name      string
+	Algorithm string
+	Secret    []byte
+}
+
+func (k tsigKey) hash() (func() hash.Hash, error) {
+	switch strings.ToLower(fqdn(k.Algorithm)) {
+	case tsigAlgorithmSHA256:
+		return sha256.New, nil
+	case tsigAlgorithmSHA512:
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported tsig algorithm %s", k.Algorithm)
+}
+
+// tsigRecord holds the fields of a tsig record, along with the key name
+type tsigRecord struct {
+	Name       string
+	Algorithm  string
+	TimeSigned uint64
+	Fudge      uint16
+	MAC        []byte
+	OriginalID uint16
+	Error      uint16
+	Other      []byte
+}
+
+// variables returns the tsig variables which are included in the mac
+func (t tsigRecord) variables() ([]byte, error) {
+	b, err := packDNSName(nil, strings.ToLower(t.Name))
+	if err != nil {
+		return nil, err
+	}
+	b = appendUint16(b, dnsClassANY)
+	b = appendUint32(b, 0)
+	if b, err = packDNSName(b, strings.ToLower(t.Algorithm)); err != nil {
+		return nil, err
+	}
+	b = appendUint48(b, t.TimeSigned)
+	b = appendUint16(b, t.Fudge)
+	b = appendUint16(b, t.Error)
+	b = appendUint16(b, uint16(len(t.Other)))
+	return append(b, t.Other...), nil
+}
+
+func (t tsigRecord) rr() (dnsRR, error) {
+	data, err := packDNSName(nil, strings.ToLower(t.Algorithm))
+	if err != nil {
+		return dnsRR{}, err
+	}
+	data = appendUint48(data, t.TimeSigned)
+	data = appendUint16(data, t.Fudge)
+	data = appendUint16(data, uint16(len(t.MAC)))
+	data = append(data, t.MAC...)
+	data = appendUint16(data, t.OriginalID)
+	data = appendUint16(data, t.Error)
+	data = appendUint16(data, uint16(len(t.Other)))
+	data = append(data, t.Other...)
+	return dnsRR{
+		Name:  strings.ToLower(fqdn(t.Name)),
+		Type:  dnsTypeTSIG,
+		Class: dnsClassANY,
+		Data:  data,
+	}, nil
+}
+
+func unpackTSIG(rr dnsRR) (tsigRecord, error) {
+	t := tsigRecord{Name: rr.Name}
+	alg, off, err := unpackDNSName(rr.Data, 0)
+	if err != nil {
+		return t, err
+	}
+	t.Algorithm = alg
+	d := rr.Data
+	if off+10 > len(d) {
+		return t, errors.New("tsig record too short")
+	}
+	t.TimeSigned = uint64(binary.BigEndian.Uint16(d[off:]))<<32 | uint64(binary.BigEndian.Uint32(d[off+2:]))
+	t.Fudge = binary.BigEndian.Uint16(d[off+6:])
+	macLen := int(binary.BigEndian.Uint16(d[off+8:]))
+	off += 10
+	if off+macLen+6 > len(d) {
+		return t, errors.New("tsig record too short")
+	}
+	t.MAC = d[off : off+macLen]
+	off += macLen
+	t.OriginalID = binary.BigEndian.Uint16(d[off:])
+	t.Error = binary.BigEndian.Uint16(d[off+2:])
+	otherLen := int(binary.BigEndian.Uint16(d[off+4:]))
+	off += 6
+	if off+otherLen > len(d) {
+		return t, errors.New("tsig record too short")
+	}
+	t.Other = d[off : off+otherLen]
+	return t, nil
+}
+
+// mac computes the mac for the message, which must not include the tsig record
+// requestMAC is the mac of the request when signing a response, or nil when signing a request
+func (k tsigKey) mac(msg []byte, requestMAC []byte, t tsigRecord) ([]byte, error) {
+	h, err := k.hash()
+	if err != nil {
+		return nil, err
+	}
+	vars, err := t.variables()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(h, k.Secret)
+	if requestMAC != nil {
+		mac.Write(appendUint16(nil, uint16(len(requestMAC))))
+		mac.Write(requestMAC)
+	}
+	mac.Write(msg)
+	mac.Write(vars)
+	return mac.Sum(nil), nil
+}
+
+// sign appends a tsig record to the packed message, returning the signed message and the mac
+func (k tsigKey) sign(msg []byte, requestMAC []byte, now time.Time) ([]byte, []byte, error) {
+	if len(msg) < dnsHeaderLen {
+		return nil, nil, errors.New("dns message too short")
+	}
+	t := tsigRecord{
+		Name:       k.Name,
+		Algorithm:  k.Algorithm,
+		TimeSigned: uint64(now.Unix()),
+		Fudge:      tsigFudge,
+		OriginalID: binary.BigEndian.Uint16(msg),
+	}
+	mac, err := k.mac(msg, requestMAC, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	t.MAC = mac
+	rr, err := t.rr()
+	if err != nil {
+		return nil, nil, err
+	}
+	signed, err := rr.pack(append([]byte{}, msg...))
+	if err != nil {
+		return nil, nil, err
+	}
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(signed[10:])+1)
+	return signed, mac, nil
+}
+
+// verify checks the tsig record on the raw message, returning the mac
+func (k tsigKey) verify(raw []byte, m *dnsMessage, requestMAC []byte, now time.Time) ([]byte, error) {
+	if m.tsigOffset == 0 || len(m.Additional) == 0 || m.Additional[len(m.Additional)-1].Type != dnsTypeTSIG {
+		return nil, errors.New("dns message is not signed")
+	}
+	t, err := unpackTSIG(m.Additional[len(m.Additional)-1])
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(fqdn(t.Name), fqdn(k.Name)) {
+		return nil, fmt.Errorf("dns message signed with unknown key %s", t.Name)
+	}
+	if !strings.EqualFold(fqdn(t.Algorithm), fqdn(k.Algorithm)) {
+		return nil, fmt.Errorf("dns message signed with unexpected algorithm %s", t.Algorithm)
+	}
+	if t.Error != 0 {
+		return nil, fmt.Errorf("tsig error %s", dnsRcodeName(int(t.Error)))
+	}
+
+	msg := append([]byte{}, raw[:m.tsigOffset]...)
+	binary.BigEndian.PutUint16(msg[0:], t.OriginalID)
+	binary.BigEndian.PutUint16(msg[10:], binary.BigEndian.Uint16(msg[10:])-1)
+	mac, err := k.mac(msg, requestMAC, t)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, t.MAC) {
+		return nil, errors.New("dns message has invalid tsig signature")
+	}
+
+	diff := now.Unix() - int64(t.TimeSigned)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > int64(t.Fudge) {
+		return nil, errors.New("dns message tsig time outside of allowed fudge")
+	}
+	return mac, nil
+}
+
+// dnsExchange sends the message to the server over the network (udp or tcp) and returns the response
+// If a key is provided the request is signed and the response verified
+// Truncated udp responses are retried over tcp
+func dnsExchange(network, addr string, m *dnsMessage, key *tsigKey, timeout time.Duration) (*dnsMessage, error) {
+	req, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+	var requestMAC []byte
+	if key != nil {
+		if req, requestMAC, err = key.sign(req, nil, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	raw, err := dnsSend(network, addr, req, timeout)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := unpackDNSMessage(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing dns response from %s: %v", addr, err)
+	}
+	if resp.ID != m.ID {
+		return nil, fmt.Errorf("dns response from %s has mismatched id", addr)
+	}
+	if resp.Flags&dnsFlagTC != 0 && network == "udp" {
+		return dnsExchange("tcp", addr, m, key, timeout)
+	}
+
+	if key != nil {
+		// servers may not sign responses to requests they couldn't verify, so these are reported by the rcode instead
+		if resp.tsigOffset == 0 && resp.Rcode() != dnsRcodeSuccess {
+			return resp, nil
+		}
+		if _, err := key.verify(raw, resp, requestMAC, time.Now()); err != nil {
+			return nil, fmt.Errorf("error verifying dns response from %s: %v", addr, err)
+		}
+	}
+	return resp, nil
+}
+
+func dnsSend(network, addr string, req []byte, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to dns server %s: %v", addr, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	if network == "udp" {
+		if _, err := conn.Write(req); err != nil {
+			return nil, fmt.Errorf("error sending dns request to %s: %v", addr, err)
+		}
+		buf := make([]byte, dnsMaxUDPSize)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading dns response from %s: %v", addr, err)
+		}
+		return buf[:n], nil
+	}
+
+	// tcp messages are prefixed with a two byte length
+	if _, err := conn.Write(append(appendUint16(nil, uint16(len(req))), req...)); err != nil {
+		return nil, fmt.Errorf("error sending dns request to %s: %v", addr, err)
+	}
+	var l [2]byte
+	if _, err := io.ReadFull(conn, l[:]); err != nil {
+		return nil, fmt.Errorf("error reading dns response from %s: %v", addr, err)
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(l[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, fmt.Errorf("error reading dns response from %s: %v", addr, err)
+	}
+	return buf, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint48(b []byte, v uint64) []byte {
+	return append(b, byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,8 @@
+package authenticator
+
+import "os/exec"
+
+// hookCommand returns a command to run a hook through the shell, so hooks can use pipes, redirects etc
+func hookCommand(hook string) *exec.Cmd {
+	return exec.Command("cmd", "/C", hook)
+}
@@ -0,0 +1,196 @@
+package authenticator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/log"
+)
+
+const (
+	ManualName = "manual"
+
+	// environment variables set when running the auth and cleanup hooks, the same as certbot
+	EnvDomain              = "CERTBOT_DOMAIN"
+	EnvValidation          = "CERTBOT_VALIDATION"
+	EnvToken               = "CERTBOT_TOKEN"
+	EnvRemainingChallenges = "CERTBOT_REMAINING_CHALLENGES"
+	EnvAllDomains          = "CERTBOT_ALL_DOMAINS"
+	EnvAuthOutput          = "CERTBOT_AUTH_OUTPUT"
+
+	dns01Prefix = "_acme-challenge."
+)
+
+// Manual is an authenticator which hands the challenges off to user provided hook scripts,
+// or to the user themselves if no auth hook is provided
+type Manual struct {
+	// AuthHook is a command run through the shell for each challenge to set it up
+	AuthHook string
+
+	// CleanupHook is a command run through the shell for each challenge to clean it up, optional
+	CleanupHook string
+
+	// Prompt shows instructions to the user and returns once they have completed them
+	// Used when there is no AuthHook, if both are empty challenges cannot be performed
+	Prompt func(instructions string) error
+
+	mu sync.Mutex
+	// env holds the environment the auth hook was run with for each challenge, including the auth hook output
+	env map[Challenge][]string
+}
+
+// NewManual returns a manual authenticator using the provided hooks
+func NewManual(authHook, cleanupHook string, prompt func(instructions string) error) *Manual {
+	return &Manual{
+		AuthHook:    authHook,
+		CleanupHook: cleanupHook,
+		Prompt:      prompt,
+		env:         map[Challenge][]string{},
+	}
+}
+
+func (m *Manual) Name() string {
+	return ManualName
+}
+
+func (m *Manual) ChallengeTypes() []string {
+	return []string{acme.ChallengeTypeHTTP01, acme.ChallengeTypeDNS01}
+}
+
+func (m *Manual) Perform(chals []Challenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, chal := range chals {
+		if chal.Type != acme.ChallengeTypeHTTP01 && chal.Type != acme.ChallengeTypeDNS01 {
+			return fmt.Errorf("manual authenticator does not support challenge type %s", chal.Type)
+		}
+	}
+	if m.AuthHook == "" && m.Prompt == nil {
+		return errors.New("an authentication script must be provided when running the manual authenticator non-interactively")
+	}
+
+	for i, chal := range chals {
+		env := challengeEnv(chal, chals, i)
+		ll := log.WithFields("domain", chal.Domain, "type", chal.Type)
+
+		if m.AuthHook == "" {
+			ll.Debug("manual prompting for challenge")
+			if err := m.Prompt(instructions(chal, chals, i)); err != nil {
+				return err
+			}
+			m.env[chal] = env
+			continue
+		}
+
+		ll.WithField("hook", m.AuthHook).Debug("manual running auth hook")
+		out, err := runHook(m.AuthHook, env)
+		if err != nil {
+			return fmt.Errorf("auth hook for %s: %v", chal.Domain, err)
+		}
+		m.env[chal] = append(env, EnvAuthOutput+"="+strings.TrimSpace(out))
+	}
+
+	return nil
+}
+
+func (m *Manual) Cleanup(chals []Challenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []string
+	for i, chal := range chals {
+		env, ok := m.env[chal]
+		delete(m.env, chal)
+		if m.CleanupHook == "" {
+			continue
+		}
+		if !ok {
+			env = challengeEnv(chal, chals, i)
+		}
+		log.WithFields("domain", chal.Domain, "type", chal.Type, "hook", m.CleanupHook).Debug("manual running cleanup hook")
+		if _, err := runHook(m.CleanupHook, env); err != nil {
+			errs = append(errs, fmt.Sprintf("cleanup hook for %s: %v", chal.Domain, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validation returns the value to be provisioned for the challenge
+func validation(chal Challenge) string {
+	if chal.Type == acme.ChallengeTypeDNS01 {
+		return acme.DNS01Value(chal.KeyAuthorization)
+	}
+	return chal.KeyAuthorization
+}
+
+// challengeEnv returns the environment variables to run a hook with for the challenge at index i of chals
+func challengeEnv(chal Challenge, chals []Challenge, i int) []string {
+	var domains []string
+	for _, c := range chals {
+		domains = append(domains, c.Domain)
+	}
+	env := []string{
+		EnvDomain + "=" + chal.Domain,
+		EnvValidation + "=" + validation(chal),
+		EnvRemainingChallenges + "=" + strconv.Itoa(len(chals)-i-1),
+		EnvAllDomains + "=" + strings.Join(domains, ","),
+	}
+	if chal.Type == acme.ChallengeTypeHTTP01 {
+		env = append(env, EnvToken+"="+chal.Token)
+	}
+	return env
+}
+
+// instructions returns the text shown to the user to set up the challenge at index i of chals
+func instructions(chal Challenge, chals []Challenge, i int) string {
+	var sb strings.Builder
+	if chal.Type == acme.ChallengeTypeDNS01 {
+		fmt.Fprintf(&sb, "Please deploy a DNS TXT record under the name:\n\n%s%s.\n\nwith the following value:\n\n%s\n",
+			dns01Prefix, chal.Domain, validation(chal))
+	} else {
+		fmt.Fprintf(&sb, "Create a file containing just this data:\n\n%s\n\nAnd make it available on your web server at this URL:\n\nhttp://%s%s%s\n",
+			validation(chal), chal.Domain, http01Path, chal.Token)
+	}
+	if i > 0 {
+		sb.WriteString("\n(This must be set up in addition to the previous challenges; do not remove, replace, or undo the previous challenge tasks yet.)\n")
+	}
+	if i < len(chals)-1 {
+		fmt.Fprintf(&sb, "\n%d challenges remaining after this one.\n", len(chals)-i-1)
+	}
+	return sb.String()
+}
+
+// runHook runs the hook with the provided environment variables, returning the standard output
+// Any CERTBOT_ variables already in the environment are removed so they don't leak between challenges
+func runHook(hook string, env []string) (string, error) {
+	cmd := hookCommand(hook)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, "CERTBOT_") {
+			cmd.Env = append(cmd.Env, e)
+		}
+	}
+	cmd.Env = append(cmd.Env, env...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	ll := log.WithFields("hook", hook, "stdout", stdout.String(), "stderr", stderr.String())
+	if err != nil {
+		ll.WithError(err).Debug("hook failed")
+		return stdout.String(), fmt.Errorf("error running hook %q: %v: %s", hook, err, strings.TrimSpace(stderr.String()))
+	}
+	ll.Trace("hook ran")
+	return stdout.String(), nil
+}
@@ -0,0 +1,170 @@
+package authenticator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/eggsampler/certgot/acme"
+)
+
+func Test_challengeEnv(t *testing.T) {
+	chals := []Challenge{
+		{Domain: "example.com", Type: acme.ChallengeTypeHTTP01, Token: "token1", KeyAuthorization: "token1.thumb"},
+		{Domain: "www.example.com", Type: acme.ChallengeTypeDNS01, Token: "token2", KeyAuthorization: "token2.thumb"},
+	}
+	tests := []struct {
+		name string
+		i    int
+		want []string
+	}{
+		{
+			name: "http-01",
+			i:    0,
+			want: []string{
+				"CERTBOT_DOMAIN=example.com",
+				"CERTBOT_VALIDATION=token1.thumb",
+				"CERTBOT_REMAINING_CHALLENGES=1",
+				"CERTBOT_ALL_DOMAINS=example.com,www.example.com",
+				"CERTBOT_TOKEN=token1",
+			},
+		},
+		{
+			name: "dns-01",
+			i:    1,
+			want: []string{
+				"CERTBOT_DOMAIN=www.example.com",
+				"CERTBOT_VALIDATION=" + acme.DNS01Value("token2.thumb"),
+				"CERTBOT_REMAINING_CHALLENGES=0",
+				"CERTBOT_ALL_DOMAINS=example.com,www.example.com",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := challengeEnv(chals[tt.i], chals, tt.i)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("challengeEnv() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManual(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use a posix shell")
+	}
+	dir, err := ioutil.TempDir("", "manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// leaked from the parent environment, must not be seen by the hooks
+	os.Setenv(EnvToken, "leaked")
+	defer os.Unsetenv(EnvToken)
+
+	authHook := `echo "$CERTBOT_DOMAIN $CERTBOT_VALIDATION $CERTBOT_TOKEN $CERTBOT_REMAINING_CHALLENGES $CERTBOT_ALL_DOMAINS" >> ` +
+		filepath.Join(dir, "auth") + `; echo "output $CERTBOT_DOMAIN"`
+	cleanupHook := `echo "$CERTBOT_DOMAIN $CERTBOT_AUTH_OUTPUT" >> ` + filepath.Join(dir, "cleanup")
+
+	m := NewManual(authHook, cleanupHook, nil)
+	chals := []Challenge{
+		{Domain: "example.com", Type: acme.ChallengeTypeHTTP01, Token: "token1", KeyAuthorization: "token1.thumb"},
+		{Domain: "www.example.com", Type: acme.ChallengeTypeDNS01, Token: "token2", KeyAuthorization: "token2.thumb"},
+	}
+	if err := m.Perform(chals); err != nil {
+		t.Fatalf("error performing: %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "auth"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "example.com token1.thumb token1 1 example.com,www.example.com\n" +
+		"www.example.com " + acme.DNS01Value("token2.thumb") + "  0 example.com,www.example.com\n"
+	if string(b) != want {
+		t.Errorf("unexpected auth hook output:\n%s\nwant:\n%s", string(b), want)
+	}
+
+	if err := m.Cleanup(chals); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	b, err = ioutil.ReadFile(filepath.Join(dir, "cleanup"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "example.com output example.com\nwww.example.com output www.example.com\n"
+	if string(b) != want {
+		t.Errorf("unexpected cleanup hook output:\n%s\nwant:\n%s", string(b), want)
+	}
+}
+
+func TestManual_Perform(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks use a posix shell")
+	}
+	chal := Challenge{Domain: "example.com", Type: acme.ChallengeTypeDNS01, Token: "token", KeyAuthorization: "token.thumb"}
+
+	tests := []struct {
+		name     string
+		authHook string
+		prompt   func(string) error
+		chal     Challenge
+		wantErr  bool
+		errStr   string
+	}{
+		{
+			name:    "bad type",
+			prompt:  func(string) error { return nil },
+			chal:    Challenge{Domain: "example.com", Type: acme.ChallengeTypeTLSALPN01},
+			wantErr: true,
+			errStr:  "does not support",
+		},
+		{
+			name:    "non-interactive",
+			chal:    chal,
+			wantErr: true,
+			errStr:  "authentication script must be provided",
+		},
+		{
+			name:     "hook fails",
+			authHook: "echo nope >&2; exit 1",
+			chal:     chal,
+			wantErr:  true,
+			errStr:   "nope",
+		},
+		{
+			name: "prompt",
+			prompt: func(s string) error {
+				if !strings.Contains(s, "_acme-challenge.example.com.") || !strings.Contains(s, acme.DNS01Value("token.thumb")) {
+					return errors.New("bad instructions: " + s)
+				}
+				return nil
+			},
+			chal: chal,
+		},
+		{
+			name:    "prompt fails",
+			prompt:  func(string) error { return errors.New("cancelled") },
+			chal:    chal,
+			wantErr: true,
+			errStr:  "cancelled",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManual(tt.authHook, "", tt.prompt)
+			err := m.Perform([]Challenge{tt.chal})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Perform() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.errStr) {
+				t.Errorf("expected %q in error: %v", tt.errStr, err)
+			}
+		})
+	}
+}
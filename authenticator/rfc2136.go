@@ -0,0 +1,360 @@
+package authenticator
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/util"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	RFC2136Name = "dns-rfc2136"
+
+	// keys in the credentials file, the same as certbot-dns-rfc2136
+	rfc2136KeyServer    = "dns_rfc2136_server"
+	rfc2136KeyPort      = "dns_rfc2136_port"
+	rfc2136KeyName      = "dns_rfc2136_name"
+	rfc2136KeySecret    = "dns_rfc2136_secret"
+	rfc2136KeyAlgorithm = "dns_rfc2136_algorithm"
+	rfc2136KeySignQuery = "dns_rfc2136_sign_query"
+
+	rfc2136DefaultPort      = 53
+	rfc2136DefaultAlgorithm = "HMAC-SHA512"
+	rfc2136TTL              = 120
+)
+
+// RFC2136Credentials holds the server and key settings for sending dynamic updates
+type RFC2136Credentials struct {
+	// Server is the ip address of the primary nameserver to send updates to
+	Server string
+
+	// Port is the port of the nameserver
+	Port int
+
+	// KeyName is the name of the tsig key
+	KeyName string
+
+	// Secret is the tsig key secret
+	Secret []byte
+
+	// Algorithm is the tsig algorithm, either HMAC-SHA256 or HMAC-SHA512
+	Algorithm string
+
+	// SignQuery determines whether SOA and TXT queries are also signed with the tsig key
+	SignQuery bool
+}
+
+// Addr returns the host:port address of the nameserver
+func (c RFC2136Credentials) Addr() string {
+	return net.JoinHostPort(c.Server, strconv.Itoa(c.Port))
+}
+
+// LoadRFC2136Credentials loads the credentials from an ini file in the same format as certbot-dns-rfc2136, eg
+//   dns_rfc2136_server = 192.0.2.1
+//   dns_rfc2136_name = keyname.
+//   dns_rfc2136_secret = 4q4wM/2I180UXoMyN4INVhJNi8V9BCV+jMw2mXgZw/CSuxUT8C7NKKFs AmKd7ak51vWKgSl12ib86oQRPkpDjg==
+//   dns_rfc2136_algorithm = HMAC-SHA512
+func LoadRFC2136Credentials(path string) (RFC2136Credentials, error) {
+	creds := RFC2136Credentials{
+		Port:      rfc2136DefaultPort,
+		Algorithm: rfc2136DefaultAlgorithm,
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return creds, fmt.Errorf("error reading credentials file %s: %v", path, err)
+	}
+	if util.UnsafePermissions(fi) {
+		log.WithFields("path", path, "mode", fi.Mode()).Warn("unsafe permissions on credentials file, it should only be readable by the owner")
+	}
+
+	f, err := ini.Load(path)
+	if err != nil {
+		return creds, fmt.Errorf("error loading credentials file %s: %v", path, err)
+	}
+	section := f.Section("")
+
+	missing := func(key string) error {
+		return fmt.Errorf("missing %s in credentials file %s", key, path)
+	}
+
+	creds.Server = section.Key(rfc2136KeyServer).String()
+	if creds.Server == "" {
+		return creds, missing(rfc2136KeyServer)
+	}
+	if net.ParseIP(creds.Server) == nil {
+		return creds, fmt.Errorf("%s must be an ip address, not a hostname: %s", rfc2136KeyServer, creds.Server)
+	}
+
+	if section.HasKey(rfc2136KeyPort) {
+		if creds.Port, err = section.Key(rfc2136KeyPort).Int(); err != nil || creds.Port <= 0 || creds.Port > 65535 {
+			return creds, fmt.Errorf("invalid %s in credentials file %s: %s", rfc2136KeyPort, path, section.Key(rfc2136KeyPort).String())
+		}
+	}
+
+	creds.KeyName = section.Key(rfc2136KeyName).String()
+	if creds.KeyName == "" {
+		return creds, missing(rfc2136KeyName)
+	}
+
+	secret := section.Key(rfc2136KeySecret).String()
+	if secret == "" {
+		return creds, missing(rfc2136KeySecret)
+	}
+	// certbot allows whitespace in the secret, as it is often copied from a bind key file
+	if creds.Secret, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(secret), "")); err != nil {
+		return creds, fmt.Errorf("invalid %s in credentials file %s: %v", rfc2136KeySecret, path, err)
+	}
+
+	if alg := section.Key(rfc2136KeyAlgorithm).String(); alg != "" {
+		creds.Algorithm = strings.ToUpper(alg)
+	}
+	if _, err := creds.tsigKey().hash(); err != nil {
+		return creds, fmt.Errorf("invalid %s in credentials file %s, must be one of HMAC-SHA256, HMAC-SHA512", rfc2136KeyAlgorithm, path)
+	}
+
+	if section.HasKey(rfc2136KeySignQuery) {
+		if creds.SignQuery, err = section.Key(rfc2136KeySignQuery).Bool(); err != nil {
+			return creds, fmt.Errorf("invalid %s in credentials file %s: %v", rfc2136KeySignQuery, path, err)
+		}
+	}
+
+	return creds, nil
+}
+
+func (c RFC2136Credentials) tsigKey() tsigKey {
+	return tsigKey{
+		Name:      fqdn(c.KeyName),
+		Algorithm: fqdn(strings.ToLower(c.Algorithm)),
+		Secret:    c.Secret,
+	}
+}
+
+// RFC2136 is an authenticator which solves dns-01 challenges by sending dynamic updates to a nameserver
+// as per https://tools.ietf.org/html/rfc2136
+type RFC2136 struct {
+	Credentials RFC2136Credentials
+
+	// PropagationTimeout is how long to wait for the records to be served by the nameserver
+	PropagationTimeout time.Duration
+
+	// PollInterval is how long to wait between checking the records have propagated
+	PollInterval time.Duration
+
+	// Timeout is the timeout for each dns request
+	Timeout time.Duration
+
+	mu sync.Mutex
+	// zones holds the zone each challenge record was added to
+	zones map[Challenge]string
+}
+
+// NewRFC2136 returns an rfc2136 authenticator using the provided credentials, waiting up to propagationTimeout for
+// records to be served
+func NewRFC2136(creds RFC2136Credentials, propagationTimeout time.Duration) *RFC2136 {
+	return &RFC2136{
+		Credentials:        creds,
+		PropagationTimeout: propagationTimeout,
+		PollInterval:       2 * time.Second,
+		Timeout:            10 * time.Second,
+		zones:              map[Challenge]string{},
+	}
+}
+
+func (r *RFC2136) Name() string {
+	return RFC2136Name
+}
+
+func (r *RFC2136) ChallengeTypes() []string {
+	return []string{acme.ChallengeTypeDNS01}
+}
+
+func (r *RFC2136) Perform(chals []Challenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, chal := range chals {
+		if chal.Type != acme.ChallengeTypeDNS01 {
+			return fmt.Errorf("dns-rfc2136 authenticator does not support challenge type %s", chal.Type)
+		}
+	}
+
+	for _, chal := range chals {
+		name := recordName(chal.Domain)
+		zone, err := r.findZone(name)
+		if err != nil {
+			return err
+		}
+		log.WithFields("record", name, "zone", zone).Debug("dns-rfc2136 adding txt record")
+		if err := r.update(zone, dnsRR{
+			Name:  name,
+			Type:  dnsTypeTXT,
+			Class: dnsClassINET,
+			TTL:   rfc2136TTL,
+			Data:  packTXT(acme.DNS01Value(chal.KeyAuthorization)),
+		}); err != nil {
+			return fmt.Errorf("error adding txt record %s: %v", name, err)
+		}
+		r.zones[chal] = zone
+	}
+
+	return r.waitPropagation(chals)
+}
+
+func (r *RFC2136) Cleanup(chals []Challenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []string
+	for _, chal := range chals {
+		zone, ok := r.zones[chal]
+		if !ok {
+			continue
+		}
+		name := recordName(chal.Domain)
+		log.WithFields("record", name, "zone", zone).Debug("dns-rfc2136 deleting txt record")
+		// a class of NONE deletes the specific record, leaving any others with the same name
+		if err := r.update(zone, dnsRR{
+			Name:  name,
+			Type:  dnsTypeTXT,
+			Class: dnsClassNONE,
+			Data:  packTXT(acme.DNS01Value(chal.KeyAuthorization)),
+		}); err != nil {
+			errs = append(errs, fmt.Sprintf("error deleting txt record %s: %v", name, err))
+			continue
+		}
+		delete(r.zones, chal)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// recordName returns the fqdn of the dns-01 txt record for the domain
+func recordName(domain string) string {
+	return fqdn(dns01Prefix + domain)
+}
+
+// query sends a non-recursive query to the nameserver, signed if configured to
+func (r *RFC2136) query(name string, typ uint16) (*dnsMessage, error) {
+	m, err := newDNSMessage(dnsOpcodeQuery)
+	if err != nil {
+		return nil, err
+	}
+	m.Question = []dnsQuestion{{Name: name, Type: typ, Class: dnsClassINET}}
+	var key *tsigKey
+	if r.Credentials.SignQuery {
+		k := r.Credentials.tsigKey()
+		key = &k
+	}
+	return dnsExchange("udp", r.Credentials.Addr(), m, key, r.Timeout)
+}
+
+// findZone finds the zone the record belongs to, by looking for the closest name the nameserver is authoritative for
+func (r *RFC2136) findZone(name string) (string, error) {
+	var guesses []string
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i := range labels {
+		guesses = append(guesses, strings.Join(labels[i:], ".")+".")
+	}
+	for _, guess := range guesses {
+		resp, err := r.query(guess, dnsTypeSOA)
+		if err != nil {
+			return "", err
+		}
+		ll := log.WithFields("name", guess, "rcode", dnsRcodeName(resp.Rcode()), "answers", len(resp.Answer))
+		if resp.Rcode() == dnsRcodeSuccess && resp.Flags&dnsFlagAA != 0 && hasType(resp.Answer, dnsTypeSOA) {
+			ll.Debug("dns-rfc2136 found zone")
+			return guess, nil
+		}
+		ll.Trace("dns-rfc2136 not authoritative")
+	}
+	return "", fmt.Errorf("unable to determine zone for %s using names: %s", name, strings.Join(guesses, ", "))
+}
+
+func hasType(rrs []dnsRR, typ uint16) bool {
+	for _, rr := range rrs {
+		if rr.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// update sends a signed dynamic update for the zone with a single record in the update section
+func (r *RFC2136) update(zone string, rr dnsRR) error {
+	m, err := newDNSMessage(dnsOpcodeUpdate)
+	if err != nil {
+		return err
+	}
+	m.Question = []dnsQuestion{{Name: zone, Type: dnsTypeSOA, Class: dnsClassINET}}
+	m.Authority = []dnsRR{rr}
+	key := r.Credentials.tsigKey()
+	resp, err := dnsExchange("tcp", r.Credentials.Addr(), m, &key, r.Timeout)
+	if err != nil {
+		return err
+	}
+	if resp.Rcode() != dnsRcodeSuccess {
+		return fmt.Errorf("received response from server: %s", dnsRcodeName(resp.Rcode()))
+	}
+	return nil
+}
+
+// waitPropagation waits for all the challenge records to be served by the primary nameserver
+func (r *RFC2136) waitPropagation(chals []Challenge) error {
+	deadline := time.Now().Add(r.PropagationTimeout)
+	for {
+		pending, err := r.pendingRecords(chals)
+		if err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			log.Debug("dns-rfc2136 all records propagated")
+			return nil
+		}
+		if time.Now().Add(r.PollInterval).After(deadline) {
+			return fmt.Errorf("timed out waiting for txt records to propagate: %s", strings.Join(pending, ", "))
+		}
+		log.WithField("pending", pending).Trace("dns-rfc2136 waiting for records to propagate")
+		time.Sleep(r.PollInterval)
+	}
+}
+
+// pendingRecords returns the names of records which are not being served yet
+func (r *RFC2136) pendingRecords(chals []Challenge) ([]string, error) {
+	var pending []string
+	for _, chal := range chals {
+		name := recordName(chal.Domain)
+		resp, err := r.query(name, dnsTypeTXT)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		want := acme.DNS01Value(chal.KeyAuthorization)
+		for _, rr := range resp.Answer {
+			if rr.Type != dnsTypeTXT {
+				continue
+			}
+			if v, err := unpackTXT(rr.Data); err == nil && v == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,490 @@
+package authenticator
+
+import (
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eggsampler/certgot/acme"
+)
+
+// testDNSServer is a stand-in authoritative nameserver for a single zone, accepting signed dynamic updates for txt records
+type testDNSServer struct {
+	zone string
+	key  tsigKey
+
+	// hideQueries is the number of txt queries to answer without any records, to simulate propagation delay
+	hideQueries int
+
+	mu      sync.Mutex
+	records map[string][]string
+	updates int
+
+	udp net.PacketConn
+	tcp net.Listener
+}
+
+func newTestDNSServer(t *testing.T, zone string, key tsigKey) *testDNSServer {
+	s := &testDNSServer{
+		zone:    zone,
+		key:     key,
+		records: map[string][]string{},
+	}
+
+	// bind udp and tcp to the same port, retrying if the tcp port is taken
+	for i := 0; ; i++ {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("error listening on udp: %v", err)
+		}
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			udp.Close()
+			if i > 10 {
+				t.Fatalf("error listening on tcp: %v", err)
+			}
+			continue
+		}
+		s.udp, s.tcp = udp, tcp
+		break
+	}
+
+	go s.serveUDP()
+	go s.serveTCP()
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testDNSServer) Close() {
+	s.udp.Close()
+	s.tcp.Close()
+}
+
+func (s *testDNSServer) Port() int {
+	return s.udp.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (s *testDNSServer) Records(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.records[strings.ToLower(name)]...)
+}
+
+func (s *testDNSServer) serveUDP() {
+	buf := make([]byte, dnsMaxUDPSize)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp := s.handle(buf[:n]); resp != nil {
+			_, _ = s.udp.WriteTo(resp, addr)
+		}
+	}
+}
+
+func (s *testDNSServer) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			var l [2]byte
+			if _, err := io.ReadFull(conn, l[:]); err != nil {
+				return
+			}
+			req := make([]byte, binary.BigEndian.Uint16(l[:]))
+			if _, err := io.ReadFull(conn, req); err != nil {
+				return
+			}
+			if resp := s.handle(req); resp != nil {
+				_, _ = conn.Write(append(appendUint16(nil, uint16(len(resp))), resp...))
+			}
+		}(conn)
+	}
+}
+
+func (s *testDNSServer) handle(raw []byte) []byte {
+	req, err := unpackDNSMessage(raw)
+	if err != nil || len(req.Question) != 1 {
+		return nil
+	}
+
+	var requestMAC []byte
+	if req.tsigOffset > 0 {
+		if requestMAC, err = s.key.verify(raw, req, nil, time.Now()); err != nil {
+			return s.respond(req, dnsRcodeNotAuth, nil)
+		}
+		// the tsig record isn't part of the request
+		req.Additional = req.Additional[:len(req.Additional)-1]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := req.Question[0]
+	name := strings.ToLower(q.Name)
+	inZone := name == s.zone || strings.HasSuffix(name, "."+s.zone)
+
+	if req.Opcode() == dnsOpcodeUpdate {
+		if requestMAC == nil {
+			return s.respond(req, dnsRcodeRefused, nil)
+		}
+		if name != s.zone {
+			return s.respond(req, dnsRcodeNotAuth, requestMAC)
+		}
+		for _, rr := range req.Authority {
+			rrName := strings.ToLower(rr.Name)
+			value, err := unpackTXT(rr.Data)
+			if err != nil || rr.Type != dnsTypeTXT {
+				return s.respond(req, 1, requestMAC)
+			}
+			switch rr.Class {
+			case dnsClassINET:
+				s.records[rrName] = append(s.records[rrName], value)
+			case dnsClassNONE:
+				var kept []string
+				for _, v := range s.records[rrName] {
+					if v != value {
+						kept = append(kept, v)
+					}
+				}
+				s.records[rrName] = kept
+			}
+		}
+		s.updates++
+		return s.respond(req, dnsRcodeSuccess, requestMAC)
+	}
+
+	if !inZone {
+		return s.respond(req, dnsRcodeRefused, requestMAC)
+	}
+
+	var answers []dnsRR
+	switch q.Type {
+	case dnsTypeSOA:
+		if name == s.zone {
+			soa, _ := packDNSName(nil, "ns1."+s.zone)
+			soa, _ = packDNSName(soa, "hostmaster."+s.zone)
+			soa = append(soa, make([]byte, 20)...)
+			answers = append(answers, dnsRR{Name: q.Name, Type: dnsTypeSOA, Class: dnsClassINET, TTL: 60, Data: soa})
+		}
+	case dnsTypeTXT:
+		if s.hideQueries > 0 {
+			s.hideQueries--
+			break
+		}
+		for _, v := range s.records[name] {
+			answers = append(answers, dnsRR{Name: q.Name, Type: dnsTypeTXT, Class: dnsClassINET, TTL: 60, Data: packTXT(v)})
+		}
+	}
+
+	resp := s.newResponse(req, dnsRcodeSuccess)
+	resp.Flags |= dnsFlagAA
+	resp.Answer = answers
+	return s.pack(resp, requestMAC)
+}
+
+func (s *testDNSServer) newResponse(req *dnsMessage, rcode int) *dnsMessage {
+	return &dnsMessage{
+		ID:       req.ID,
+		Flags:    dnsFlagQR | uint16(req.Opcode())<<11 | uint16(rcode),
+		Question: req.Question,
+	}
+}
+
+func (s *testDNSServer) respond(req *dnsMessage, rcode int, requestMAC []byte) []byte {
+	return s.pack(s.newResponse(req, rcode), requestMAC)
+}
+
+func (s *testDNSServer) pack(resp *dnsMessage, requestMAC []byte) []byte {
+	b, err := resp.Pack()
+	if err != nil {
+		return nil
+	}
+	if requestMAC != nil {
+		if b, _, err = s.key.sign(b, requestMAC, time.Now()); err != nil {
+			return nil
+		}
+	}
+	return b
+}
+
+func writeCredentials(t *testing.T, dir, contents string, mode os.FileMode) string {
+	p := filepath.Join(dir, "rfc2136.ini")
+	if err := ioutil.WriteFile(p, []byte(contents), mode); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadRFC2136Credentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rfc2136")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		contents string
+		want     RFC2136Credentials
+		wantErr  bool
+		errStr   string
+	}{
+		{
+			name: "ok",
+			contents: "dns_rfc2136_server = 192.0.2.1\n" +
+				"dns_rfc2136_port = 5353\n" +
+				"dns_rfc2136_name = keyname.\n" +
+				"dns_rfc2136_secret = c2Vj cmV0\n" +
+				"dns_rfc2136_algorithm = hmac-sha256\n" +
+				"dns_rfc2136_sign_query = true\n",
+			want: RFC2136Credentials{
+				Server:    "192.0.2.1",
+				Port:      5353,
+				KeyName:   "keyname.",
+				Secret:    []byte("secret"),
+				Algorithm: "HMAC-SHA256",
+				SignQuery: true,
+			},
+		},
+		{
+			name: "defaults",
+			contents: "dns_rfc2136_server = ::1\n" +
+				"dns_rfc2136_name = keyname\n" +
+				"dns_rfc2136_secret = c2VjcmV0\n",
+			want: RFC2136Credentials{
+				Server:    "::1",
+				Port:      53,
+				KeyName:   "keyname",
+				Secret:    []byte("secret"),
+				Algorithm: "HMAC-SHA512",
+			},
+		},
+		{
+			name:     "missing server",
+			contents: "dns_rfc2136_name = keyname\ndns_rfc2136_secret = c2VjcmV0\n",
+			wantErr:  true,
+			errStr:   "missing dns_rfc2136_server",
+		},
+		{
+			name:     "hostname server",
+			contents: "dns_rfc2136_server = ns1.example.com\ndns_rfc2136_name = keyname\ndns_rfc2136_secret = c2VjcmV0\n",
+			wantErr:  true,
+			errStr:   "ip address",
+		},
+		{
+			name:     "bad port",
+			contents: "dns_rfc2136_server = 192.0.2.1\ndns_rfc2136_port = nope\ndns_rfc2136_name = keyname\ndns_rfc2136_secret = c2VjcmV0\n",
+			wantErr:  true,
+			errStr:   "dns_rfc2136_port",
+		},
+		{
+			name:     "missing name",
+			contents: "dns_rfc2136_server = 192.0.2.1\ndns_rfc2136_secret = c2VjcmV0\n",
+			wantErr:  true,
+			errStr:   "missing dns_rfc2136_name",
+		},
+		{
+			name:     "bad secret",
+			contents: "dns_rfc2136_server = 192.0.2.1\ndns_rfc2136_name = keyname\ndns_rfc2136_secret = !!!\n",
+			wantErr:  true,
+			errStr:   "dns_rfc2136_secret",
+		},
+		{
+			name:     "unsupported algorithm",
+			contents: "dns_rfc2136_server = 192.0.2.1\ndns_rfc2136_name = keyname\ndns_rfc2136_secret = c2VjcmV0\ndns_rfc2136_algorithm = HMAC-MD5\n",
+			wantErr:  true,
+			errStr:   "dns_rfc2136_algorithm",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := writeCredentials(t, dir, tt.contents, 0600)
+			got, err := LoadRFC2136Credentials(p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadRFC2136Credentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), tt.errStr) {
+					t.Errorf("expected %q in error: %v", tt.errStr, err)
+				}
+				return
+			}
+			if got.Server != tt.want.Server || got.Port != tt.want.Port || got.KeyName != tt.want.KeyName ||
+				string(got.Secret) != string(tt.want.Secret) || got.Algorithm != tt.want.Algorithm || got.SignQuery != tt.want.SignQuery {
+				t.Errorf("LoadRFC2136Credentials() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadRFC2136Credentials(filepath.Join(dir, "nope.ini")); err == nil {
+		t.Errorf("expected error loading missing file")
+	}
+}
+
+func TestRFC2136(t *testing.T) {
+	key := tsigKey{Name: "keyname.", Algorithm: tsigAlgorithmSHA512, Secret: []byte("secret")}
+	server := newTestDNSServer(t, "example.com.", key)
+	server.hideQueries = 1
+
+	dir, err := ioutil.TempDir("", "rfc2136")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := writeCredentials(t, dir, "dns_rfc2136_server = 127.0.0.1\n"+
+		"dns_rfc2136_port = "+strconv.Itoa(server.Port())+"\n"+
+		"dns_rfc2136_name = keyname\n"+
+		"dns_rfc2136_secret = c2VjcmV0\n"+
+		"dns_rfc2136_sign_query = true\n", 0600)
+	creds, err := LoadRFC2136Credentials(p)
+	if err != nil {
+		t.Fatalf("error loading credentials: %v", err)
+	}
+
+	r := NewRFC2136(creds, 5*time.Second)
+	r.PollInterval = 10 * time.Millisecond
+	r.Timeout = time.Second
+
+	// a wildcard and base domain share the same record name
+	chals := []Challenge{
+		{Domain: "example.com", Type: acme.ChallengeTypeDNS01, Token: "token1", KeyAuthorization: "token1.thumb"},
+		{Domain: "example.com", Type: acme.ChallengeTypeDNS01, Token: "token2", KeyAuthorization: "token2.thumb"},
+		{Domain: "sub.example.com", Type: acme.ChallengeTypeDNS01, Token: "token3", KeyAuthorization: "token3.thumb"},
+	}
+	if err := r.Perform(chals); err != nil {
+		t.Fatalf("error performing: %v", err)
+	}
+
+	got := server.Records("_acme-challenge.example.com.")
+	want := []string{acme.DNS01Value("token1.thumb"), acme.DNS01Value("token2.thumb")}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected records got = %v, want %v", got, want)
+	}
+	if got := server.Records("_acme-challenge.sub.example.com."); len(got) != 1 || got[0] != acme.DNS01Value("token3.thumb") {
+		t.Errorf("unexpected records: %v", got)
+	}
+
+	// cleaning up one challenge leaves the other record with the same name
+	if err := r.Cleanup(chals[:1]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if got := server.Records("_acme-challenge.example.com."); len(got) != 1 || got[0] != acme.DNS01Value("token2.thumb") {
+		t.Errorf("unexpected records after cleanup: %v", got)
+	}
+	if err := r.Cleanup(chals[1:]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if got := server.Records("_acme-challenge.example.com."); len(got) != 0 {
+		t.Errorf("unexpected records after cleanup: %v", got)
+	}
+	if got := server.Records("_acme-challenge.sub.example.com."); len(got) != 0 {
+		t.Errorf("unexpected records after cleanup: %v", got)
+	}
+}
+
+func TestRFC2136_Perform(t *testing.T) {
+	key := tsigKey{Name: "keyname.", Algorithm: tsigAlgorithmSHA256, Secret: []byte("secret")}
+	server := newTestDNSServer(t, "example.com.", key)
+
+	creds := RFC2136Credentials{
+		Server:    "127.0.0.1",
+		Port:      server.Port(),
+		KeyName:   "keyname",
+		Secret:    []byte("secret"),
+		Algorithm: "HMAC-SHA256",
+	}
+	chal := Challenge{Domain: "example.com", Type: acme.ChallengeTypeDNS01, Token: "token", KeyAuthorization: "token.thumb"}
+
+	tests := []struct {
+		name   string
+		creds  func(c *RFC2136Credentials)
+		setup  func()
+		chal   Challenge
+		errStr string
+	}{
+		{
+			name:   "bad type",
+			chal:   Challenge{Domain: "example.com", Type: acme.ChallengeTypeHTTP01},
+			errStr: "does not support",
+		},
+		{
+			name:   "unknown zone",
+			chal:   Challenge{Domain: "example.org", Type: acme.ChallengeTypeDNS01},
+			errStr: "unable to determine zone",
+		},
+		{
+			name: "bad secret",
+			creds: func(c *RFC2136Credentials) {
+				c.Secret = []byte("wrong")
+			},
+			chal:   chal,
+			errStr: "NOTAUTH",
+		},
+		{
+			name: "propagation timeout",
+			setup: func() {
+				server.mu.Lock()
+				server.hideQueries = 1000
+				server.mu.Unlock()
+			},
+			chal:   chal,
+			errStr: "timed out",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := creds
+			if tt.creds != nil {
+				tt.creds(&c)
+			}
+			if tt.setup != nil {
+				tt.setup()
+			}
+			r := NewRFC2136(c, 50*time.Millisecond)
+			r.PollInterval = 10 * time.Millisecond
+			r.Timeout = time.Second
+			err := r.Perform([]Challenge{tt.chal})
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.errStr) {
+				t.Errorf("expected %q in error: %v", tt.errStr, err)
+			}
+		})
+	}
+
+	// the server refuses updates that aren't signed
+	server.mu.Lock()
+	updates := server.updates
+	server.mu.Unlock()
+	m, err := newDNSMessage(dnsOpcodeUpdate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Question = []dnsQuestion{{Name: "example.com.", Type: dnsTypeSOA, Class: dnsClassINET}}
+	resp, err := dnsExchange("tcp", creds.Addr(), m, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Rcode() != dnsRcodeRefused {
+		t.Errorf("expected unsigned update to be refused, got: %s", dnsRcodeName(resp.Rcode()))
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.updates != updates {
+		t.Errorf("unexpected updates: %d", server.updates-updates)
+	}
+}
@@ -0,0 +1,300 @@
+package authenticator
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/log"
+)
+
+const (
+	StandaloneName = "standalone"
+
+	http01Path = "/.well-known/acme-challenge/"
+)
+
+// Standalone is an authenticator which runs its own temporary servers to answer challenges
+type Standalone struct {
+	// HTTPAddress is the address the http-01 listener binds to, empty for all addresses
+	HTTPAddress string
+
+	// HTTPPort is the port the http-01 listener binds to
+	HTTPPort int
+
+	// TLSAddress is the address the tls-alpn-01 listener binds to, empty for all addresses
+	TLSAddress string
+
+	// TLSPort is the port the tls-alpn-01 listener binds to
+	TLSPort int
+
+	mu       sync.Mutex
+	tokens   map[string]string
+	listener net.Listener
+	server   *http.Server
+
+	certs       map[string]*tls.Certificate
+	tlsListener net.Listener
+}
+
+// NewStandalone returns a standalone authenticator listening on the provided addresses and ports for http-01 and
+// tls-alpn-01 challenges
+func NewStandalone(httpAddress string, httpPort int, tlsAddress string, tlsPort int) *Standalone {
+	return &Standalone{
+		HTTPAddress: httpAddress,
+		HTTPPort:    httpPort,
+		TLSAddress:  tlsAddress,
+		TLSPort:     tlsPort,
+		tokens:      map[string]string{},
+		certs:       map[string]*tls.Certificate{},
+	}
+}
+
+func (s *Standalone) Name() string {
+	return StandaloneName
+}
+
+func (s *Standalone) ChallengeTypes() []string {
+	return []string{acme.ChallengeTypeHTTP01, acme.ChallengeTypeTLSALPN01}
+}
+
+func (s *Standalone) Perform(chals []Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var needHTTP, needTLS bool
+	for _, chal := range chals {
+		switch chal.Type {
+		case acme.ChallengeTypeHTTP01:
+			needHTTP = true
+		case acme.ChallengeTypeTLSALPN01:
+			needTLS = true
+		default:
+			return fmt.Errorf("standalone authenticator does not support challenge type %s", chal.Type)
+		}
+	}
+
+	if needHTTP && s.listener == nil {
+		if err := s.listenHTTP(); err != nil {
+			return err
+		}
+	}
+	if needTLS && s.tlsListener == nil {
+		if err := s.listenTLS(); err != nil {
+			return err
+		}
+	}
+
+	for _, chal := range chals {
+		ll := log.WithFields("domain", chal.Domain, "token", chal.Token)
+		if chal.Type == acme.ChallengeTypeTLSALPN01 {
+			cert, err := newTLSALPNCertificate(chal.Domain, chal.KeyAuthorization)
+			if err != nil {
+				return err
+			}
+			ll.Debug("standalone serving tls-alpn-01 challenge")
+			s.certs[tlsALPNServerName(chal.Domain)] = cert
+			continue
+		}
+		ll.Debug("standalone serving http-01 challenge")
+		s.tokens[chal.Token] = chal.KeyAuthorization
+	}
+
+	return nil
+}
+
+func (s *Standalone) Cleanup(chals []Challenge) error {
+	s.mu.Lock()
+	for _, chal := range chals {
+		if chal.Type == acme.ChallengeTypeTLSALPN01 {
+			delete(s.certs, tlsALPNServerName(chal.Domain))
+		} else {
+			delete(s.tokens, chal.Token)
+		}
+	}
+	remaining := len(s.tokens) + len(s.certs)
+	s.mu.Unlock()
+
+	if remaining > 0 {
+		return nil
+	}
+	return s.Close()
+}
+
+// Close stops the listeners regardless of any challenges still being served
+// It is safe to call multiple times
+func (s *Standalone) Close() error {
+	s.mu.Lock()
+	server := s.server
+	tlsListener := s.tlsListener
+	s.server = nil
+	s.listener = nil
+	s.tlsListener = nil
+	s.tokens = map[string]string{}
+	s.certs = map[string]*tls.Certificate{}
+	s.mu.Unlock()
+
+	var tlsErr error
+	if tlsListener != nil {
+		log.WithField("addr", tlsListener.Addr()).Debug("standalone shutting down tls listener")
+		if err := tlsListener.Close(); err != nil {
+			tlsErr = fmt.Errorf("error shutting down standalone tls listener: %v", err)
+		}
+	}
+
+	if server == nil {
+		return tlsErr
+	}
+
+	log.WithField("addr", server.Addr).Debug("standalone shutting down http listener")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		_ = server.Close()
+		return fmt.Errorf("error shutting down standalone http listener: %v", err)
+	}
+	return tlsErr
+}
+
+// Addr returns the address the http listener is bound to, or nil if it is not running
+func (s *Standalone) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// TLSAddr returns the address the tls listener is bound to, or nil if it is not running
+func (s *Standalone) TLSAddr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tlsListener == nil {
+		return nil
+	}
+	return s.tlsListener.Addr()
+}
+
+// listenHTTP must be called with the lock held
+func (s *Standalone) listenHTTP() error {
+	addr := net.JoinHostPort(s.HTTPAddress, strconv.Itoa(s.HTTPPort))
+	ll := log.WithField("addr", addr)
+	ll.Debug("standalone starting http listener")
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error binding standalone http listener to %s, is something else already listening on that port? %w", addr, err)
+	}
+
+	s.listener = l
+	s.server = &http.Server{
+		Addr:    addr,
+		Handler: http.HandlerFunc(s.serveHTTP),
+	}
+
+	go func(server *http.Server) {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			ll.WithError(err).Error("standalone http listener")
+		}
+	}(s.server)
+
+	return nil
+}
+
+func (s *Standalone) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	ll := log.WithFields("remote", r.RemoteAddr, "host", r.Host, "path", r.URL.Path)
+	if !strings.HasPrefix(r.URL.Path, http01Path) {
+		ll.Debug("standalone unknown request")
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	keyAuth, ok := s.tokens[strings.TrimPrefix(r.URL.Path, http01Path)]
+	s.mu.Unlock()
+
+	if !ok {
+		ll.Debug("standalone unknown token")
+		http.NotFound(w, r)
+		return
+	}
+
+	ll.Debug("standalone serving key authorization")
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(keyAuth))
+}
+
+// listenTLS must be called with the lock held
+func (s *Standalone) listenTLS() error {
+	addr := net.JoinHostPort(s.TLSAddress, strconv.Itoa(s.TLSPort))
+	ll := log.WithField("addr", addr)
+	ll.Debug("standalone starting tls listener")
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error binding standalone tls listener to %s, is something else already listening on that port? %w", addr, err)
+	}
+	s.tlsListener = l
+
+	cfg := &tls.Config{
+		NextProtos:     []string{tlsALPNProtocol},
+		GetCertificate: s.getCertificate,
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					ll.WithError(err).Error("standalone tls listener")
+				}
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+				// the challenge is validated during the handshake, so there is nothing to do afterwards
+				if err := tls.Server(conn, cfg).Handshake(); err != nil {
+					ll.WithError(err).WithField("remote", conn.RemoteAddr()).Debug("standalone tls handshake")
+				}
+			}(conn)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Standalone) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	ll := log.WithFields("remote", hello.Conn.RemoteAddr(), "servername", hello.ServerName, "protos", hello.SupportedProtos)
+
+	acmeTLS := false
+	for _, p := range hello.SupportedProtos {
+		if p == tlsALPNProtocol {
+			acmeTLS = true
+		}
+	}
+	if !acmeTLS {
+		ll.Debug("standalone tls request without acme-tls/1 protocol")
+		return nil, fmt.Errorf("client did not offer %s protocol", tlsALPNProtocol)
+	}
+
+	s.mu.Lock()
+	cert, ok := s.certs[strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))]
+	s.mu.Unlock()
+
+	if !ok {
+		ll.Debug("standalone unknown tls-alpn-01 server name")
+		return nil, fmt.Errorf("no challenge for server name %q", hello.ServerName)
+	}
+
+	ll.Debug("standalone serving tls-alpn-01 certificate")
+	return cert, nil
+}
@@ -0,0 +1,198 @@
+package authenticator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/asn1"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/eggsampler/certgot/acme"
+)
+
+func httpGet(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("error fetching %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestStandalone(t *testing.T) {
+	s := NewStandalone("127.0.0.1", 0, "127.0.0.1", 0)
+	defer s.Close()
+
+	chals := []Challenge{
+		{Domain: "example.com", Type: acme.ChallengeTypeHTTP01, Token: "token1", KeyAuthorization: "token1.thumb"},
+		{Domain: "www.example.com", Type: acme.ChallengeTypeHTTP01, Token: "token2", KeyAuthorization: "token2.thumb"},
+	}
+	if err := s.Perform(chals); err != nil {
+		t.Fatalf("error performing: %v", err)
+	}
+	addr := s.Addr()
+	if addr == nil {
+		t.Fatalf("listener not started")
+	}
+	base := "http://" + addr.String()
+
+	for _, chal := range chals {
+		status, body := httpGet(t, base+http01Path+chal.Token)
+		if status != http.StatusOK || body != chal.KeyAuthorization {
+			t.Errorf("unexpected response for %s: %d %q", chal.Token, status, body)
+		}
+	}
+	if status, _ := httpGet(t, base+http01Path+"nope"); status != http.StatusNotFound {
+		t.Errorf("expected not found for unknown token, got: %d", status)
+	}
+	if status, _ := httpGet(t, base+"/token1"); status != http.StatusNotFound {
+		t.Errorf("expected not found for unknown path, got: %d", status)
+	}
+
+	// cleaning up some challenges keeps the listener running for the rest
+	if err := s.Cleanup(chals[:1]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if status, _ := httpGet(t, base+http01Path+chals[0].Token); status != http.StatusNotFound {
+		t.Errorf("expected cleaned up token to be not found, got: %d", status)
+	}
+	if status, _ := httpGet(t, base+http01Path+chals[1].Token); status != http.StatusOK {
+		t.Errorf("expected remaining token to be served, got: %d", status)
+	}
+
+	// and cleaning up the rest stops the listener
+	if err := s.Cleanup(chals[1:]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if s.Addr() != nil {
+		t.Errorf("expected listener to be stopped")
+	}
+	if _, err := net.Dial("tcp", addr.String()); err == nil {
+		t.Errorf("expected listener to be closed")
+	}
+
+	// closing again is fine
+	if err := s.Close(); err != nil {
+		t.Errorf("unexpected error closing twice: %v", err)
+	}
+}
+
+func TestStandalone_Perform(t *testing.T) {
+	s := NewStandalone("127.0.0.1", 0, "127.0.0.1", 0)
+	defer s.Close()
+
+	if err := s.Perform([]Challenge{{Type: acme.ChallengeTypeDNS01}}); err == nil {
+		t.Errorf("expected error for unsupported challenge type")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	busy := NewStandalone("127.0.0.1", l.Addr().(*net.TCPAddr).Port, "127.0.0.1", l.Addr().(*net.TCPAddr).Port)
+	if err := busy.Perform([]Challenge{{Type: acme.ChallengeTypeHTTP01}}); err == nil {
+		t.Errorf("expected error binding to port in use")
+	}
+	if err := busy.Perform([]Challenge{{Type: acme.ChallengeTypeTLSALPN01}}); err == nil {
+		t.Errorf("expected error binding tls to port in use")
+	}
+}
+
+func TestStandalone_TLSALPN(t *testing.T) {
+	s := NewStandalone("127.0.0.1", 0, "127.0.0.1", 0)
+	defer s.Close()
+
+	chals := []Challenge{
+		{Domain: "example.com", Type: acme.ChallengeTypeTLSALPN01, Token: "token1", KeyAuthorization: "token1.thumb"},
+		{Domain: "www.example.com", Type: acme.ChallengeTypeTLSALPN01, Token: "token2", KeyAuthorization: "token2.thumb"},
+		{Domain: "192.0.2.1", Type: acme.ChallengeTypeTLSALPN01, Token: "token3", KeyAuthorization: "token3.thumb"},
+		{Domain: "2001:db8::1", Type: acme.ChallengeTypeTLSALPN01, Token: "token4", KeyAuthorization: "token4.thumb"},
+	}
+	// ip addresses are validated using their reverse mapping domain name as the server name
+	serverNames := map[string]string{
+		"example.com":     "example.com",
+		"www.example.com": "www.example.com",
+		"192.0.2.1":       "1.2.0.192.in-addr.arpa",
+		"2001:db8::1":     "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa",
+	}
+	if err := s.Perform(chals); err != nil {
+		t.Fatalf("error performing: %v", err)
+	}
+	if s.Addr() != nil {
+		t.Errorf("expected http listener to not be started")
+	}
+	addr := s.TLSAddr()
+	if addr == nil {
+		t.Fatalf("tls listener not started")
+	}
+
+	dial := func(serverName string, protos []string) (*tls.Conn, error) {
+		return tls.Dial("tcp", addr.String(), &tls.Config{
+			ServerName:         serverName,
+			NextProtos:         protos,
+			InsecureSkipVerify: true,
+		})
+	}
+
+	for _, chal := range chals {
+		conn, err := dial(serverNames[chal.Domain], []string{tlsALPNProtocol})
+		if err != nil {
+			t.Fatalf("error connecting for %s: %v", chal.Domain, err)
+		}
+		state := conn.ConnectionState()
+		conn.Close()
+		if state.NegotiatedProtocol != tlsALPNProtocol {
+			t.Errorf("unexpected negotiated protocol: %q", state.NegotiatedProtocol)
+		}
+		cert := state.PeerCertificates[0]
+		if ip := net.ParseIP(chal.Domain); ip != nil {
+			if len(cert.DNSNames) != 0 || len(cert.IPAddresses) != 1 || !cert.IPAddresses[0].Equal(ip) {
+				t.Errorf("unexpected certificate names: %v %v", cert.DNSNames, cert.IPAddresses)
+			}
+		} else if len(cert.DNSNames) != 1 || cert.DNSNames[0] != chal.Domain || len(cert.IPAddresses) != 0 {
+			t.Errorf("unexpected certificate names: %v %v", cert.DNSNames, cert.IPAddresses)
+		}
+		digest := sha256.Sum256([]byte(chal.KeyAuthorization))
+		wantExt, _ := asn1.Marshal(digest[:])
+		found := false
+		for _, ext := range cert.Extensions {
+			if ext.Id.Equal(idPeAcmeIdentifier) {
+				found = true
+				if !ext.Critical || !bytes.Equal(ext.Value, wantExt) {
+					t.Errorf("unexpected acmeIdentifier extension: %+v", ext)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("certificate missing acmeIdentifier extension")
+		}
+	}
+
+	if _, err := dial("example.com", nil); err == nil {
+		t.Errorf("expected error connecting without acme-tls/1")
+	}
+	if _, err := dial("other.com", []string{tlsALPNProtocol}); err == nil {
+		t.Errorf("expected error connecting for unknown server name")
+	}
+	if _, err := dial("192.0.2.1", []string{tlsALPNProtocol}); err == nil {
+		t.Errorf("expected error connecting with an ip address as the server name")
+	}
+
+	if err := s.Cleanup(chals[:1]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if _, err := dial(chals[0].Domain, []string{tlsALPNProtocol}); err == nil {
+		t.Errorf("expected error connecting for cleaned up challenge")
+	}
+	if err := s.Cleanup(chals[1:]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if s.TLSAddr() != nil {
+		t.Errorf("expected tls listener to be stopped")
+	}
+}
@@ -0,0 +1,94 @@
+package authenticator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// tlsALPNProtocol is the alpn protocol negotiated for tls-alpn-01 challenges
+	tlsALPNProtocol = "acme-tls/1"
+)
+
+var (
+	// idPeAcmeIdentifier is the oid of the acmeIdentifier certificate extension
+	// as per https://tools.ietf.org/html/rfc8737#section-6.1
+	idPeAcmeIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+)
+
+// newTLSALPNCertificate returns a self-signed certificate for the identifier which carries the sha256 digest of the key
+// authorization in the critical acmeIdentifier extension, as per https://tools.ietf.org/html/rfc8737#section-3
+// An ip address identifier is put in the certificate as an ip address rather than a dns name, as per
+// https://tools.ietf.org/html/rfc8738#section-6
+func newTLSALPNCertificate(identifier, keyAuth string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating tls-alpn-01 key: %v", err)
+	}
+
+	digest := sha256.Sum256([]byte(keyAuth))
+	extValue, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("error encoding acmeIdentifier extension: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("error generating tls-alpn-01 serial: %v", err)
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "certgot tls-alpn-01 challenge"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		ExtraExtensions: []pkix.Extension{
+			{Id: idPeAcmeIdentifier, Critical: true, Value: extValue},
+		},
+	}
+	if ip := net.ParseIP(identifier); ip != nil {
+		tmpl.IPAddresses = []net.IP{ip}
+	} else {
+		tmpl.DNSNames = []string{identifier}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating tls-alpn-01 certificate: %v", err)
+	}
+
+	return &tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
+
+// tlsALPNServerName returns the tls server name the acme server sends when validating the identifier
+// An ip address can't be sent as a server name, so its reverse mapping domain name is sent instead, eg
+// 1.2.0.192.in-addr.arpa, as per https://tools.ietf.org/html/rfc8738#section-6
+func tlsALPNServerName(identifier string) string {
+	ip := net.ParseIP(identifier)
+	if ip == nil {
+		return strings.ToLower(identifier)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa", ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+	var b strings.Builder
+	for i := len(ip) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%x.%x.", ip[i]&0xf, ip[i]>>4)
+	}
+	b.WriteString("ip6.arpa")
+	return b.String()
+}
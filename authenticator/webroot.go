@@ -0,0 +1,205 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/util"
+)
+
+const (
+	WebrootName = "webroot"
+)
+
+// Webroot is an authenticator which places http-01 challenge files into the document root of an existing webserver
+type Webroot struct {
+	// Map holds the webroot path to use for each domain
+	Map map[string]string
+
+	mu sync.Mutex
+	// created holds the directories created under each webroot, in the order they were created
+	created map[string][]string
+}
+
+// NewWebroot returns a webroot authenticator using the provided map of domain to webroot path
+func NewWebroot(webrootMap map[string]string) *Webroot {
+	return &Webroot{
+		Map:     webrootMap,
+		created: map[string][]string{},
+	}
+}
+
+// ParseWebrootMap parses the json webroot map as used in the `--webroot-map` flag and renewal config files
+// Keys can be a comma separated list of domains, eg `{"example.com,www.example.com":"/var/www/html"}`
+func ParseWebrootMap(s string) (map[string]string, error) {
+	raw := map[string]string{}
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("error parsing webroot map: %v", err)
+	}
+	m := map[string]string{}
+	for domains, webroot := range raw {
+		for _, d := range strings.Split(domains, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "" {
+				continue
+			}
+			m[d] = webroot
+		}
+	}
+	return m, nil
+}
+
+// FormatWebrootMap formats the webroot map as json, the inverse of ParseWebrootMap
+func FormatWebrootMap(m map[string]string) string {
+	// encoding/json sorts map keys, so the output is stable
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func (w *Webroot) Name() string {
+	return WebrootName
+}
+
+func (w *Webroot) ChallengeTypes() []string {
+	return []string{acme.ChallengeTypeHTTP01}
+}
+
+func (w *Webroot) Perform(chals []Challenge) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, chal := range chals {
+		if chal.Type != acme.ChallengeTypeHTTP01 {
+			return fmt.Errorf("webroot authenticator does not support challenge type %s", chal.Type)
+		}
+		if _, ok := w.Map[chal.Domain]; !ok {
+			return fmt.Errorf("missing webroot path for domain %s", chal.Domain)
+		}
+	}
+
+	for _, chal := range chals {
+		webroot := w.Map[chal.Domain]
+		ll := log.WithFields("domain", chal.Domain, "webroot", webroot, "token", chal.Token)
+
+		info, err := os.Stat(webroot)
+		if err != nil {
+			return fmt.Errorf("error reading webroot path %s for domain %s: %v", webroot, chal.Domain, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("webroot path %s for domain %s is not a directory", webroot, chal.Domain)
+		}
+
+		dir, err := w.makeChallengeDir(webroot, info)
+		if err != nil {
+			return err
+		}
+
+		p := filepath.Join(dir, chal.Token)
+		ll.WithField("path", p).Debug("webroot writing challenge file")
+		if err := ioutil.WriteFile(p, []byte(chal.KeyAuthorization), 0644); err != nil {
+			return fmt.Errorf("error writing challenge file %s: %v", p, err)
+		}
+		// explicitly set the mode as it may have been masked by the umask
+		if err := os.Chmod(p, 0644); err != nil {
+			return fmt.Errorf("error setting permissions on challenge file %s: %v", p, err)
+		}
+		copyOwner(p, info)
+	}
+
+	return nil
+}
+
+func (w *Webroot) Cleanup(chals []Challenge) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []string
+	webroots := map[string]bool{}
+	for _, chal := range chals {
+		webroot, ok := w.Map[chal.Domain]
+		if !ok {
+			continue
+		}
+		webroots[webroot] = true
+		p := filepath.Join(webroot, http01Path, chal.Token)
+		log.WithFields("domain", chal.Domain, "path", p).Debug("webroot removing challenge file")
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, fmt.Sprintf("error removing challenge file %s: %v", p, err))
+		}
+	}
+
+	var sorted []string
+	for webroot := range webroots {
+		sorted = append(sorted, webroot)
+	}
+	sort.Strings(sorted)
+
+	for _, webroot := range sorted {
+		created := w.created[webroot]
+		var remaining []string
+		// remove in reverse order of creation so child directories are removed first
+		for i := len(created) - 1; i >= 0; i-- {
+			dir := created[i]
+			if err := os.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+				// most likely still has challenge files from other domains in it
+				log.WithError(err).WithField("dir", dir).Debug("webroot not removing directory")
+				remaining = append([]string{dir}, remaining...)
+				continue
+			}
+			log.WithField("dir", dir).Debug("webroot removed directory")
+		}
+		if len(remaining) == 0 {
+			delete(w.created, webroot)
+		} else {
+			w.created[webroot] = remaining
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// makeChallengeDir creates the challenge directory under the webroot if it doesn't exist, with any directories created
+// having the same mode and owner as the webroot
+// Must be called with the lock held
+func (w *Webroot) makeChallengeDir(webroot string, info os.FileInfo) (string, error) {
+	dir := webroot
+	for _, name := range strings.Split(strings.Trim(http01Path, "/"), "/") {
+		dir = filepath.Join(dir, name)
+		if _, err := os.Stat(dir); err == nil {
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("error reading challenge directory %s: %v", dir, err)
+		}
+		log.WithField("dir", dir).Debug("webroot creating directory")
+		if err := os.Mkdir(dir, info.Mode().Perm()); err != nil {
+			return "", fmt.Errorf("error creating challenge directory %s: %v", dir, err)
+		}
+		w.created[webroot] = append(w.created[webroot], dir)
+		// explicitly set the mode as it may have been masked by the umask
+		if err := os.Chmod(dir, info.Mode().Perm()); err != nil {
+			return "", fmt.Errorf("error setting permissions on challenge directory %s: %v", dir, err)
+		}
+		copyOwner(dir, info)
+	}
+	return dir, nil
+}
+
+// copyOwner tries to match the owner of path to the webroot, which is only possible when running as root
+// so any failure is only logged
+func copyOwner(path string, info os.FileInfo) {
+	if err := util.CopyOwner(path, info); err != nil {
+		log.WithError(err).WithField("path", path).Debug("webroot unable to match webroot owner")
+	}
+}
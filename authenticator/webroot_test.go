@@ -0,0 +1,204 @@
+package authenticator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/eggsampler/certgot/acme"
+)
+
+func TestParseWebrootMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "invalid",
+			s:       "{",
+			wantErr: true,
+		},
+		{
+			name: "empty",
+			s:    "{}",
+			want: map[string]string{},
+		},
+		{
+			name: "single",
+			s:    `{"example.com": "/var/www"}`,
+			want: map[string]string{"example.com": "/var/www"},
+		},
+		{
+			name: "comma separated",
+			s:    `{"example.com, WWW.example.com": "/var/www", "other.com": "/srv/other"}`,
+			want: map[string]string{
+				"example.com":     "/var/www",
+				"www.example.com": "/var/www",
+				"other.com":       "/srv/other",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWebrootMap(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWebrootMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseWebrootMap() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatWebrootMap(t *testing.T) {
+	m := map[string]string{"www.example.com": "/var/www", "example.com": "/var/www"}
+	want := `{"example.com":"/var/www","www.example.com":"/var/www"}`
+	if got := FormatWebrootMap(m); got != want {
+		t.Errorf("FormatWebrootMap() got = %s, want %s", got, want)
+	}
+	parsed, err := ParseWebrootMap(want)
+	if err != nil {
+		t.Fatalf("error parsing formatted map: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, m) {
+		t.Errorf("round trip got = %v, want %v", parsed, m)
+	}
+}
+
+func TestWebroot(t *testing.T) {
+	root1, err := ioutil.TempDir("", "webroot1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root1)
+	root2, err := ioutil.TempDir("", "webroot2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root2)
+	if err := os.Chmod(root1, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	// root2 already has a .well-known directory which must be left alone
+	wellKnown := filepath.Join(root2, ".well-known")
+	if err := os.Mkdir(wellKnown, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWebroot(map[string]string{
+		"example.com":     root1,
+		"www.example.com": root1,
+		"other.com":       root2,
+	})
+	chals := []Challenge{
+		{Domain: "example.com", Type: acme.ChallengeTypeHTTP01, Token: "token1", KeyAuthorization: "token1.thumb"},
+		{Domain: "www.example.com", Type: acme.ChallengeTypeHTTP01, Token: "token2", KeyAuthorization: "token2.thumb"},
+		{Domain: "other.com", Type: acme.ChallengeTypeHTTP01, Token: "token3", KeyAuthorization: "token3.thumb"},
+	}
+	if err := w.Perform(chals); err != nil {
+		t.Fatalf("error performing: %v", err)
+	}
+
+	for _, chal := range chals {
+		p := filepath.Join(w.Map[chal.Domain], http01Path, chal.Token)
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatalf("error reading challenge file: %v", err)
+		}
+		if string(b) != chal.KeyAuthorization {
+			t.Errorf("unexpected challenge file contents %s: %q", p, string(b))
+		}
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0644 {
+			t.Errorf("unexpected challenge file mode %s: %v", p, fi.Mode())
+		}
+	}
+	for _, dir := range []string{filepath.Join(root1, ".well-known"), filepath.Join(root1, http01Path)} {
+		fi, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0750 {
+			t.Errorf("unexpected directory mode %s: %v", dir, fi.Mode())
+		}
+	}
+
+	// cleaning up one challenge leaves the directory for the other
+	if err := w.Cleanup(chals[:1]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root1, http01Path, chals[0].Token)); !os.IsNotExist(err) {
+		t.Errorf("expected challenge file to be removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root1, http01Path, chals[1].Token)); err != nil {
+		t.Errorf("expected challenge file to remain: %v", err)
+	}
+
+	if err := w.Cleanup(chals[1:]); err != nil {
+		t.Fatalf("error cleaning up: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root1, ".well-known")); !os.IsNotExist(err) {
+		t.Errorf("expected created directory to be removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root2, http01Path)); !os.IsNotExist(err) {
+		t.Errorf("expected created directory to be removed: %v", err)
+	}
+	if _, err := os.Stat(wellKnown); err != nil {
+		t.Errorf("expected existing directory to remain: %v", err)
+	}
+}
+
+func TestWebroot_Perform(t *testing.T) {
+	root, err := ioutil.TempDir("", "webroot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	file := filepath.Join(root, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		webrootMap map[string]string
+		chal       Challenge
+	}{
+		{
+			name:       "bad type",
+			webrootMap: map[string]string{"example.com": root},
+			chal:       Challenge{Domain: "example.com", Type: acme.ChallengeTypeDNS01},
+		},
+		{
+			name: "missing domain",
+			chal: Challenge{Domain: "example.com", Type: acme.ChallengeTypeHTTP01},
+		},
+		{
+			name:       "missing webroot",
+			webrootMap: map[string]string{"example.com": filepath.Join(root, "nope")},
+			chal:       Challenge{Domain: "example.com", Type: acme.ChallengeTypeHTTP01},
+		},
+		{
+			name:       "not a directory",
+			webrootMap: map[string]string{"example.com": file},
+			chal:       Challenge{Domain: "example.com", Type: acme.ChallengeTypeHTTP01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWebroot(tt.webrootMap)
+			if err := w.Perform([]Challenge{tt.chal}); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -62,6 +62,9 @@ func (app *App) Run(args []string) (err error) {
 
 	// execute any flag functions
 	for _, f := range ctx.Flags {
+		if f.PostParseFunc == nil {
+			continue
+		}
 		if err := f.PostParseFunc(f, &ctx); err != nil {
 			return fmt.Errorf("error on flag %s PostParseFunc: %w", f.Name, err)
 		}
@@ -81,7 +84,7 @@ func (app *App) Run(args []string) (err error) {
 
 	// run the app post run
 	if app.PostRunFunc != nil {
-		if postErr := app.PostRunFunc(&ctx, err); err != nil {
+		if postErr := app.PostRunFunc(&ctx, err); postErr != nil {
 			return fmt.Errorf("error in app PostRunFunc: %w", postErr)
 		}
 	}
@@ -20,8 +20,8 @@ func parseArguments(argsToParse []string, ctx *Context, validFlags FlagList, val
 	// this is used to hold a flag if it takes a value, so the next argument can be applied as the value to that flag
 	var lastFlagExpectingValue *Flag
 
-	// parse all of the arguments, excluding
-	for _, arg := range argsToParse[1:] {
+	// parse all of the arguments, excluding the binary name
+	for argIndex, arg := range argsToParse[1:] {
 
 		// first check if it's a flag
 		if strings.HasPrefix(arg, "-") {
@@ -102,6 +102,7 @@ func parseArguments(argsToParse []string, ctx *Context, validFlags FlagList, val
 				RawFlag:  flagMatch[1],
 				HasValue: hasValue,
 				Value:    value,
+				Position: argIndex + 1,
 			})
 
 		} else if lastFlagExpectingValue != nil {
@@ -157,6 +157,48 @@ func Test_parseArguments(t *testing.T) {
 				return nil
 			},
 		},
+		{
+			name: "ok alt name",
+			args: args{
+				argsToParse: []string{"bin", "-d", "1"},
+				ctx:         &Context{},
+				fl:          FlagList{&Flag{Name: "domain", AltNames: []string{"d"}, TakesValue: true}},
+			},
+			checkFunc: func(ctx *Context) error {
+				f := ctx.Flags.Get("domain")
+				if f == nil {
+					return errors.New("ctx didn't include flag")
+				}
+				if len(f.valuesRaw) == 0 || f.valuesRaw[0] != "1" {
+					return fmt.Errorf("unexpected flag value: %+v", f.valuesRaw)
+				}
+				return nil
+			},
+		},
+		{
+			name: "value positions",
+			args: args{
+				argsToParse: []string{"bin", "-w", "a", "-d", "1", "-w=b", "-d", "2"},
+				ctx:         &Context{},
+				fl: FlagList{
+					&Flag{Name: "d", TakesValue: true, AllowMultiple: true},
+					&Flag{Name: "w", TakesValue: true, AllowMultiple: true},
+				},
+			},
+			checkFunc: func(ctx *Context) error {
+				var positions []int
+				for _, v := range ctx.Flags.Get("w").ValueList() {
+					positions = append(positions, v.Position)
+				}
+				for _, v := range ctx.Flags.Get("d").ValueList() {
+					positions = append(positions, v.Position)
+				}
+				if !reflect.DeepEqual(positions, []int{1, 5, 3, 6}) {
+					return fmt.Errorf("unexpected positions: %+v", positions)
+				}
+				return nil
+			},
+		},
 		{
 			name: "invalid command",
 			args: args{
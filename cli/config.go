@@ -59,6 +59,17 @@ func (c *Config) set(v []string, src ConfigSource) error {
 	return nil
 }
 
+// Set sets the value of the config, as if it was provided by the source
+func (c *Config) Set(v []string, src ConfigSource) error {
+	return c.set(v, src)
+}
+
+// Unset removes any value set, so the default is used
+func (c *Config) Unset() {
+	c.value = nil
+	c.isSet = false
+}
+
 func (c Config) Bool() bool {
 	return c.isSet
 }
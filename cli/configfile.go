@@ -17,7 +17,7 @@ import (
 
 var (
 	// TODO: ini reader?
-	configLine = regexp.MustCompile(`^([a-zA-Z\-]+)(?:\s*=\s*(.+))?$`)
+	configLine = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9\-]*)(?:\s*=\s*(.+))?$`)
 )
 
 type configFileEntry struct {
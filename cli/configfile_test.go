@@ -61,6 +61,24 @@ func Test_parseConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "config w/ number",
+			args: args{
+				s:        "http-01-port = 8080",
+				fileName: "hi2u3",
+			},
+			want: map[string][]configFileEntry{
+				"http-01-port": {
+					{
+						fileName: "hi2u3",
+						line:     1,
+						key:      "http-01-port",
+						hasValue: true,
+						value:    "8080",
+					},
+				},
+			},
+		},
 		{
 			name: "config w/o value",
 			args: args{
@@ -14,7 +14,7 @@ func (fl FlagList) Get(name string) *Flag {
 			return f
 		}
 		for _, an := range f.AltNames {
-			if strings.EqualFold(an, f.Name) {
+			if strings.EqualFold(an, name) {
 				return f
 			}
 		}
@@ -36,6 +36,10 @@ type FlagValue struct {
 	RawFlag  string
 	HasValue bool
 	Value    string
+
+	// Position is the index of the flag in the argument list
+	// Can be used to determine the order flags were provided in, relative to other flags
+	Position int
 }
 
 // Flag represents an argument that is prefixed by a single dash, or two dashes
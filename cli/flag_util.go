@@ -15,6 +15,21 @@ func SetConfigValue(name string) func(f *Flag, ctx *Context) error {
 	}
 }
 
+// SetConfigFixedValue sets a config to a fixed value when the flag is present, for flags that don't take a value
+// eg, `--standalone` setting the authenticator config to "standalone"
+func SetConfigFixedValue(name string, values ...string) func(f *Flag, ctx *Context) error {
+	return func(f *Flag, ctx *Context) error {
+		cfg := ctx.App.Configs.Get(name)
+		if cfg == nil {
+			return fmt.Errorf("no config %q for flag %q", name, f.Name)
+		}
+		return cfg.set(values, ConfigSource{
+			Source: SourceFlag,
+			Extra:  f.Name,
+		})
+	}
+}
+
 func GetConfigDefault(name string) func(*Context) (string, error) {
 	return func(ctx *Context) (string, error) {
 		cfg := ctx.App.Configs.Get(name)
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
+)
+
+const (
+	// accountKeySize is the size of rsa keys generated for new accounts, the same as certbot
+	accountKeySize = 2048
+)
+
+var (
+	errNoAccount = errors.New("no account found")
+)
+
+// getAccount returns the saved account for the server, registering and saving a new account if there isn't one
+func getAccount(client *acme.Client) (*storage.Account, error) {
+	acct, err := loadAccount()
+	if errors.Is(err, errNoAccount) {
+		acct, err = registerAccount(client)
+	}
+	return acct, err
+}
+
+// loadAccount loads the saved account for the server, returning errNoAccount if there are none
+// The account set by --account, or stored in the renewal params when renewing, is loaded if set and must exist,
+// otherwise if there is more than one account the first is used
+func loadAccount() (*storage.Account, error) {
+	server := cfgServer.String()
+	if cfgAccount.IsSet() {
+		id := cfgAccount.String()
+		acct, err := getStorage().Account(server, id)
+		if errors.Is(err, storage.ErrAccountNotFound) {
+			return nil, fmt.Errorf("account %s not found for server %s", id, server)
+		} else if err != nil {
+			return nil, err
+		}
+		log.WithFields("server", server, "id", acct.ID, "account", acct.URI).Debug("loaded account")
+		return acct, nil
+	}
+
+	accounts, err := getStorage().Accounts(server)
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("%w for server %s", errNoAccount, server)
+	}
+	if len(accounts) > 1 {
+		log.WithFields("server", server, "count", len(accounts)).Debug("multiple accounts found, using the first")
+	}
+	log.WithFields("server", server, "id", accounts[0].ID, "account", accounts[0].URI).Debug("loaded account")
+	return accounts[0], nil
+}
+
+// registerAccount registers a new account with the acme server and saves it
+func registerAccount(client *acme.Client) (*storage.Account, error) {
+	if err := agreeTOS(client); err != nil {
+		return nil, err
+	}
+	contacts, err := getContacts()
+	if err != nil {
+		return nil, err
+	}
+	key, err := rsa.GenerateKey(rand.Reader, accountKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("error generating account key: %v", err)
+	}
+	acct, err := client.NewAccount(key, contacts, true)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("account", acct.URL).Debug("registered account")
+
+	saved, err := storage.NewAccount(cfgServer.String(), acct.URL, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := getStorage().SaveAccount(saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func toACMEAccount(acct *storage.Account) acme.Account {
+	return acme.Account{
+		URL:        acct.URI,
+		PrivateKey: acct.Key,
+	}
+}
+
+// formatContacts returns the email addresses of the contacts, without the mailto: prefix
+func formatContacts(contacts []string) string {
+	var emails []string
+	for _, c := range contacts {
+		emails = append(emails, strings.TrimPrefix(c, "mailto:"))
+	}
+	return strings.Join(emails, ", ")
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/authenticator"
+	"github.com/eggsampler/certgot/log"
+)
+
+var (
+	// authenticatorNames is the list of authenticators that can be selected
+	authenticatorNames = []string{
+		authenticator.StandaloneName,
+		authenticator.WebrootName,
+		authenticator.ManualName,
+		authenticator.RFC2136Name,
+	}
+
+	// performedAuthenticator and performedChallenges hold any challenges that have been set up but not cleaned up,
+	// so they can be cleaned up when the program exits, even on error
+	performedAuthenticator authenticator.Authenticator
+	performedChallenges    []authenticator.Challenge
+)
+
+// getAuthenticator returns the authenticator selected by the user, to be used for the provided domains
+func getAuthenticator(domains []string) (authenticator.Authenticator, error) {
+	name := cfgAuthenticator.String()
+	if name == "" {
+		return nil, fmt.Errorf("no authenticator selected, use the --%s flag with one of: %s",
+			FLAG_AUTHENTICATOR, strings.Join(authenticatorNames, ", "))
+	}
+	return newAuthenticator(name, domains)
+}
+
+func newAuthenticator(name string, domains []string) (authenticator.Authenticator, error) {
+	log.WithField("authenticator", name).Debug("creating authenticator")
+	switch strings.ToLower(name) {
+	case authenticator.StandaloneName:
+		return authenticator.NewStandalone(cfgHTTP01Address.String(), cfgHTTP01Port.Int(),
+			cfgTLSALPN01Address.String(), cfgTLSALPN01Port.Int()), nil
+	case authenticator.WebrootName:
+		webrootMap, err := getWebrootMap(domains)
+		if err != nil {
+			return nil, err
+		}
+		return authenticator.NewWebroot(webrootMap), nil
+	case authenticator.ManualName:
+		return newManualAuthenticator()
+	case authenticator.RFC2136Name:
+		return newRFC2136Authenticator()
+	}
+	return nil, fmt.Errorf("unknown authenticator %q, valid authenticators: %s",
+		name, strings.Join(authenticatorNames, ", "))
+}
+
+// authenticate performs all challenges for the pending authorizations, responds to them and waits for them to be valid
+func authenticate(client *acme.Client, acct acme.Account, auth authenticator.Authenticator, authzs []acme.Authorization) error {
+	var chals []authenticator.Challenge
+	var acmeChals []acme.Challenge
+
+	types, err := challengeTypes(auth)
+	if err != nil {
+		return err
+	}
+
+	for _, authz := range authzs {
+		chal, err := selectChallenge(auth, types, authz)
+		if err != nil {
+			return err
+		}
+		keyAuth, err := acct.KeyAuthorization(chal.Token)
+		if err != nil {
+			return err
+		}
+		chals = append(chals, authenticator.Challenge{
+			Domain:           authz.Identifier.Value,
+			Type:             chal.Type,
+			Token:            chal.Token,
+			KeyAuthorization: keyAuth,
+		})
+		acmeChals = append(acmeChals, chal)
+	}
+
+	performedAuthenticator = auth
+	performedChallenges = chals
+	defer func() {
+		if err := cleanupAuthenticator(); err != nil {
+			log.WithError(err).Error("cleaning up challenges")
+		}
+	}()
+
+	log.WithFields("authenticator", auth.Name(), "count", len(chals)).Debug("performing challenges")
+	if err := auth.Perform(chals); err != nil {
+		return fmt.Errorf("error performing challenges with %s authenticator: %w", auth.Name(), err)
+	}
+
+	for _, chal := range acmeChals {
+		if _, err := client.UpdateChallenge(acct, chal); err != nil {
+			return err
+		}
+	}
+
+	for _, authz := range authzs {
+		if _, err := client.WaitAuthorization(acct, authz.URL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getPreferredChallenges returns the challenge types set with the preferred challenges flag, in order of preference
+// Accepts the same short names as certbot, eg "http" for http-01
+func getPreferredChallenges() ([]string, error) {
+	var types []string
+	seen := map[string]bool{}
+	for _, v := range cfgPreferredChallenges.StringSlice() {
+		for _, typ := range strings.Split(v, ",") {
+			typ = strings.ToLower(strings.TrimSpace(typ))
+			if typ == "" {
+				continue
+			}
+			if !strings.HasSuffix(typ, "-01") {
+				typ += "-01"
+			}
+			switch typ {
+			case acme.ChallengeTypeHTTP01, acme.ChallengeTypeDNS01, acme.ChallengeTypeTLSALPN01:
+			default:
+				return nil, fmt.Errorf("unsupported challenge type %q in --%s, valid types: %s",
+					typ, FLAG_PREFERRED_CHALLENGES, strings.Join([]string{acme.ChallengeTypeHTTP01, acme.ChallengeTypeDNS01, acme.ChallengeTypeTLSALPN01}, ", "))
+			}
+			if !seen[typ] {
+				seen[typ] = true
+				types = append(types, typ)
+			}
+		}
+	}
+	return types, nil
+}
+
+// challengeTypes returns the challenge types to use with the authenticator in order of preference,
+// being the preferred challenges supported by the authenticator if set, or all challenges supported by the authenticator
+func challengeTypes(auth authenticator.Authenticator) ([]string, error) {
+	preferred, err := getPreferredChallenges()
+	if err != nil {
+		return nil, err
+	}
+	if len(preferred) == 0 {
+		return auth.ChallengeTypes(), nil
+	}
+	supported := map[string]bool{}
+	for _, typ := range auth.ChallengeTypes() {
+		supported[typ] = true
+	}
+	var types []string
+	for _, typ := range preferred {
+		if supported[typ] {
+			types = append(types, typ)
+		}
+	}
+	if len(types) == 0 {
+		return nil, fmt.Errorf("none of the preferred challenges (%s) are supported by the %s authenticator, which supports: %s",
+			strings.Join(preferred, ", "), auth.Name(), strings.Join(auth.ChallengeTypes(), ", "))
+	}
+	return types, nil
+}
+
+// selectChallenge picks the first challenge type in types that is offered by the authorization
+func selectChallenge(auth authenticator.Authenticator, types []string, authz acme.Authorization) (acme.Challenge, error) {
+	var offered []string
+	for _, c := range authz.Challenges {
+		offered = append(offered, c.Type)
+	}
+	for _, typ := range types {
+		if chal, ok := authz.Challenge(typ); ok {
+			return chal, nil
+		}
+	}
+	return acme.Challenge{}, fmt.Errorf("%s authenticator does not support any of the challenges offered for %s: %s",
+		auth.Name(), authz.Identifier.Value, strings.Join(offered, ", "))
+}
+
+// cleanupAuthenticator cleans up any performed challenges
+func cleanupAuthenticator() error {
+	if performedAuthenticator == nil {
+		return nil
+	}
+	auth, chals := performedAuthenticator, performedChallenges
+	performedAuthenticator, performedChallenges = nil, nil
+	log.WithFields("authenticator", auth.Name(), "count", len(chals)).Debug("cleaning up challenges")
+	if err := auth.Cleanup(chals); err != nil {
+		return fmt.Errorf("error cleaning up challenges with %s authenticator: %w", auth.Name(), err)
+	}
+	return nil
+}
+
+// newManualAuthenticator returns a manual authenticator, which prompts the user to set up challenges if no auth hook
+// is provided and running interactively
+func newManualAuthenticator() (authenticator.Authenticator, error) {
+	authHook := cfgManualAuthHook.String()
+	if authHook == "" && !isInteractive() {
+		return nil, fmt.Errorf("an authentication script must be provided with --%s when using the manual authenticator non-interactively",
+			FLAG_MANUAL_AUTH_HOOK)
+	}
+	return authenticator.NewManual(authHook, cfgManualCleanupHook.String(), promptInstructions), nil
+}
+
+// newRFC2136Authenticator returns an rfc2136 authenticator using the credentials file provided
+func newRFC2136Authenticator() (authenticator.Authenticator, error) {
+	path := cfgDNSRFC2136Credentials.String()
+	if path == "" {
+		return nil, fmt.Errorf("no credentials file provided, use the --%s flag", FLAG_DNS_RFC2136_CREDENTIALS)
+	}
+	creds, err := authenticator.LoadRFC2136Credentials(path)
+	if err != nil {
+		return nil, err
+	}
+	propagation := time.Duration(cfgDNSRFC2136PropagationSeconds.Int()) * time.Second
+	return authenticator.NewRFC2136(creds, propagation), nil
+}
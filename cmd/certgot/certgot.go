@@ -25,14 +25,74 @@ func main() {
 			flagConfigDir,
 			flagDomains,
 			flagCertName,
+			flagCertPath,
+			flagKeyPath,
 			flagNonInteractive,
 			flagForceInteractive,
+			flagDryRun,
+			flagForceRenewal,
+			flagServer,
+			flagAccount,
+			flagEmail,
+			flagAgreeTOS,
+			flagRegisterUnsafelyWithoutEmail,
+			flagAuthenticator,
+			flagInstaller,
+			flagNginx,
+			flagApache,
+			flagStandalone,
+			flagHTTP01Port,
+			flagHTTP01Address,
+			flagTLSALPN01Port,
+			flagTLSALPN01Address,
+			flagPreferredChallenges,
+			flagWebroot,
+			flagWebrootPath,
+			flagWebrootMap,
+			flagManual,
+			flagManualAuthHook,
+			flagManualCleanupHook,
+			flagDNSRFC2136,
+			flagDNSRFC2136Credentials,
+			flagDNSRFC2136PropagationSeconds,
+			flagReason,
+			flagDeleteAfterRevoke,
+			flagNoDeleteAfterRevoke,
+			flagForceDelete,
+			flagNginxServerRoot,
+			flagNginxCtl,
+			flagApacheServerRoot,
+			flagApacheCtl,
+			flagCheckpoints,
+			flagKeyType,
+			flagRSAKeySize,
+			flagEllipticCurve,
+			flagReuseKey,
+			flagNewKey,
+			flagMustStaple,
+			flagRedirect,
+			flagHSTS,
+			flagUIR,
+			flagStapleOCSP,
+			flagCSR,
+			flagChainPath,
+			flagFullChainPath,
 		},
 
 		Commands: cli.CommandList{
 			cmdRun,
 			cmdCertOnly,
+			cmdInstall,
+			cmdRenew,
 			cmdCertificates,
+			cmdRevoke,
+			cmdDelete,
+			cmdRollback,
+			cmdEnhance,
+			cmdRegister,
+			cmdUpdateAccount,
+			cmdUnregister,
+			cmdShowAccount,
 			cmdHelp,
 		},
 
@@ -41,14 +101,65 @@ func main() {
 			cfgLogsDir,
 			cfgConfigDir,
 			cfgWorkDir,
+			cfgDomains,
+			cfgCertName,
+			cfgCertPath,
+			cfgKeyPath,
+			cfgNonInteractive,
+			cfgForceInteractive,
+			cfgDryRun,
+			cfgForceRenewal,
+			cfgServer,
+			cfgAccount,
+			cfgEmail,
+			cfgAgreeTOS,
+			cfgRegisterUnsafelyWithoutEmail,
+			cfgAuthenticator,
+			cfgInstaller,
+			cfgHTTP01Port,
+			cfgHTTP01Address,
+			cfgTLSALPN01Port,
+			cfgTLSALPN01Address,
+			cfgPreferredChallenges,
+			cfgWebrootPath,
+			cfgWebrootMap,
+			cfgManualAuthHook,
+			cfgManualCleanupHook,
+			cfgDNSRFC2136Credentials,
+			cfgDNSRFC2136PropagationSeconds,
+			cfgReason,
+			cfgDeleteAfterRevoke,
+			cfgNoDeleteAfterRevoke,
+			cfgForceDelete,
+			cfgNginxServerRoot,
+			cfgNginxCtl,
+			cfgApacheServerRoot,
+			cfgApacheCtl,
+			cfgCheckpoints,
+			cfgKeyType,
+			cfgRSAKeySize,
+			cfgEllipticCurve,
+			cfgReuseKey,
+			cfgNewKey,
+			cfgMustStaple,
+			cfgRedirect,
+			cfgHSTS,
+			cfgUIR,
+			cfgStapleOCSP,
+			cfgCSR,
+			cfgChainPath,
+			cfgFullChainPath,
 		},
 
 		Help: cli.HelpCategories{
 			catUsage,
 			catCommon,
 			catManageCerts,
+			catManageAccount,
 			catOptional,
 			catPaths,
+			catSecurity,
+			catPlugins,
 		},
 
 		PreRunFunc:  doPreRun,
@@ -2,14 +2,13 @@ package main
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/eggsampler/certgot/cli"
 	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
 	"github.com/eggsampler/certgot/util"
-	"gopkg.in/ini.v1"
 )
 
 const (
@@ -25,23 +24,12 @@ var (
 		UsageDescription:    "Display information about certificates you have from Certbot",
 		ArgumentDescription: "List certificates managed by Certbot",
 	}
-
-	certSections = []string{"cert", "privkey", "chain", "fullchain"}
 )
 
 func commandCertificates(ctx *cli.Context) error {
-	configDir := cfgConfigDir.String()
-	if len(configDir) == 0 {
+	if len(cfgConfigDir.String()) == 0 {
 		return fmt.Errorf("no configuration directory")
 	}
-	renewalDir := filepath.Join(configDir, "renewal")
-	renewalConfPattern := filepath.Join(renewalDir, "*.conf")
-	renewalFiles, err := filepath.Glob(renewalConfPattern)
-	if err != nil {
-		log.WithField("path", renewalConfPattern).Error("globbing renewal files")
-		return fmt.Errorf("error finding renewal files: %v", err)
-	}
-	log.WithField("path", renewalConfPattern).WithField("count", len(renewalFiles)).Debug("found renewals files")
 
 	wantedCertName := ""
 	if cfgCertName.IsSet() {
@@ -62,52 +50,23 @@ func commandCertificates(ctx *cli.Context) error {
 
 	var foundCerts []foundCert
 
-	for _, f := range renewalFiles {
-		ll := log.WithField("renewalfile", f)
-		ll.Debug("reading")
-
-		cfg, err := ini.Load(f)
-		if err != nil {
-			ll.WithError(err).Error("opening renewal file")
-			return fmt.Errorf("error loading renewal file %s: %v", f, err)
-		}
-
-		skip := false
-		for _, v := range certSections {
-			if !cfg.Section("").HasKey(v) {
-				ll.WithField("section", v).Error("missing required section")
-				fmt.Printf("Renewal configuration file %s is missing required section %q. Skipping.\n", f, v)
-				skip = true
-				break
-			}
-		}
-		if skip {
-			continue
-		}
-
-		certName := filepath.Base(f)
-		certName = strings.TrimSuffix(certName, filepath.Ext(certName))
-
-		if wantedCertName != "" && !strings.EqualFold(certName, wantedCertName) {
-			ll.WithField("wantedCertName", wantedCertName).
-				WithField("certName", certName).
-				Debug("skipping due to cert name mismatch")
-			continue
-		}
+	lineages, _ := loadLineages(wantedCertName)
+	for _, l := range lineages {
+		ll := log.WithField("renewalfile", l.ConfigPath)
 
 		fc := foundCert{
-			name:     certName,
-			certPath: cfg.Section("").Key("cert").String(),
-			keyPath:  cfg.Section("").Key("privkey").String(),
+			name:     l.Name,
+			certPath: l.CertPath,
+			keyPath:  l.PrivKeyPath,
 		}
 
-		cert, err := util.ReadCertificate(fc.certPath)
+		cert, err := l.Certificate()
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
 
-		chain, err := util.ReadCertificate(cfg.Section("").Key("chain").String())
+		chain, err := util.ReadCertificate(l.ChainPath)
 		if err != nil {
 			fmt.Println(err)
 			continue
@@ -122,7 +81,7 @@ func commandCertificates(ctx *cli.Context) error {
 		fc.domains = cert.DNSNames
 		fc.expiry = cert.NotAfter
 
-		if strings.Contains(cfg.Section("renewalparams").Key("server").String(), "staging") {
+		if strings.Contains(l.RenewalParam("server"), "staging") {
 			fc.validStr = "INVALID: TEST_CERT"
 		} else if fc.expiry.Before(time.Now()) {
 			fc.validStr = "INVALID: EXPIRED"
@@ -167,3 +126,37 @@ func commandCertificates(ctx *cli.Context) error {
 
 	return nil
 }
+
+// loadLineages loads the lineages from the renewal config files, only loading the lineage with the cert name if set
+// Any renewal config files that fail to load are printed and skipped, and their paths returned
+func loadLineages(certName string) ([]*storage.Lineage, []string) {
+	store := getStorage()
+	names, err := store.Names()
+	if err != nil {
+		log.WithError(err).Error("finding renewal files")
+		fmt.Println(err)
+		return nil, nil
+	}
+	log.WithField("path", store.RenewalDir()).WithField("count", len(names)).Debug("found renewals files")
+
+	var lineages []*storage.Lineage
+	var failed []string
+	for _, name := range names {
+		if certName != "" && !strings.EqualFold(name, certName) {
+			log.WithField("wantedCertName", certName).
+				WithField("certName", name).
+				Debug("skipping due to cert name mismatch")
+			continue
+		}
+		l, err := store.Lineage(name)
+		if err != nil {
+			p := store.RenewalConfPath(name)
+			log.WithError(err).WithField("renewalfile", p).Error("loading renewal file")
+			fmt.Printf("Renewal configuration file %s produced an unexpected error: %v. Skipping.\n", p, err)
+			failed = append(failed, p)
+			continue
+		}
+		lineages = append(lineages, l)
+	}
+	return lineages, failed
+}
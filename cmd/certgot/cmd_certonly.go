@@ -1,7 +1,17 @@
 package main
 
 import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
+	"github.com/eggsampler/certgot/util"
 )
 
 const (
@@ -10,23 +20,225 @@ const (
 
 var (
 	cmdCertOnly = &cli.Command{
-		Name:           CMD_CERTONLY,
-		RunFunc:        commandCertOnly,
-		HelpCategories: []string{CATEGORY_COMMON},
-		HelpFlags:      []string{FLAG_NONINTERACTIVE},
+		Name:             CMD_CERTONLY,
+		RunFunc:          commandCertOnly,
+		HelpCategories:   []string{CATEGORY_COMMON},
+		HelpFlags:        []string{FLAG_NON_INTERACTIVE, FLAG_DOMAIN, FLAG_CERT_NAME, FLAG_SERVER, FLAG_ACCOUNT, FLAG_AUTHENTICATOR, FLAG_STANDALONE, FLAG_WEBROOT, FLAG_MANUAL, FLAG_DNS_RFC2136, FLAG_PREFERRED_CHALLENGES, FLAG_KEY_TYPE, FLAG_REUSE_KEY, FLAG_CSR},
+		UsageDescription: "Obtain or renew a certificate, but do not install it",
 	}
 )
 
 func commandCertOnly(ctx *cli.Context) error {
-	/*
-		val, err := flagAuthenticator.String(getFlagValues(true))
-		if err != nil {
-			return err
-		}
+	if cfgCSR.IsSet() {
+		return certOnlyCSR(cfgCSR.String())
+	}
 
-		fmt.Println("authenticator: ", val)
+	domains := getDomains()
+	if len(domains) == 0 {
+		return errors.New("no domains provided, use the -d flag to specify domains")
+	}
 
-	*/
+	l, certs, err := issueCertificate(domains, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully received certificate.\n"+
+		"Certificate is saved at: %s\n"+
+		"Key is saved at:         %s\n"+
+		"This certificate expires on %s.\n",
+		l.FullChainPath, l.PrivKeyPath, certs[0].NotAfter.Format("2006-01-02"))
 
 	return nil
 }
+
+// issueCertificate obtains a certificate for the domains using the configured authenticator and server,
+// and saves it unless dryRun is set, in which case the returned lineage is nil
+func issueCertificate(domains []string, dryRun bool) (*storage.Lineage, []*x509.Certificate, error) {
+	ll := log.WithFields("certname", cfgCertName.String(), "domains", domains)
+
+	auth, err := getAuthenticator(domains)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := newACMEClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	acct, err := getAccount(client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, keyPem, err := getCertificateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := util.CreateCSR(key, domains, cfgMustStaple.Bool())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ll.Debug("obtaining certificate")
+	certs, err := obtainCertificate(client, toACMEAccount(acct), auth, csr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if dryRun {
+		ll.Debug("dry run, not saving certificate")
+		return nil, certs, nil
+	}
+
+	l, err := saveLineage(domains, keyPem, certs, auth, acct)
+	if err != nil {
+		return nil, nil, err
+	}
+	ll.WithField("name", l.Name).Debug("saved certificate")
+
+	return l, certs, nil
+}
+
+// certOnlyCSR obtains a certificate for an existing csr, writing the certificate and chain to the --cert-path,
+// --chain-path and --fullchain-path files instead of a lineage
+func certOnlyCSR(csrPath string) error {
+	csr, err := util.ReadCSR(csrPath)
+	if err != nil {
+		return err
+	}
+	domains := util.CSRNames(csr)
+	if len(domains) == 0 {
+		return fmt.Errorf("no domains found in csr %s", csrPath)
+	}
+	if cmdDomains := getDomains(); len(cmdDomains) > 0 && !sameNames(domains, cmdDomains) {
+		return fmt.Errorf("inconsistent domain requests:\nFrom the CSR: %s\nFrom command line/config: %s",
+			strings.Join(domains, ", "), strings.Join(cmdDomains, ", "))
+	}
+	ll := log.WithFields("csr", csrPath, "domains", domains)
+
+	auth, err := getAuthenticator(domains)
+	if err != nil {
+		return err
+	}
+
+	client, err := newACMEClient()
+	if err != nil {
+		return err
+	}
+
+	acct, err := getAccount(client)
+	if err != nil {
+		return err
+	}
+
+	ll.Debug("obtaining certificate")
+	certs, err := obtainCertificate(client, toACMEAccount(acct), auth, csr)
+	if err != nil {
+		return err
+	}
+
+	cert, chain := encodeCertificates(certs)
+	certPath, chainPath, fullChainPath, err := writeCertificateFiles(cert, chain)
+	if err != nil {
+		return err
+	}
+	ll.Debug("saved certificate")
+
+	fmt.Printf("Successfully received certificate.\n"+
+		"Certificate is saved at:            %s\n", certPath)
+	if chainPath != "" {
+		fmt.Printf("Intermediate CA chain is saved at:  %s\n", chainPath)
+	}
+	fmt.Printf("Full certificate chain is saved at: %s\n"+
+		"This certificate expires on %s.\n",
+		fullChainPath, certs[0].NotAfter.Format("2006-01-02"))
+
+	return nil
+}
+
+// writeCertificateFiles writes the certificate, chain and full chain to the paths set by --cert-path, --chain-path and
+// --fullchain-path, returning the paths written. The chain file is skipped if the server didn't return a chain.
+// If any file can't be written the files already written are removed, so a failed run leaves nothing behind.
+func writeCertificateFiles(cert, chain []byte) (certPath, chainPath, fullChainPath string, err error) {
+	var written []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, path := range written {
+			if rerr := os.Remove(path); rerr != nil {
+				log.WithError(rerr).WithField("path", path).Warn("error removing partially written certificate file")
+			}
+		}
+	}()
+
+	if certPath, err = writePemFile(cfgCertPath, cert); err != nil {
+		return "", "", "", err
+	}
+	written = append(written, certPath)
+	if len(chain) > 0 {
+		if chainPath, err = writePemFile(cfgChainPath, chain); err != nil {
+			return "", "", "", err
+		}
+		written = append(written, chainPath)
+	}
+	fullChain := append(append([]byte{}, cert...), chain...)
+	if fullChainPath, err = writePemFile(cfgFullChainPath, fullChain); err != nil {
+		return "", "", "", err
+	}
+	return certPath, chainPath, fullChainPath, nil
+}
+
+// writePemFile writes a pem file to the path in the config, returning the absolute path written.
+// An explicitly set path must not already exist, while the default path is made unique like certbot by
+// prefixing the file name with a counter, eg 0000_cert.pem
+func writePemFile(cfg *cli.Config, data []byte) (string, error) {
+	path := cfg.String()
+	if !cfg.IsSet() {
+		dir, name := filepath.Split(path)
+		for i := 0; ; i++ {
+			path = filepath.Join(dir, fmt.Sprintf("%04d_%s", i, name))
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				break
+			}
+		}
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(abs, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", fmt.Errorf("error creating %s: %v", abs, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(abs)
+		return "", fmt.Errorf("error writing %s: %v", abs, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(abs)
+		return "", fmt.Errorf("error writing %s: %v", abs, err)
+	}
+	return abs, nil
+}
+
+// sameNames returns whether both lists contain the same names, ignoring order and duplicates
+func sameNames(a, b []string) bool {
+	set := map[string]bool{}
+	for _, v := range a {
+		set[v] = true
+	}
+	for _, v := range b {
+		if !set[v] {
+			return false
+		}
+	}
+	for _, v := range b {
+		delete(set, v)
+	}
+	return len(set) == 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/storage"
+)
+
+const (
+	CMD_DELETE = "delete"
+)
+
+var (
+	cmdDelete = &cli.Command{
+		Name:             CMD_DELETE,
+		RunFunc:          commandDelete,
+		HelpCategories:   []string{CATEGORY_MANAGE_CERTIFICATES},
+		HelpFlags:        []string{FLAG_CERT_NAME, FLAG_FORCE_DELETE, FLAG_NGINX_SERVER_ROOT},
+		UsageDescription: "Clean up all files related to a certificate",
+	}
+)
+
+func commandDelete(ctx *cli.Context) error {
+	var lineages []*storage.Lineage
+	if cfgCertName.IsSet() {
+		l, err := getStorage().Lineage(cfgCertName.String())
+		if err != nil {
+			return err
+		}
+		lineages = append(lineages, l)
+	} else {
+		var err error
+		lineages, err = chooseLineages("Which certificate(s) would you like to delete?")
+		if errors.Is(err, errNonInteractive) {
+			return fmt.Errorf("no certificate name provided, use the --%s flag", FLAG_CERT_NAME)
+		} else if err != nil {
+			return err
+		}
+		if len(lineages) == 0 {
+			return errors.New("no certificates selected")
+		}
+
+		var names []string
+		for _, l := range lineages {
+			names = append(names, l.Name)
+		}
+		confirmed, err := promptYesNo(fmt.Sprintf("Are you sure you want to delete %s?", strings.Join(names, ", ")), true)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			return errors.New("deletion aborted")
+		}
+	}
+
+	for _, l := range lineages {
+		if err := deleteLineage(l); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted all files relating to certificate %s.\n", l.Name)
+	}
+	return nil
+}
+
+// chooseLineages asks the user to pick one or more of the existing lineages
+func chooseLineages(question string) ([]*storage.Lineage, error) {
+	if !isInteractive() {
+		return nil, errNonInteractive
+	}
+	lineages, _ := loadLineages("")
+	if len(lineages) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	var options []string
+	for _, l := range lineages {
+		options = append(options, l.Name)
+	}
+	chosen, err := promptChoices(question, options)
+	if err != nil {
+		return nil, err
+	}
+	var selected []*storage.Lineage
+	for _, i := range chosen {
+		selected = append(selected, lineages[i])
+	}
+	return selected, nil
+}
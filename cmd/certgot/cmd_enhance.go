@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eggsampler/certgot/cli"
+)
+
+const (
+	CMD_ENHANCE = "enhance"
+)
+
+var (
+	cmdEnhance = &cli.Command{
+		Name:             CMD_ENHANCE,
+		RunFunc:          commandEnhance,
+		HelpCategories:   []string{CATEGORY_MANAGE_CERTIFICATES},
+		HelpFlags:        []string{FLAG_CERT_NAME, FLAG_DOMAIN, FLAG_INSTALLER, FLAG_REDIRECT, FLAG_HSTS, FLAG_UIR, FLAG_STAPLE_OCSP},
+		UsageDescription: "Add security enhancements to your existing configuration",
+	}
+)
+
+func commandEnhance(ctx *cli.Context) error {
+	enhancements := selectedEnhancements()
+	if len(enhancements) == 0 {
+		return fmt.Errorf("no enhancements selected, use one or more of --%s, --%s, --%s or --%s",
+			FLAG_REDIRECT, FLAG_HSTS, FLAG_UIR, FLAG_STAPLE_OCSP)
+	}
+
+	l, err := getLineage("Which certificate would you like to enhance?")
+	if err != nil {
+		return err
+	}
+
+	domains := getDomains()
+	if len(domains) == 0 {
+		if domains, err = l.Names(); err != nil {
+			return err
+		}
+	}
+
+	// use the installer the certificate was installed with, unless the user chose one
+	restore, err := setRenewalConfigs(l, false)
+	defer restore()
+	if err != nil {
+		return err
+	}
+
+	inst, err := getInstaller()
+	if err != nil {
+		return err
+	}
+	if err := checkEnhancements(inst, enhancements); err != nil {
+		return err
+	}
+	if err := enhanceDomains(inst, lineageCertificate(l), domains, enhancements); err != nil {
+		return err
+	}
+	if err := saveInstaller(inst, fmt.Sprintf("Added %s for %s", strings.Join(enhancements, ", "), strings.Join(domains, ", "))); err != nil {
+		return err
+	}
+	fmt.Printf("Successfully added %s to the configuration for %s\n", strings.Join(enhancements, ", "), strings.Join(domains, ", "))
+	return nil
+}
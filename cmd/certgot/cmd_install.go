@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/storage"
+)
+
+const (
+	CMD_INSTALL = "install"
+)
+
+var (
+	cmdInstall = &cli.Command{
+		Name:             CMD_INSTALL,
+		RunFunc:          commandInstall,
+		HelpCategories:   []string{CATEGORY_COMMON},
+		HelpFlags:        []string{FLAG_CERT_NAME, FLAG_DOMAIN, FLAG_CERT_PATH, FLAG_KEY_PATH, FLAG_FULLCHAIN_PATH, FLAG_CHAIN_PATH, FLAG_INSTALLER, FLAG_NGINX, FLAG_APACHE},
+		UsageDescription: "Install an arbitrary certificate in a server",
+	}
+)
+
+func commandInstall(ctx *cli.Context) error {
+	l, err := installLineage()
+	if err != nil {
+		return err
+	}
+
+	domains := getDomains()
+	if len(domains) == 0 {
+		if domains, err = l.Names(); err != nil {
+			return err
+		}
+	}
+
+	// use the installer the certificate was last installed with, unless the user chose one
+	restore, err := setRenewalConfigs(l, false)
+	defer restore()
+	if err != nil {
+		return err
+	}
+
+	inst, err := getInstaller()
+	if err != nil {
+		return err
+	}
+	enhancements := selectedEnhancements()
+	if err := checkEnhancements(inst, enhancements); err != nil {
+		return err
+	}
+
+	cert := lineageCertificate(l)
+	paths := []struct {
+		cfg  *cli.Config
+		path *string
+	}{
+		{cfgCertPath, &cert.CertPath},
+		{cfgKeyPath, &cert.KeyPath},
+		{cfgFullChainPath, &cert.FullChainPath},
+		{cfgChainPath, &cert.ChainPath},
+	}
+	for _, p := range paths {
+		if !p.cfg.IsSet() {
+			continue
+		}
+		// webservers resolve relative paths from their own directories
+		if *p.path, err = filepath.Abs(p.cfg.String()); err != nil {
+			return fmt.Errorf("error finding absolute path of %s: %v", p.cfg.String(), err)
+		}
+	}
+
+	if err := deployCertificate(inst, l.Name, cert, domains, enhancements); err != nil {
+		return err
+	}
+
+	// renewals deploy the renewed certificate with the same installer
+	setInstallerParams(l)
+	if err := l.Save(); err != nil {
+		return err
+	}
+
+	printDeployed(inst, domains, enhancements)
+	return nil
+}
+
+// installLineage returns the lineage to install, named by --cert-name or found from --cert-path, otherwise the user
+// is asked to choose one
+func installLineage() (*storage.Lineage, error) {
+	if cfgCertName.IsSet() || !cfgCertPath.IsSet() {
+		return getLineage("Which certificate would you like to install?")
+	}
+	l, err := findLineageByCertPath(cfgCertPath.String())
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return nil, fmt.Errorf("certificate %s is not part of a certificate lineage, use --%s to choose one",
+			cfgCertPath.String(), FLAG_CERT_NAME)
+	}
+	return l, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/eggsampler/certgot/cli"
+)
+
+const (
+	CMD_REGISTER = "register"
+)
+
+var (
+	cmdRegister = &cli.Command{
+		Name:             CMD_REGISTER,
+		RunFunc:          commandRegister,
+		HelpCategories:   []string{CATEGORY_MANAGE_ACCOUNT},
+		HelpFlags:        []string{FLAG_SERVER, FLAG_EMAIL, FLAG_AGREE_TOS, FLAG_REGISTER_UNSAFELY_WITHOUT_EMAIL},
+		UsageDescription: "Create an ACME account",
+	}
+)
+
+func commandRegister(ctx *cli.Context) error {
+	_, err := loadAccount()
+	if err == nil {
+		return errors.New("there is an existing account; registration of a duplicate account with this command is currently unsupported")
+	} else if !errors.Is(err, errNoAccount) {
+		return err
+	}
+
+	client, err := newACMEClient()
+	if err != nil {
+		return err
+	}
+	if _, err := registerAccount(client); err != nil {
+		return err
+	}
+
+	fmt.Println("Account registered.")
+	return nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eggsampler/certgot/authenticator"
+	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
+)
+
+const (
+	CMD_RENEW = "renew"
+
+	// sourceRenewal is the config source for values loaded from a renewal config file
+	sourceRenewal cli.SourceName = "renewal"
+
+	defaultRenewBeforeExpiry = 30 * 24 * time.Hour
+	stagingServer            = "https://acme-staging-v02.api.letsencrypt.org/directory"
+)
+
+var (
+	cmdRenew = &cli.Command{
+		Name:             CMD_RENEW,
+		RunFunc:          commandRenew,
+		HelpCategories:   []string{CATEGORY_COMMON},
+		HelpFlags:        []string{FLAG_CERT_NAME, FLAG_DRY_RUN, FLAG_FORCE_RENEWAL, FLAG_NEW_KEY, FLAG_NON_INTERACTIVE},
+		UsageDescription: "Renew all previously obtained certificates that are near expiry",
+	}
+
+	// renewalParams are the renewal params, as named by certbot, that are used to set configs when renewing
+	// Lists are stored comma separated, and booleans as True or False
+	renewalParams = []struct {
+		key     string
+		cfg     *cli.Config
+		list    bool
+		boolean bool
+	}{
+		{"authenticator", cfgAuthenticator, false, false},
+		{"installer", cfgInstaller, false, false},
+		{"server", cfgServer, false, false},
+		{"account", cfgAccount, false, false},
+		{"pref_challs", cfgPreferredChallenges, true, false},
+		{"http01_port", cfgHTTP01Port, false, false},
+		{"http01_address", cfgHTTP01Address, false, false},
+		{"https_port", cfgTLSALPN01Port, false, false},
+		{"tls_alpn01_address", cfgTLSALPN01Address, false, false},
+		{"webroot_path", cfgWebrootPath, true, false},
+		{"manual_auth_hook", cfgManualAuthHook, false, false},
+		{"manual_cleanup_hook", cfgManualCleanupHook, false, false},
+		{"dns_rfc2136_credentials", cfgDNSRFC2136Credentials, false, false},
+		{"dns_rfc2136_propagation_seconds", cfgDNSRFC2136PropagationSeconds, false, false},
+		{"nginx_server_root", cfgNginxServerRoot, false, false},
+		{"nginx_ctl", cfgNginxCtl, false, false},
+		{"apache_server_root", cfgApacheServerRoot, false, false},
+		{"apache_ctl", cfgApacheCtl, false, false},
+		{"key_type", cfgKeyType, false, false},
+		{"rsa_key_size", cfgRSAKeySize, false, false},
+		{"elliptic_curve", cfgEllipticCurve, false, false},
+		{"reuse_key", cfgReuseKey, false, true},
+		{"must_staple", cfgMustStaple, false, true},
+	}
+)
+
+func commandRenew(ctx *cli.Context) error {
+	if cfgDomains.IsSet() {
+		return fmt.Errorf("the renew command renews all certificates, use --%s to select a single certificate instead of -d",
+			FLAG_CERT_NAME)
+	}
+
+	dryRun := cfgDryRun.Bool()
+	certName := ""
+	if cfgCertName.IsSet() {
+		certName = cfgCertName.String()
+	}
+
+	lineages, parseFailures := loadLineages(certName)
+	if certName != "" && len(lineages) == 0 && len(parseFailures) == 0 {
+		return fmt.Errorf("no certificate found with name %s", certName)
+	}
+
+	var renewed, failed, skipped []string
+	for _, l := range lineages {
+		fmt.Println(strings.Repeat("- ", 40))
+		fmt.Printf("Processing %s\n", l.ConfigPath)
+
+		due, err := renewLineage(l, dryRun)
+		if err != nil {
+			log.WithError(err).WithField("name", l.Name).Error("renewing certificate")
+			fmt.Printf("Failed to renew certificate %s with error: %v\n", l.Name, err)
+			failed = append(failed, fmt.Sprintf("%s (failure)", l.FullChainPath))
+			continue
+		}
+		if !due {
+			fmt.Println("Certificate not yet due for renewal")
+			skipped = append(skipped, l.FullChainPath)
+			continue
+		}
+		renewed = append(renewed, fmt.Sprintf("%s (success)", l.FullChainPath))
+	}
+
+	printRenewSummary(dryRun, renewed, failed, skipped, parseFailures)
+
+	if len(failed) > 0 || len(parseFailures) > 0 {
+		return fmt.Errorf("%d renew failure(s), %d parse failure(s)", len(failed), len(parseFailures))
+	}
+	return nil
+}
+
+// renewLineage renews the certificate if it is due, or always if forced or doing a dry run,
+// using the options stored in the renewal config file unless overridden by the user
+// Returns whether the certificate was due for renewal
+func renewLineage(l *storage.Lineage, dryRun bool) (bool, error) {
+	cert, err := l.Certificate()
+	if err != nil {
+		return false, err
+	}
+
+	renewBefore := defaultRenewBeforeExpiry
+	if l.RenewBeforeExpiry != "" {
+		renewBefore, err = parseInterval(l.RenewBeforeExpiry)
+		if err != nil {
+			return false, fmt.Errorf("invalid renew_before_expiry in %s: %v", l.ConfigPath, err)
+		}
+	}
+	ll := log.WithFields("name", l.Name, "expiry", cert.NotAfter, "renewbefore", renewBefore)
+
+	switch {
+	case cfgForceRenewal.Bool():
+		ll.Debug("forcing renewal")
+	case dryRun:
+		ll.Debug("dry run, simulating renewal")
+	case time.Until(cert.NotAfter) > renewBefore:
+		ll.Debug("certificate not due for renewal")
+		return false, nil
+	default:
+		ll.Debug("certificate due for renewal")
+	}
+
+	domains, err := l.Names()
+	if err != nil {
+		return false, err
+	}
+
+	restore, err := setRenewalConfigs(l, dryRun)
+	defer restore()
+	if err != nil {
+		return false, err
+	}
+
+	_, certs, err := issueCertificate(domains, dryRun)
+	if err != nil {
+		return false, err
+	}
+
+	if dryRun {
+		fmt.Printf("Simulated renewal of certificate %s, expiring on %s\n", l.Name, certs[0].NotAfter.Format("2006-01-02"))
+		return true, nil
+	}
+	fmt.Printf("Renewed certificate %s, expiring on %s\n", l.Name, certs[0].NotAfter.Format("2006-01-02"))
+
+	if err := redeployCertificate(l, domains); err != nil {
+		return true, fmt.Errorf("error deploying the renewed certificate: %w", err)
+	}
+	return true, nil
+}
+
+// setRenewalConfigs sets any configs not already set by the user to the values stored in the renewal config file,
+// and sets the cert name so the renewed certificate is saved to the same lineage
+// Dry runs use the staging server instead of the default server, unless the user set the server, along with any
+// account on it instead of the stored account
+// The returned func restores the configs to their previous state, and must be called even if an error is returned
+func setRenewalConfigs(l *storage.Lineage, dryRun bool) (func(), error) {
+	userServer := cfgServer.IsSet()
+	userAccount := cfgAccount.IsSet()
+	var changed []*cli.Config
+	restore := func() {
+		for _, cfg := range changed {
+			cfg.Unset()
+		}
+	}
+	src := cli.ConfigSource{Source: sourceRenewal, Extra: l.ConfigPath}
+	set := func(cfg *cli.Config, values []string) error {
+		if cfg.IsSet() || len(values) == 0 {
+			return nil
+		}
+		log.WithFields("config", cfg.Name, "values", values).Trace("setting config from renewal params")
+		changed = append(changed, cfg)
+		return cfg.Set(values, src)
+	}
+
+	for _, p := range renewalParams {
+		v := l.RenewalParam(p.key)
+		if v == "" {
+			continue
+		}
+		if p.boolean && !strings.EqualFold(v, "true") {
+			// boolean configs are only set when true
+			continue
+		}
+		values := []string{v}
+		if p.list {
+			values = parseList(v)
+		}
+		if err := set(p.cfg, values); err != nil {
+			return restore, err
+		}
+	}
+	if m := l.Subsections["webroot_map"]; len(m) > 0 {
+		if err := set(cfgWebrootMap, []string{authenticator.FormatWebrootMap(m)}); err != nil {
+			return restore, err
+		}
+	}
+	if err := set(cfgCertName, []string{l.Name}); err != nil {
+		return restore, err
+	}
+
+	if dryRun && !userServer && cfgServer.String() == defaultServer {
+		log.WithField("server", stagingServer).Debug("using staging server for dry run")
+		changed = append(changed, cfgServer)
+		if err := cfgServer.Set([]string{stagingServer}, src); err != nil {
+			return restore, err
+		}
+		// the stored account is registered with the stored server, not the staging server
+		if !userAccount && cfgAccount.IsSet() {
+			cfgAccount.Unset()
+		}
+	}
+	return restore, nil
+}
+
+// parseInterval parses an interval like certbot's renew_before_expiry, eg "30 days" or "2 weeks"
+func parseInterval(s string) (time.Duration, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("invalid interval %q, must be a number and a unit, eg 30 days", s)
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid number in interval %q: %v", s, err)
+	}
+	units := map[string]time.Duration{
+		"second": time.Second,
+		"minute": time.Minute,
+		"hour":   time.Hour,
+		"day":    24 * time.Hour,
+		"week":   7 * 24 * time.Hour,
+	}
+	unit, ok := units[strings.TrimSuffix(fields[1], "s")]
+	if !ok {
+		return 0, fmt.Errorf("invalid unit in interval %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+func printRenewSummary(dryRun bool, renewed, failed, skipped, parseFailures []string) {
+	printList := func(header string, items []string, suffix string) {
+		fmt.Println(header)
+		for _, v := range items {
+			fmt.Printf("  %s%s\n", v, suffix)
+		}
+	}
+
+	fmt.Println(strings.Repeat("- ", 40))
+	if dryRun {
+		fmt.Println("** DRY RUN: simulating 'certgot renew' close to cert expiry")
+		fmt.Println("**          (The test certificates below have not been saved.)")
+		fmt.Println()
+	}
+	if len(skipped) > 0 {
+		printList("The following certificates are not due for renewal yet:", skipped, " (skipped)")
+	}
+	switch {
+	case len(renewed) == 0 && len(failed) == 0:
+		fmt.Println("No renewals were attempted.")
+	case len(failed) == 0:
+		printList("Congratulations, all renewals succeeded:", renewed, "")
+	case len(renewed) == 0:
+		printList("All renewals failed. The following certificates could not be renewed:", failed, "")
+	default:
+		printList("The following certificates were successfully renewed:", renewed, "")
+		printList("The following certificates could not be renewed:", failed, "")
+	}
+	if len(parseFailures) > 0 {
+		printList("Additionally, the following renewal configurations were invalid:", parseFailures, " (parsefail)")
+	}
+	if dryRun {
+		fmt.Println()
+		fmt.Println("** DRY RUN: simulating 'certgot renew' close to cert expiry")
+		fmt.Println("**          (The test certificates above have not been saved.)")
+	}
+	fmt.Println(strings.Repeat("- ", 40))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
+	"github.com/eggsampler/certgot/util"
+)
+
+const (
+	CMD_REVOKE = "revoke"
+)
+
+var (
+	cmdRevoke = &cli.Command{
+		Name:             CMD_REVOKE,
+		RunFunc:          commandRevoke,
+		HelpCategories:   []string{CATEGORY_MANAGE_CERTIFICATES},
+		HelpFlags:        []string{FLAG_CERT_PATH, FLAG_CERT_NAME, FLAG_KEY_PATH, FLAG_REASON, FLAG_DELETE_AFTER_REVOKE, FLAG_NO_DELETE_AFTER_REVOKE, FLAG_FORCE_DELETE, FLAG_SERVER},
+		UsageDescription: "Revoke a certificate (supply --cert-path or --cert-name)",
+	}
+
+	// revocationReasons are the reasons that can be given with the reason flag, as named by certbot
+	revocationReasons = map[string]int{
+		"unspecified":          acme.ReasonUnspecified,
+		"keycompromise":        acme.ReasonKeyCompromise,
+		"affiliationchanged":   acme.ReasonAffiliationChanged,
+		"superseded":           acme.ReasonSuperseded,
+		"cessationofoperation": acme.ReasonCessationOfOperation,
+	}
+)
+
+func commandRevoke(ctx *cli.Context) error {
+	if cfgCertPath.IsSet() == cfgCertName.IsSet() {
+		return fmt.Errorf("exactly one of --%s or --%s must be provided", FLAG_CERT_PATH, FLAG_CERT_NAME)
+	}
+	if cfgDeleteAfterRevoke.Bool() && cfgNoDeleteAfterRevoke.Bool() {
+		return fmt.Errorf("only one of --%s or --%s can be provided", FLAG_DELETE_AFTER_REVOKE, FLAG_NO_DELETE_AFTER_REVOKE)
+	}
+
+	reason, err := getRevocationReason()
+	if err != nil {
+		return err
+	}
+
+	var l *storage.Lineage
+	certPath := cfgCertPath.String()
+	if cfgCertName.IsSet() {
+		l, err = getStorage().Lineage(cfgCertName.String())
+		if err != nil {
+			return err
+		}
+		certPath = l.CertPath
+	} else {
+		l, err = findLineageByCertPath(certPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	cert, err := util.ReadCertificate(certPath)
+	if err != nil {
+		return err
+	}
+	ll := log.WithFields("path", certPath, "serial", fmt.Sprintf("%x", cert.SerialNumber), "reason", reason)
+
+	client, err := newACMEClient()
+	if err != nil {
+		return err
+	}
+
+	if cfgKeyPath.IsSet() {
+		key, err := util.ReadPrivateKey(cfgKeyPath.String())
+		if err != nil {
+			return err
+		}
+		pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+		if !ok || !pub.Equal(key.Public()) {
+			return fmt.Errorf("the private key %s does not match the certificate %s", cfgKeyPath.String(), certPath)
+		}
+		ll.Debug("revoking certificate with certificate key")
+		err = client.RevokeCertificateWithKey(key, cert, reason)
+		if err != nil {
+			return err
+		}
+	} else {
+		acct, err := loadAccount()
+		if err != nil {
+			return err
+		}
+		ll.WithField("account", acct.URI).Debug("revoking certificate with account")
+		if err := client.RevokeCertificate(toACMEAccount(acct), cert, reason); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Congratulations! You have successfully revoked the certificate that was located at %s.\n", certPath)
+
+	if l == nil {
+		return nil
+	}
+	del, err := shouldDeleteAfterRevoke()
+	if err != nil {
+		return err
+	}
+	if !del {
+		return nil
+	}
+	if err := deleteLineage(l); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted all files relating to certificate %s.\n", l.Name)
+	return nil
+}
+
+// getRevocationReason returns the reason code for the reason set with the reason flag
+func getRevocationReason() (int, error) {
+	name := strings.ToLower(cfgReason.String())
+	reason, ok := revocationReasons[name]
+	if !ok {
+		var names []string
+		for n := range revocationReasons {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return 0, fmt.Errorf("invalid revocation reason %q, valid reasons: %s", cfgReason.String(), strings.Join(names, ", "))
+	}
+	return reason, nil
+}
+
+// shouldDeleteAfterRevoke returns whether the revoked lineage should be deleted, asking the user if not set with flags
+// Like certbot, the lineage is deleted when running non-interactively
+func shouldDeleteAfterRevoke() (bool, error) {
+	if cfgNoDeleteAfterRevoke.Bool() {
+		return false, nil
+	}
+	if cfgDeleteAfterRevoke.Bool() {
+		return true, nil
+	}
+	del, err := promptYesNo("Would you like to delete the certificate(s) you just revoked, along with all earlier and later versions of the certificate?", true)
+	if errors.Is(err, errNonInteractive) {
+		return true, nil
+	}
+	return del, err
+}
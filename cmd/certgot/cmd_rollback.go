@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/log"
+)
+
+const (
+	CMD_ROLLBACK = "rollback"
+)
+
+var (
+	cmdRollback = &cli.Command{
+		Name:             CMD_ROLLBACK,
+		RunFunc:          commandRollback,
+		HelpCategories:   []string{CATEGORY_MANAGE_CERTIFICATES},
+		HelpFlags:        []string{FLAG_CHECKPOINTS, FLAG_INSTALLER},
+		UsageDescription: "Roll back server configuration changes made during certificate installation",
+	}
+)
+
+func commandRollback(ctx *cli.Context) error {
+	count, err := strconv.Atoi(cfgCheckpoints.String())
+	if err != nil || count < 1 {
+		return fmt.Errorf("invalid number of checkpoints %q, must be a positive number", cfgCheckpoints.String())
+	}
+
+	reverter := getReverter()
+	checkpoints, err := reverter.Checkpoints()
+	if err != nil {
+		return err
+	}
+	if len(checkpoints) == 0 {
+		fmt.Println("No checkpoints to roll back.")
+		return nil
+	}
+	if count > len(checkpoints) {
+		fmt.Printf("Unable to roll back %d checkpoints, only %d exist.\n", count, len(checkpoints))
+	}
+
+	rolledBack, err := reverter.RollbackCheckpoints(count)
+	for _, cp := range rolledBack {
+		fmt.Printf("Rolled back checkpoint from %s: %s\n", cp.Time.Format("2006-01-02 15:04:05"), cp.Title)
+	}
+	if err != nil {
+		return err
+	}
+
+	// reload the webserver so it uses the restored config
+	if cfgInstaller.IsSet() {
+		inst, err := getInstaller()
+		if err != nil {
+			return err
+		}
+		if err := inst.ConfigTest(); err != nil {
+			return err
+		}
+		if err := inst.Restart(); err != nil {
+			return err
+		}
+		log.WithField("installer", inst.Name()).Debug("restarted webserver after rollback")
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/eggsampler/certgot/cli"
 )
 
@@ -8,8 +11,44 @@ var (
 	cmdRun = &cli.Command{
 		Name:             "run",
 		Default:          true,
+		RunFunc:          commandRun,
 		HelpCategories:   []string{CATEGORY_COMMON},
+		HelpFlags:        []string{FLAG_NON_INTERACTIVE, FLAG_DOMAIN, FLAG_CERT_NAME, FLAG_AUTHENTICATOR, FLAG_INSTALLER, FLAG_NGINX, FLAG_APACHE},
 		UsageDescription: "Obtain & install a certificate in your current webserver",
-		// TODO
 	}
 )
+
+func commandRun(ctx *cli.Context) error {
+	domains := getDomains()
+	if len(domains) == 0 {
+		return errors.New("no domains provided, use the -d flag to specify domains")
+	}
+
+	// load the webserver config before obtaining a certificate, so any problems with it are found first
+	inst, err := getInstaller()
+	if err != nil {
+		return err
+	}
+	enhancements := selectedEnhancements()
+	if err := checkEnhancements(inst, enhancements); err != nil {
+		return err
+	}
+
+	l, certs, err := issueCertificate(domains, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully received certificate.\n"+
+		"Certificate is saved at: %s\n"+
+		"Key is saved at:         %s\n"+
+		"This certificate expires on %s.\n",
+		l.FullChainPath, l.PrivKeyPath, certs[0].NotAfter.Format("2006-01-02"))
+
+	if err := deployCertificate(inst, l.Name, lineageCertificate(l), domains, enhancements); err != nil {
+		return err
+	}
+	printDeployed(inst, domains, enhancements)
+
+	return nil
+}
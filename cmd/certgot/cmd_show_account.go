@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eggsampler/certgot/cli"
+)
+
+const (
+	CMD_SHOW_ACCOUNT = "show_account"
+)
+
+var (
+	cmdShowAccount = &cli.Command{
+		Name:             CMD_SHOW_ACCOUNT,
+		RunFunc:          commandShowAccount,
+		HelpCategories:   []string{CATEGORY_MANAGE_ACCOUNT},
+		HelpFlags:        []string{FLAG_SERVER},
+		UsageDescription: "Display account details",
+	}
+)
+
+func commandShowAccount(ctx *cli.Context) error {
+	acct, err := loadAccount()
+	if err != nil {
+		return err
+	}
+
+	client, err := newACMEClient()
+	if err != nil {
+		return err
+	}
+	// the account details aren't stored locally, so are always fetched from the server
+	remote, err := client.FetchAccount(acct.Key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Account details for server %s:\n", cfgServer.String())
+	fmt.Printf("  Account URL: %s\n", remote.URL)
+	switch len(remote.Contact) {
+	case 0:
+		fmt.Println("  Email contact: none")
+	case 1:
+		fmt.Printf("  Email contact: %s\n", formatContacts(remote.Contact))
+	default:
+		fmt.Printf("  Email contacts: %s\n", formatContacts(remote.Contact))
+	}
+	return nil
+}
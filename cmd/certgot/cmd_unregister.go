@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/eggsampler/certgot/cli"
+)
+
+const (
+	CMD_UNREGISTER = "unregister"
+)
+
+var (
+	cmdUnregister = &cli.Command{
+		Name:             CMD_UNREGISTER,
+		RunFunc:          commandUnregister,
+		HelpCategories:   []string{CATEGORY_MANAGE_ACCOUNT},
+		HelpFlags:        []string{FLAG_SERVER, FLAG_NON_INTERACTIVE},
+		UsageDescription: "Deactivate an ACME account",
+	}
+)
+
+func commandUnregister(ctx *cli.Context) error {
+	acct, err := loadAccount()
+	if err != nil {
+		return err
+	}
+
+	// like certbot, deactivation goes ahead without asking when running non-interactively
+	confirmed, err := promptYesNo("Are you sure you would like to irrevocably deactivate your account?", false)
+	if errors.Is(err, errNonInteractive) {
+		confirmed = true
+	} else if err != nil {
+		return err
+	}
+	if !confirmed {
+		return errors.New("deactivation aborted")
+	}
+
+	client, err := newACMEClient()
+	if err != nil {
+		return err
+	}
+	if _, err := client.DeactivateAccount(toACMEAccount(acct)); err != nil {
+		return err
+	}
+	if err := getStorage().DeleteAccount(acct); err != nil {
+		return err
+	}
+
+	fmt.Println("Account deactivated.")
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/eggsampler/certgot/cli"
+)
+
+const (
+	CMD_UPDATE_ACCOUNT = "update_account"
+)
+
+var (
+	cmdUpdateAccount = &cli.Command{
+		Name:             CMD_UPDATE_ACCOUNT,
+		RunFunc:          commandUpdateAccount,
+		HelpCategories:   []string{CATEGORY_MANAGE_ACCOUNT},
+		HelpFlags:        []string{FLAG_SERVER, FLAG_EMAIL, FLAG_REGISTER_UNSAFELY_WITHOUT_EMAIL},
+		UsageDescription: "Update an ACME account",
+	}
+)
+
+func commandUpdateAccount(ctx *cli.Context) error {
+	acct, err := loadAccount()
+	if err != nil {
+		return err
+	}
+
+	contacts, err := getContacts()
+	if err != nil {
+		return err
+	}
+
+	client, err := newACMEClient()
+	if err != nil {
+		return err
+	}
+	updated, err := client.UpdateAccount(toACMEAccount(acct), contacts)
+	if err != nil {
+		return err
+	}
+
+	if len(updated.Contact) == 0 {
+		fmt.Println("Any contact information associated with this account has been removed.")
+	} else {
+		fmt.Printf("Your e-mail address was updated to %s.\n", formatContacts(updated.Contact))
+	}
+	return nil
+}
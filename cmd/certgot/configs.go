@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/installer"
+	"github.com/eggsampler/certgot/util"
 )
 
 const (
@@ -13,6 +15,73 @@ const (
 	CONFIG_WORK_DIR   = "work-dir"
 	CONFIG_DOMAINS    = "domains"
 	CONFIG_CERT_NAME  = "cert-name"
+	CONFIG_CERT_PATH  = "cert-path"
+	CONFIG_KEY_PATH   = "key-path"
+
+	CONFIG_NON_INTERACTIVE                 = "non-interactive"
+	CONFIG_FORCE_INTERACTIVE               = "force-interactive"
+	CONFIG_DRY_RUN                         = "dry-run"
+	CONFIG_FORCE_RENEWAL                   = "force-renewal"
+	CONFIG_SERVER                          = "server"
+	CONFIG_ACCOUNT                         = "account"
+	CONFIG_EMAIL                           = "email"
+	CONFIG_AGREE_TOS                       = "agree-tos"
+	CONFIG_REGISTER_UNSAFELY_WITHOUT_EMAIL = "register-unsafely-without-email"
+	CONFIG_AUTHENTICATOR                   = "authenticator"
+	CONFIG_INSTALLER                       = "installer"
+	CONFIG_HTTP01_PORT                     = "http-01-port"
+	CONFIG_HTTP01_ADDRESS                  = "http-01-address"
+	CONFIG_TLSALPN01_PORT                  = "tls-alpn-01-port"
+	CONFIG_TLSALPN01_ADDRESS               = "tls-alpn-01-address"
+	CONFIG_PREFERRED_CHALLENGES            = "preferred-challenges"
+	CONFIG_WEBROOT_PATH                    = "webroot-path"
+	CONFIG_WEBROOT_MAP                     = "webroot-map"
+	CONFIG_MANUAL_AUTH_HOOK                = "manual-auth-hook"
+	CONFIG_MANUAL_CLEANUP_HOOK             = "manual-cleanup-hook"
+	CONFIG_DNS_RFC2136_CREDENTIALS         = "dns-rfc2136-credentials"
+	CONFIG_DNS_RFC2136_PROPAGATION_SECONDS = "dns-rfc2136-propagation-seconds"
+	CONFIG_REASON                          = "reason"
+	CONFIG_DELETE_AFTER_REVOKE             = "delete-after-revoke"
+	CONFIG_NO_DELETE_AFTER_REVOKE          = "no-delete-after-revoke"
+	CONFIG_FORCE_DELETE                    = "force-delete"
+	CONFIG_NGINX_SERVER_ROOT               = "nginx-server-root"
+	CONFIG_NGINX_CTL                       = "nginx-ctl"
+	CONFIG_APACHE_SERVER_ROOT              = "apache-server-root"
+	CONFIG_APACHE_CTL                      = "apache-ctl"
+	CONFIG_CHECKPOINTS                     = "checkpoints"
+	CONFIG_KEY_TYPE                        = "key-type"
+	CONFIG_RSA_KEY_SIZE                    = "rsa-key-size"
+	CONFIG_ELLIPTIC_CURVE                  = "elliptic-curve"
+	CONFIG_REUSE_KEY                       = "reuse-key"
+	CONFIG_NEW_KEY                         = "new-key"
+	CONFIG_MUST_STAPLE                     = "must-staple"
+	CONFIG_REDIRECT                        = "redirect"
+	CONFIG_HSTS                            = "hsts"
+	CONFIG_UIR                             = "uir"
+	CONFIG_STAPLE_OCSP                     = "staple-ocsp"
+	CONFIG_CSR                             = "csr"
+	CONFIG_CHAIN_PATH                      = "chain-path"
+	CONFIG_FULLCHAIN_PATH                  = "fullchain-path"
+)
+
+const (
+	defaultServer        = "https://acme-v02.api.letsencrypt.org/directory"
+	defaultHTTP01Port    = "80"
+	defaultTLSALPN01Port = "443"
+
+	defaultDNSRFC2136PropagationSeconds = "60"
+	defaultReason                       = "unspecified"
+	defaultNginxServerRoot              = "/etc/nginx"
+	defaultNginxCtl                     = installer.DefaultNginxCtl
+	defaultApacheServerRoot             = "/etc/apache2"
+	defaultApacheCtl                    = installer.DefaultApacheCtl
+	defaultCheckpoints                  = "1"
+	defaultKeyType                      = util.KeyTypeRSA
+	defaultRSAKeySize                   = "2048"
+	defaultEllipticCurve                = "secp256r1"
+	defaultCertPath                     = "./cert.pem"
+	defaultChainPath                    = "./chain.pem"
+	defaultFullChainPath                = "./fullchain.pem"
 )
 
 var (
@@ -48,4 +117,174 @@ var (
 		HelpDefault: "",
 		OnSet:       nil,
 	}
+	cfgCertPath = &cli.Config{
+		Name:        CONFIG_CERT_PATH,
+		Default:     []string{defaultCertPath},
+		HelpDefault: defaultCertPath,
+	}
+	cfgChainPath = &cli.Config{
+		Name:        CONFIG_CHAIN_PATH,
+		Default:     []string{defaultChainPath},
+		HelpDefault: defaultChainPath,
+	}
+	cfgFullChainPath = &cli.Config{
+		Name:        CONFIG_FULLCHAIN_PATH,
+		Default:     []string{defaultFullChainPath},
+		HelpDefault: defaultFullChainPath,
+	}
+	cfgKeyPath = &cli.Config{
+		Name: CONFIG_KEY_PATH,
+	}
+	cfgNonInteractive = &cli.Config{
+		Name: CONFIG_NON_INTERACTIVE,
+	}
+	cfgForceInteractive = &cli.Config{
+		Name: CONFIG_FORCE_INTERACTIVE,
+	}
+	cfgDryRun = &cli.Config{
+		Name: CONFIG_DRY_RUN,
+	}
+	cfgForceRenewal = &cli.Config{
+		Name: CONFIG_FORCE_RENEWAL,
+	}
+	cfgServer = &cli.Config{
+		Name:        CONFIG_SERVER,
+		Default:     []string{defaultServer},
+		HelpDefault: defaultServer,
+	}
+	cfgAccount = &cli.Config{
+		Name: CONFIG_ACCOUNT,
+	}
+	cfgEmail = &cli.Config{
+		Name: CONFIG_EMAIL,
+	}
+	cfgAgreeTOS = &cli.Config{
+		Name: CONFIG_AGREE_TOS,
+	}
+	cfgRegisterUnsafelyWithoutEmail = &cli.Config{
+		Name: CONFIG_REGISTER_UNSAFELY_WITHOUT_EMAIL,
+	}
+	cfgAuthenticator = &cli.Config{
+		Name: CONFIG_AUTHENTICATOR,
+	}
+	cfgInstaller = &cli.Config{
+		Name: CONFIG_INSTALLER,
+	}
+	cfgHTTP01Port = &cli.Config{
+		Name:        CONFIG_HTTP01_PORT,
+		Default:     []string{defaultHTTP01Port},
+		HelpDefault: defaultHTTP01Port,
+	}
+	cfgHTTP01Address = &cli.Config{
+		Name: CONFIG_HTTP01_ADDRESS,
+	}
+	cfgTLSALPN01Port = &cli.Config{
+		Name:        CONFIG_TLSALPN01_PORT,
+		Default:     []string{defaultTLSALPN01Port},
+		HelpDefault: defaultTLSALPN01Port,
+	}
+	cfgTLSALPN01Address = &cli.Config{
+		Name: CONFIG_TLSALPN01_ADDRESS,
+	}
+	cfgPreferredChallenges = &cli.Config{
+		Name: CONFIG_PREFERRED_CHALLENGES,
+	}
+	cfgWebrootPath = &cli.Config{
+		Name: CONFIG_WEBROOT_PATH,
+	}
+	cfgWebrootMap = &cli.Config{
+		Name: CONFIG_WEBROOT_MAP,
+	}
+	cfgManualAuthHook = &cli.Config{
+		Name: CONFIG_MANUAL_AUTH_HOOK,
+	}
+	cfgManualCleanupHook = &cli.Config{
+		Name: CONFIG_MANUAL_CLEANUP_HOOK,
+	}
+	cfgDNSRFC2136Credentials = &cli.Config{
+		Name: CONFIG_DNS_RFC2136_CREDENTIALS,
+	}
+	cfgDNSRFC2136PropagationSeconds = &cli.Config{
+		Name:        CONFIG_DNS_RFC2136_PROPAGATION_SECONDS,
+		Default:     []string{defaultDNSRFC2136PropagationSeconds},
+		HelpDefault: defaultDNSRFC2136PropagationSeconds,
+	}
+	cfgReason = &cli.Config{
+		Name:        CONFIG_REASON,
+		Default:     []string{defaultReason},
+		HelpDefault: defaultReason,
+	}
+	cfgDeleteAfterRevoke = &cli.Config{
+		Name: CONFIG_DELETE_AFTER_REVOKE,
+	}
+	cfgNoDeleteAfterRevoke = &cli.Config{
+		Name: CONFIG_NO_DELETE_AFTER_REVOKE,
+	}
+	cfgForceDelete = &cli.Config{
+		Name: CONFIG_FORCE_DELETE,
+	}
+	cfgNginxServerRoot = &cli.Config{
+		Name:        CONFIG_NGINX_SERVER_ROOT,
+		Default:     []string{defaultNginxServerRoot},
+		HelpDefault: defaultNginxServerRoot,
+	}
+	cfgNginxCtl = &cli.Config{
+		Name:        CONFIG_NGINX_CTL,
+		Default:     []string{defaultNginxCtl},
+		HelpDefault: defaultNginxCtl,
+	}
+	cfgApacheServerRoot = &cli.Config{
+		Name:        CONFIG_APACHE_SERVER_ROOT,
+		Default:     []string{defaultApacheServerRoot},
+		HelpDefault: defaultApacheServerRoot,
+	}
+	cfgApacheCtl = &cli.Config{
+		Name:        CONFIG_APACHE_CTL,
+		Default:     []string{defaultApacheCtl},
+		HelpDefault: defaultApacheCtl,
+	}
+	cfgCheckpoints = &cli.Config{
+		Name:        CONFIG_CHECKPOINTS,
+		Default:     []string{defaultCheckpoints},
+		HelpDefault: defaultCheckpoints,
+	}
+	cfgKeyType = &cli.Config{
+		Name:        CONFIG_KEY_TYPE,
+		Default:     []string{defaultKeyType},
+		HelpDefault: defaultKeyType,
+	}
+	cfgRSAKeySize = &cli.Config{
+		Name:        CONFIG_RSA_KEY_SIZE,
+		Default:     []string{defaultRSAKeySize},
+		HelpDefault: defaultRSAKeySize,
+	}
+	cfgEllipticCurve = &cli.Config{
+		Name:        CONFIG_ELLIPTIC_CURVE,
+		Default:     []string{defaultEllipticCurve},
+		HelpDefault: defaultEllipticCurve,
+	}
+	cfgReuseKey = &cli.Config{
+		Name: CONFIG_REUSE_KEY,
+	}
+	cfgNewKey = &cli.Config{
+		Name: CONFIG_NEW_KEY,
+	}
+	cfgMustStaple = &cli.Config{
+		Name: CONFIG_MUST_STAPLE,
+	}
+	cfgRedirect = &cli.Config{
+		Name: CONFIG_REDIRECT,
+	}
+	cfgHSTS = &cli.Config{
+		Name: CONFIG_HSTS,
+	}
+	cfgUIR = &cli.Config{
+		Name: CONFIG_UIR,
+	}
+	cfgStapleOCSP = &cli.Config{
+		Name: CONFIG_STAPLE_OCSP,
+	}
+	cfgCSR = &cli.Config{
+		Name: CONFIG_CSR,
+	}
 )
@@ -1,6 +1,10 @@
 package main
 
-import "github.com/eggsampler/certgot/cli"
+import (
+	"github.com/eggsampler/certgot/authenticator"
+	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/installer"
+)
 
 // TODO: pick a better naming scheme to identify the constant names vs the variable flags
 // to better match go naming https://golang.org/doc/effective_go#mixed-caps
@@ -13,20 +17,69 @@ const (
 	FLAG_WORK_DIR                        = "work-dir"
 	FLAG_LOGS_DIR                        = "logs-dir"
 	FLAG_CONFIG_DIR                      = "config-dir"
+	FLAG_SERVER                          = "server"
+	FLAG_ACCOUNT                         = "account"
 	FLAG_EMAIL                           = "email"
+	FLAG_EMAIL_SHORT                     = "m"
+	FLAG_AGREE_TOS                       = "agree-tos"
 	FLAG_REGISTER_UNSAFELY_WITHOUT_EMAIL = "register-unsafely-without-email"
 	FLAG_STANDALONE                      = "standalone"
 	FLAG_WEBROOT                         = "webroot"
+	FLAG_WEBROOT_PATH                    = "webroot-path"
+	FLAG_WEBROOT_PATH_SHORT              = "w"
+	FLAG_WEBROOT_MAP                     = "webroot-map"
+	FLAG_MANUAL                          = "manual"
+	FLAG_MANUAL_AUTH_HOOK                = "manual-auth-hook"
+	FLAG_MANUAL_CLEANUP_HOOK             = "manual-cleanup-hook"
+	FLAG_DNS_RFC2136                     = "dns-rfc2136"
+	FLAG_DNS_RFC2136_CREDENTIALS         = "dns-rfc2136-credentials"
+	FLAG_DNS_RFC2136_PROPAGATION_SECONDS = "dns-rfc2136-propagation-seconds"
 	FLAG_AUTHENTICATOR                   = "authenticator"
 	FLAG_AUTHENTICATOR_SHORT             = "a"
+	FLAG_INSTALLER                       = "installer"
+	FLAG_INSTALLER_SHORT                 = "i"
+	FLAG_NGINX                           = "nginx"
+	FLAG_APACHE                          = "apache"
+	FLAG_HTTP01_PORT                     = "http-01-port"
+	FLAG_HTTP01_ADDRESS                  = "http-01-address"
+	FLAG_TLSALPN01_PORT                  = "tls-alpn-01-port"
+	FLAG_TLSALPN01_ADDRESS               = "tls-alpn-01-address"
+	FLAG_PREFERRED_CHALLENGES            = "preferred-challenges"
 	FLAG_DOMAIN                          = "domain"
 	FLAG_DOMAINS                         = "domains"
 	FLAG_DOMAIN_SHORT                    = "d"
 	FLAG_CERT_NAME                       = "cert-name"
+	FLAG_CERT_PATH                       = "cert-path"
+	FLAG_KEY_PATH                        = "key-path"
+	FLAG_REASON                          = "reason"
+	FLAG_DELETE_AFTER_REVOKE             = "delete-after-revoke"
+	FLAG_NO_DELETE_AFTER_REVOKE          = "no-delete-after-revoke"
+	FLAG_FORCE_DELETE                    = "force-delete"
+	FLAG_KEY_TYPE                        = "key-type"
+	FLAG_RSA_KEY_SIZE                    = "rsa-key-size"
+	FLAG_ELLIPTIC_CURVE                  = "elliptic-curve"
+	FLAG_REUSE_KEY                       = "reuse-key"
+	FLAG_NEW_KEY                         = "new-key"
+	FLAG_MUST_STAPLE                     = "must-staple"
+	FLAG_REDIRECT                        = "redirect"
+	FLAG_HSTS                            = "hsts"
+	FLAG_UIR                             = "uir"
+	FLAG_STAPLE_OCSP                     = "staple-ocsp"
+	FLAG_CSR                             = "csr"
+	FLAG_CHAIN_PATH                      = "chain-path"
+	FLAG_FULLCHAIN_PATH                  = "fullchain-path"
+	FLAG_NGINX_SERVER_ROOT               = "nginx-server-root"
+	FLAG_NGINX_CTL                       = "nginx-ctl"
+	FLAG_APACHE_SERVER_ROOT              = "apache-server-root"
+	FLAG_APACHE_CTL                      = "apache-ctl"
+	FLAG_CHECKPOINTS                     = "checkpoints"
 	FLAG_NON_INTERACTIVE                 = "non-interactive"
 	FLAG_NONINTERACTIVE                  = "noninteractive"
 	FLAG_NON_INTERACTIVE_SHORT           = "n"
 	FLAG_FORCE_INTERACTIVE               = "force-interactive"
+	FLAG_DRY_RUN                         = "dry-run"
+	FLAG_FORCE_RENEWAL                   = "force-renewal"
+	FLAG_RENEW_BY_DEFAULT                = "renew-by-default"
 )
 
 var (
@@ -107,14 +160,434 @@ var (
 		HelpValueName:   "CERTNAME",
 		HelpDescription: "Certificate name to apply. This name is used by Certbot for housekeeping and in file paths; it doesn't affect the content of the certificate itself. To see certificate names, run 'certbot certificates'. When creating a new certificate, specifies the new certificate's name.",
 	}
+	flagCertPath = &cli.Flag{
+		Name:            FLAG_CERT_PATH,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_CERT_PATH),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_CERT_PATH),
+		HelpCategories:  []string{CATEGORY_PATHS, CMD_REVOKE},
+		HelpValueName:   "CERT_PATH",
+		HelpDescription: "Path to where certificate is saved (with certonly --csr), installed from, or revoked.",
+	}
+	flagChainPath = &cli.Flag{
+		Name:            FLAG_CHAIN_PATH,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_CHAIN_PATH),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_CHAIN_PATH),
+		HelpCategories:  []string{CATEGORY_PATHS},
+		HelpValueName:   "CHAIN_PATH",
+		HelpDescription: "Accompanying path to a certificate chain.",
+	}
+	flagFullChainPath = &cli.Flag{
+		Name:            FLAG_FULLCHAIN_PATH,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_FULLCHAIN_PATH),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_FULLCHAIN_PATH),
+		HelpCategories:  []string{CATEGORY_PATHS},
+		HelpValueName:   "FULLCHAIN_PATH",
+		HelpDescription: "Accompanying path to a full certificate chain (certificate plus chain).",
+	}
+	flagKeyPath = &cli.Flag{
+		Name:            FLAG_KEY_PATH,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_KEY_PATH),
+		HelpCategories:  []string{CATEGORY_PATHS, CMD_REVOKE},
+		HelpValueName:   "KEY_PATH",
+		HelpDescription: "Path to private key for certificate installation or revocation (if account key is missing)",
+	}
+	flagReason = &cli.Flag{
+		Name:            FLAG_REASON,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_REASON),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_REASON),
+		HelpCategories:  []string{CMD_REVOKE},
+		HelpValueName:   "{unspecified,keycompromise,affiliationchanged,superseded,cessationofoperation}",
+		HelpDescription: "Specify reason for revoking certificate.",
+	}
+	flagDeleteAfterRevoke = &cli.Flag{
+		Name:            FLAG_DELETE_AFTER_REVOKE,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_DELETE_AFTER_REVOKE),
+		HelpCategories:  []string{CMD_REVOKE},
+		HelpDescription: "Delete certificates after revoking them, along with all previous and later versions of those certificates.",
+	}
+	flagNoDeleteAfterRevoke = &cli.Flag{
+		Name:            FLAG_NO_DELETE_AFTER_REVOKE,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_NO_DELETE_AFTER_REVOKE),
+		HelpCategories:  []string{CMD_REVOKE},
+		HelpDescription: "Do not delete certificates after revoking them. This option should be used with caution because the 'renew' subcommand will attempt to renew undeleted revoked certificates.",
+	}
+	flagForceDelete = &cli.Flag{
+		Name:            FLAG_FORCE_DELETE,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_FORCE_DELETE),
+		HelpCategories:  []string{CMD_DELETE, CMD_REVOKE},
+		HelpDescription: "Delete certificates even if they are referenced by the nginx configuration, which will stop nginx from starting until the configuration is fixed.",
+	}
 	flagNonInteractive = &cli.Flag{
 		Name:            FLAG_NON_INTERACTIVE,
 		AltNames:        []string{FLAG_NONINTERACTIVE, FLAG_NON_INTERACTIVE_SHORT},
+		PostParseFunc:   cli.SetConfigValue(CONFIG_NON_INTERACTIVE),
 		HelpDescription: "Run without ever asking for user input. This may require additional command line flags; the client will try to explain which ones are required if it finds one missing",
 	}
 	flagForceInteractive = &cli.Flag{
 		Name:            FLAG_FORCE_INTERACTIVE,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_FORCE_INTERACTIVE),
 		HelpCategories:  []string{CMD_CERTONLY},
 		HelpDescription: "Force Certbot to be interactive even if it detects it's not being run in a terminal. This flag cannot be used with the renew command.",
 	}
+	flagDryRun = &cli.Flag{
+		Name:            FLAG_DRY_RUN,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_DRY_RUN),
+		HelpCategories:  []string{CMD_RENEW},
+		HelpDescription: "Test \"renew\" without saving any certificates to disk, using the staging server unless --server is set",
+	}
+	flagForceRenewal = &cli.Flag{
+		Name:            FLAG_FORCE_RENEWAL,
+		AltNames:        []string{FLAG_RENEW_BY_DEFAULT},
+		PostParseFunc:   cli.SetConfigValue(CONFIG_FORCE_RENEWAL),
+		HelpCategories:  []string{CMD_RENEW},
+		HelpDescription: "If a certificate already exists for the requested domains, renew it now, regardless of whether it is near expiry.",
+	}
+
+	flagKeyType = &cli.Flag{
+		Name:            FLAG_KEY_TYPE,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_KEY_TYPE),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_KEY_TYPE),
+		HelpValueName:   "{rsa,ecdsa}",
+		HelpDescription: "Type of generated private key. Only *ONE* per invocation can be provided at this time.",
+		HelpCategories:  []string{CATEGORY_SECURITY},
+	}
+	flagRSAKeySize = &cli.Flag{
+		Name:            FLAG_RSA_KEY_SIZE,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_RSA_KEY_SIZE),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_RSA_KEY_SIZE),
+		HelpValueName:   "N",
+		HelpDescription: "Size of the RSA key.",
+		HelpCategories:  []string{CATEGORY_SECURITY},
+	}
+	flagEllipticCurve = &cli.Flag{
+		Name:            FLAG_ELLIPTIC_CURVE,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_ELLIPTIC_CURVE),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_ELLIPTIC_CURVE),
+		HelpValueName:   "N",
+		HelpDescription: "The SECG elliptic curve name to use, one of secp256r1, secp384r1 or secp521r1.",
+		HelpCategories:  []string{CATEGORY_SECURITY},
+	}
+	flagReuseKey = &cli.Flag{
+		Name:            FLAG_REUSE_KEY,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_REUSE_KEY),
+		HelpDescription: "When renewing, use the same private key as the existing certificate.",
+		HelpCategories:  []string{CATEGORY_SECURITY, CMD_RENEW},
+	}
+	flagNewKey = &cli.Flag{
+		Name:            FLAG_NEW_KEY,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_NEW_KEY),
+		HelpDescription: "When renewing or replacing a certificate, generate a new private key, even if --reuse-key is set on the existing certificate. Combining --new-key and --reuse-key will result in the private key being replaced and then reused in future renewals.",
+		HelpCategories:  []string{CATEGORY_SECURITY, CMD_RENEW},
+	}
+
+	flagMustStaple = &cli.Flag{
+		Name:            FLAG_MUST_STAPLE,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_MUST_STAPLE),
+		HelpDescription: "Adds the OCSP Must-Staple extension to the certificate.",
+		HelpCategories:  []string{CATEGORY_SECURITY},
+	}
+	flagRedirect = &cli.Flag{
+		Name:            FLAG_REDIRECT,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_REDIRECT),
+		HelpDescription: "Automatically redirect all HTTP traffic to HTTPS for the newly authenticated vhost.",
+		HelpCategories:  []string{CATEGORY_SECURITY, CMD_ENHANCE},
+	}
+	flagHSTS = &cli.Flag{
+		Name:            FLAG_HSTS,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_HSTS),
+		HelpDescription: "Add the Strict-Transport-Security header to every HTTP response. Forcing browser to always use SSL for the domain. Defends against SSL Stripping.",
+		HelpCategories:  []string{CATEGORY_SECURITY, CMD_ENHANCE},
+	}
+	flagUIR = &cli.Flag{
+		Name:            FLAG_UIR,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_UIR),
+		HelpDescription: "Add the \"Content-Security-Policy: upgrade-insecure-requests\" header to every HTTP response. Forcing the browser to use https:// for every http:// resource.",
+		HelpCategories:  []string{CATEGORY_SECURITY, CMD_ENHANCE},
+	}
+	flagStapleOCSP = &cli.Flag{
+		Name:            FLAG_STAPLE_OCSP,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_STAPLE_OCSP),
+		HelpDescription: "Enables OCSP Stapling. A valid OCSP response is stapled to the certificate that the server offers during TLS.",
+		HelpCategories:  []string{CATEGORY_SECURITY, CMD_ENHANCE},
+	}
+	flagCSR = &cli.Flag{
+		Name:            FLAG_CSR,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_CSR),
+		HelpCategories:  []string{CMD_CERTONLY},
+		HelpValueName:   "CSR",
+		HelpDescription: "Path to a Certificate Signing Request (CSR) in DER or PEM format. Currently --csr only works with the 'certonly' subcommand.",
+	}
+
+	flagServer = &cli.Flag{
+		Name:            FLAG_SERVER,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_SERVER),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_SERVER),
+		HelpValueName:   "SERVER",
+		HelpDescription: "ACME Directory Resource URI.",
+		HelpCategories:  []string{CATEGORY_PATHS},
+	}
+	flagAccount = &cli.Flag{
+		Name:            FLAG_ACCOUNT,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_ACCOUNT),
+		HelpValueName:   "ACCOUNT_ID",
+		HelpDescription: "Account ID to use",
+		HelpCategories:  []string{CATEGORY_MANAGE_ACCOUNT},
+	}
+	flagEmail = &cli.Flag{
+		Name:            FLAG_EMAIL,
+		AltNames:        []string{FLAG_EMAIL_SHORT},
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_EMAIL),
+		HelpValueName:   "EMAIL",
+		HelpDescription: "Email used for registration and recovery contact. Use comma to register multiple emails, ex: u1@example.com,u2@example.com.",
+		HelpCategories:  []string{CATEGORY_COMMON, CATEGORY_MANAGE_ACCOUNT},
+	}
+	flagAgreeTOS = &cli.Flag{
+		Name:            FLAG_AGREE_TOS,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_AGREE_TOS),
+		HelpDescription: "Agree to the ACME Subscriber Agreement",
+		HelpCategories:  []string{CATEGORY_COMMON, CATEGORY_MANAGE_ACCOUNT},
+	}
+	flagRegisterUnsafelyWithoutEmail = &cli.Flag{
+		Name:            FLAG_REGISTER_UNSAFELY_WITHOUT_EMAIL,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_REGISTER_UNSAFELY_WITHOUT_EMAIL),
+		HelpDescription: "Specifying this flag enables registering an account with no email address. This is strongly discouraged, because you will be unable to receive notice about impending expiration or revocation of your certificates or problems with your Certbot installation that will lead to failure to renew.",
+		HelpCategories:  []string{CATEGORY_COMMON},
+	}
+
+	flagAuthenticator = &cli.Flag{
+		Name:            FLAG_AUTHENTICATOR,
+		AltNames:        []string{FLAG_AUTHENTICATOR_SHORT},
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_AUTHENTICATOR),
+		HelpValueName:   "AUTHENTICATOR",
+		HelpDescription: "Authenticator plugin name.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagInstaller = &cli.Flag{
+		Name:            FLAG_INSTALLER,
+		AltNames:        []string{FLAG_INSTALLER_SHORT},
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_INSTALLER),
+		HelpValueName:   "INSTALLER",
+		HelpDescription: "Installer plugin name.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagNginx = &cli.Flag{
+		Name:            FLAG_NGINX,
+		PostParseFunc:   cli.SetConfigFixedValue(CONFIG_INSTALLER, installer.NginxName),
+		HelpDescription: "Use the Nginx plugin for installation.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagApache = &cli.Flag{
+		Name:            FLAG_APACHE,
+		PostParseFunc:   cli.SetConfigFixedValue(CONFIG_INSTALLER, installer.ApacheName),
+		HelpDescription: "Use the Apache plugin for installation.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagStandalone = &cli.Flag{
+		Name:            FLAG_STANDALONE,
+		PostParseFunc:   cli.SetConfigFixedValue(CONFIG_AUTHENTICATOR, authenticator.StandaloneName),
+		HelpDescription: "Obtain certificates using a \"standalone\" webserver.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagHTTP01Port = &cli.Flag{
+		Name:            FLAG_HTTP01_PORT,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_HTTP01_PORT),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_HTTP01_PORT),
+		HelpValueName:   "HTTP01_PORT",
+		HelpDescription: "Port used in the http-01 challenge. This only affects the port Certbot listens on. A conforming ACME server will still attempt to connect on port 80.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagHTTP01Address = &cli.Flag{
+		Name:            FLAG_HTTP01_ADDRESS,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_HTTP01_ADDRESS),
+		HelpValueName:   "HTTP01_ADDRESS",
+		HelpDescription: "The address the server listens to during http-01 challenge.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+
+	flagTLSALPN01Port = &cli.Flag{
+		Name:            FLAG_TLSALPN01_PORT,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_TLSALPN01_PORT),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_TLSALPN01_PORT),
+		HelpValueName:   "TLSALPN01_PORT",
+		HelpDescription: "Port used in the tls-alpn-01 challenge. This only affects the port Certbot listens on. A conforming ACME server will still attempt to connect on port 443.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagTLSALPN01Address = &cli.Flag{
+		Name:            FLAG_TLSALPN01_ADDRESS,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_TLSALPN01_ADDRESS),
+		HelpValueName:   "TLSALPN01_ADDRESS",
+		HelpDescription: "The address the server listens to during tls-alpn-01 challenge.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagPreferredChallenges = &cli.Flag{
+		Name:            FLAG_PREFERRED_CHALLENGES,
+		TakesValue:      true,
+		RequiresValue:   true,
+		AllowMultiple:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_PREFERRED_CHALLENGES),
+		HelpValueName:   "PREF_CHALLS",
+		HelpDescription: "A sorted, comma delimited list of the preferred challenge to use during authorization with the most preferred challenge listed first (Eg, \"dns\" or \"http,dns\"). Not all plugins support all challenges.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagWebroot = &cli.Flag{
+		Name:            FLAG_WEBROOT,
+		PostParseFunc:   cli.SetConfigFixedValue(CONFIG_AUTHENTICATOR, authenticator.WebrootName),
+		HelpDescription: "Place files in a server's webroot folder for authentication.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagWebrootPath = &cli.Flag{
+		Name:            FLAG_WEBROOT_PATH,
+		AltNames:        []string{FLAG_WEBROOT_PATH_SHORT},
+		TakesValue:      true,
+		RequiresValue:   true,
+		AllowMultiple:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_WEBROOT_PATH),
+		HelpValueName:   "WEBROOT_PATH",
+		HelpDescription: "public_html / webroot path. This can be specified multiple times to handle different domains; each domain will have the webroot path that preceded it. For instance: `-w /var/www/example -d example.com -d www.example.com -w /var/www/thing -d thing.net -d m.thing.net`",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagWebrootMap = &cli.Flag{
+		Name:            FLAG_WEBROOT_MAP,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_WEBROOT_MAP),
+		HelpValueName:   "WEBROOT_MAP",
+		HelpDescription: "JSON dictionary mapping domains to webroot paths; this implies -d for each entry. You may need to escape this from your shell. E.g.: --webroot-map '{\"eg1.is,m.eg1.is\":\"/www/eg1/\", \"eg2.is\":\"/www/eg2\"}' This option is merged with, but takes precedence over, -w / -d entries.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagManual = &cli.Flag{
+		Name:            FLAG_MANUAL,
+		PostParseFunc:   cli.SetConfigFixedValue(CONFIG_AUTHENTICATOR, authenticator.ManualName),
+		HelpDescription: "Provide laborious manual instructions for obtaining a certificate",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagManualAuthHook = &cli.Flag{
+		Name:            FLAG_MANUAL_AUTH_HOOK,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_MANUAL_AUTH_HOOK),
+		HelpValueName:   "MANUAL_AUTH_HOOK",
+		HelpDescription: "Path or command to execute for the authentication script",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagManualCleanupHook = &cli.Flag{
+		Name:            FLAG_MANUAL_CLEANUP_HOOK,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_MANUAL_CLEANUP_HOOK),
+		HelpValueName:   "MANUAL_CLEANUP_HOOK",
+		HelpDescription: "Path or command to execute for the cleanup script",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagDNSRFC2136 = &cli.Flag{
+		Name:            FLAG_DNS_RFC2136,
+		PostParseFunc:   cli.SetConfigFixedValue(CONFIG_AUTHENTICATOR, authenticator.RFC2136Name),
+		HelpDescription: "Obtain certificates using a DNS TXT record (if you are using BIND for DNS).",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagDNSRFC2136Credentials = &cli.Flag{
+		Name:            FLAG_DNS_RFC2136_CREDENTIALS,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_DNS_RFC2136_CREDENTIALS),
+		HelpValueName:   "DNS_RFC2136_CREDENTIALS",
+		HelpDescription: "RFC 2136 credentials INI file.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagDNSRFC2136PropagationSeconds = &cli.Flag{
+		Name:            FLAG_DNS_RFC2136_PROPAGATION_SECONDS,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_DNS_RFC2136_PROPAGATION_SECONDS),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_DNS_RFC2136_PROPAGATION_SECONDS),
+		HelpValueName:   "DNS_RFC2136_PROPAGATION_SECONDS",
+		HelpDescription: "The number of seconds to wait for DNS to propagate before asking the ACME server to verify the DNS record.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagNginxServerRoot = &cli.Flag{
+		Name:            FLAG_NGINX_SERVER_ROOT,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_NGINX_SERVER_ROOT),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_NGINX_SERVER_ROOT),
+		HelpValueName:   "NGINX_SERVER_ROOT",
+		HelpDescription: "Nginx server root directory.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagNginxCtl = &cli.Flag{
+		Name:            FLAG_NGINX_CTL,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_NGINX_CTL),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_NGINX_CTL),
+		HelpValueName:   "NGINX_CTL",
+		HelpDescription: "Path to the 'nginx' binary, used for 'configtest' and reloading nginx.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagApacheServerRoot = &cli.Flag{
+		Name:            FLAG_APACHE_SERVER_ROOT,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_APACHE_SERVER_ROOT),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_APACHE_SERVER_ROOT),
+		HelpValueName:   "APACHE_SERVER_ROOT",
+		HelpDescription: "Apache server root directory.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagApacheCtl = &cli.Flag{
+		Name:            FLAG_APACHE_CTL,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_APACHE_CTL),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_APACHE_CTL),
+		HelpValueName:   "APACHE_CTL",
+		HelpDescription: "Path to the 'apachectl' binary, used for 'configtest' and gracefully restarting apache.",
+		HelpCategories:  []string{CATEGORY_PLUGINS},
+	}
+	flagCheckpoints = &cli.Flag{
+		Name:            FLAG_CHECKPOINTS,
+		TakesValue:      true,
+		RequiresValue:   true,
+		PostParseFunc:   cli.SetConfigValue(CONFIG_CHECKPOINTS),
+		HelpDefault:     cli.GetConfigDefault(CONFIG_CHECKPOINTS),
+		HelpValueName:   "N",
+		HelpDescription: "Revert configuration N number of checkpoints.",
+		HelpCategories:  []string{CMD_ROLLBACK},
+	}
 )
@@ -6,8 +6,11 @@ const (
 	CATEGORY_USAGE               = "usage"
 	CATEGORY_COMMON              = "common"
 	CATEGORY_MANAGE_CERTIFICATES = "manage"
+	CATEGORY_MANAGE_ACCOUNT      = "account"
 	CATEGORY_OPTIONAL            = "optional"
 	CATEGORY_PATHS               = "paths"
+	CATEGORY_SECURITY            = "security"
+	CATEGORY_PLUGINS             = "plugins"
 )
 
 var (
@@ -28,6 +31,11 @@ var (
 		Name:     "manage certificates",
 		ShowFunc: cli.ShowNoCategory,
 	}
+	catManageAccount = &cli.HelpCategory{
+		Category: CATEGORY_MANAGE_ACCOUNT,
+		Name:     "manage your account",
+		ShowFunc: cli.ShowNoCategory,
+	}
 	catOptional = &cli.HelpCategory{
 		Category: CATEGORY_OPTIONAL,
 		Name:     "optional arguments",
@@ -38,4 +46,16 @@ var (
 		Description: "Flags for changing execution paths & servers",
 		ShowFunc:    cli.ShowNoCategory,
 	}
+	catSecurity = &cli.HelpCategory{
+		Category:    CATEGORY_SECURITY,
+		Name:        "security",
+		Description: "Security parameters & server settings",
+		ShowFunc:    cli.ShowNoCategory,
+	}
+	catPlugins = &cli.HelpCategory{
+		Category:    CATEGORY_PLUGINS,
+		Name:        "plugins",
+		Description: "Plugin Selection: Certgot uses authenticator plugins to prove control of the requested domains to the ACME server, and installer plugins to configure a webserver to use the certificate.",
+		ShowFunc:    cli.ShowNoCategory,
+	}
 )
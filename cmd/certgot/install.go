@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eggsampler/certgot/cli"
+	"github.com/eggsampler/certgot/installer"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
+)
+
+var (
+	// installerNames is the list of installers that can be selected
+	installerNames = []string{
+		installer.NginxName,
+		installer.ApacheName,
+	}
+
+	// enhancementConfigs are the configs selecting each enhancement, named the same as the enhancements
+	enhancementConfigs = []struct {
+		name string
+		cfg  *cli.Config
+	}{
+		{installer.EnhanceRedirect, cfgRedirect},
+		{installer.EnhanceHSTS, cfgHSTS},
+		{installer.EnhanceUIR, cfgUIR},
+		{installer.EnhanceStapleOCSP, cfgStapleOCSP},
+	}
+)
+
+// getInstaller returns the installer selected by the user
+func getInstaller() (installer.Installer, error) {
+	name := cfgInstaller.String()
+	if name == "" {
+		return nil, fmt.Errorf("no installer selected, use the --%s flag with one of: %s",
+			FLAG_INSTALLER, strings.Join(installerNames, ", "))
+	}
+	return newInstaller(name)
+}
+
+func newInstaller(name string) (installer.Installer, error) {
+	log.WithField("installer", name).Debug("creating installer")
+	switch strings.ToLower(name) {
+	case installer.NginxName:
+		var choose func(string, []string) ([]int, error)
+		if isInteractive() {
+			choose = chooseServerBlocks
+		}
+		return installer.NewNginx(cfgNginxServerRoot.String(), cfgNginxCtl.String(), cfgConfigDir.String(), getReverter(), choose)
+	case installer.ApacheName:
+		var choose func(string, []string) ([]int, error)
+		if isInteractive() {
+			choose = chooseVirtualHosts
+		}
+		return installer.NewApache(cfgApacheServerRoot.String(), cfgApacheCtl.String(), cfgConfigDir.String(), getReverter(), choose)
+	}
+	return nil, fmt.Errorf("unknown installer %q, valid installers: %s",
+		name, strings.Join(installerNames, ", "))
+}
+
+// getReverter returns the reverter used to checkpoint the files changed by installers, in the work directory
+func getReverter() *installer.Reverter {
+	return installer.NewReverter(cfgWorkDir.String())
+}
+
+// recoverCheckpoint reverts any temporary checkpoint left by a previous run that was interrupted while installing
+func recoverCheckpoint() error {
+	reverter := getReverter()
+	if !reverter.HasTempCheckpoint() {
+		return nil
+	}
+	fmt.Println("Reverting incomplete server configuration changes from a previous run.")
+	if err := reverter.RevertTempCheckpoint(); err != nil {
+		return fmt.Errorf("error reverting incomplete changes in %s: %w", reverter.WorkDir, err)
+	}
+	return nil
+}
+
+// chooseServerBlocks asks the user which server blocks to install the certificate for the domain in
+func chooseServerBlocks(domain string, servers []string) ([]int, error) {
+	return promptChoices(fmt.Sprintf("Which server blocks would you like to install the certificate for %s in?", domain), servers)
+}
+
+// chooseVirtualHosts asks the user which VirtualHosts to install the certificate for the domain in
+func chooseVirtualHosts(domain string, vhosts []string) ([]int, error) {
+	return promptChoices(fmt.Sprintf("Which VirtualHosts would you like to install the certificate for %s in?", domain), vhosts)
+}
+
+// deployCertificate installs the certificate of the named lineage for each of the domains and makes the
+// enhancements, then saves the changes, see saveInstaller
+func deployCertificate(inst installer.Installer, name string, cert installer.Certificate, domains, enhancements []string) error {
+	for _, domain := range domains {
+		if err := inst.DeployCert(domain, cert); err != nil {
+			return fmt.Errorf("error deploying certificate for %s with %s installer: %w", domain, inst.Name(), err)
+		}
+		log.WithFields("installer", inst.Name(), "domain", domain, "name", name).Debug("deployed certificate")
+	}
+	if err := enhanceDomains(inst, cert, domains, enhancements); err != nil {
+		return err
+	}
+	return saveInstaller(inst, fmt.Sprintf("Deployed certificate %s for %s", name, strings.Join(domains, ", ")))
+}
+
+// printDeployed tells the user the certificate was deployed for the domains, with the enhancements
+func printDeployed(inst installer.Installer, domains, enhancements []string) {
+	for _, domain := range domains {
+		fmt.Printf("Successfully deployed certificate for %s using the %s installer\n", domain, inst.Name())
+	}
+	if len(enhancements) > 0 {
+		fmt.Printf("Successfully added %s to the configuration\n", strings.Join(enhancements, ", "))
+	}
+}
+
+// lineageCertificate returns the files of the lineage for the installer
+func lineageCertificate(l *storage.Lineage) installer.Certificate {
+	return installer.Certificate{
+		CertPath:      l.CertPath,
+		ChainPath:     l.ChainPath,
+		FullChainPath: l.FullChainPath,
+		KeyPath:       l.PrivKeyPath,
+	}
+}
+
+// selectedEnhancements returns the enhancements selected by the user's flags
+func selectedEnhancements() []string {
+	var enhancements []string
+	for _, e := range enhancementConfigs {
+		if e.cfg.Bool() {
+			enhancements = append(enhancements, e.name)
+		}
+	}
+	return enhancements
+}
+
+// checkEnhancements returns an error if the installer doesn't support any of the enhancements
+func checkEnhancements(inst installer.Installer, enhancements []string) error {
+	supported := inst.SupportedEnhancements()
+	for _, e := range enhancements {
+		found := false
+		for _, s := range supported {
+			found = found || s == e
+		}
+		if !found {
+			return fmt.Errorf("the %s installer does not support --%s", inst.Name(), e)
+		}
+	}
+	return nil
+}
+
+// enhanceDomains makes each of the enhancements for each of the domains
+func enhanceDomains(inst installer.Installer, cert installer.Certificate, domains, enhancements []string) error {
+	for _, e := range enhancements {
+		for _, domain := range domains {
+			if err := inst.Enhance(domain, e, cert); err != nil {
+				return fmt.Errorf("error adding %s for %s with %s installer: %w", e, domain, inst.Name(), err)
+			}
+			log.WithFields("installer", inst.Name(), "domain", domain, "enhancement", e).Debug("enhanced config")
+		}
+	}
+	return nil
+}
+
+// saveInstaller saves the changes made by the installer and restarts the webserver, then finalizes the checkpoint
+// with the title. If the saved config fails the webserver's config test or restart, the changes are rolled back.
+func saveInstaller(inst installer.Installer, title string) error {
+	if err := inst.Save(); err != nil {
+		return rollbackInstaller(inst, err)
+	}
+	if err := inst.ConfigTest(); err != nil {
+		return rollbackInstaller(inst, err)
+	}
+	if err := inst.Restart(); err != nil {
+		err = rollbackInstaller(inst, err)
+		if restartErr := inst.Restart(); restartErr != nil {
+			log.WithError(restartErr).Error("restarting webserver after rollback")
+		}
+		return err
+	}
+	return getReverter().FinalizeCheckpoint(title)
+}
+
+// rollbackInstaller rolls back the changes saved by the installer after an error, returning the error
+func rollbackInstaller(inst installer.Installer, err error) error {
+	log.WithError(err).WithField("installer", inst.Name()).Debug("rolling back installer changes")
+	if rollbackErr := inst.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("%w, and rolling back the changes failed: %v", err, rollbackErr)
+	}
+	return fmt.Errorf("%w, the changes have been rolled back", err)
+}
+
+// redeployCertificate deploys the certificate of a renewed lineage with the installer stored for it, so the webserver
+// uses the new certificate. certbot stores None for certificates obtained without an installer.
+func redeployCertificate(l *storage.Lineage, domains []string) error {
+	name := cfgInstaller.String()
+	if name == "" || strings.EqualFold(name, "none") {
+		return nil
+	}
+	inst, err := newInstaller(name)
+	if err != nil {
+		return err
+	}
+	return deployCertificate(inst, l.Name, lineageCertificate(l), domains, nil)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
+	"github.com/eggsampler/certgot/util"
+)
+
+// getCertificateKey returns the private key for a new certificate, and the pem encoded key to save
+// If --reuse-key is set and the lineage named by --cert-name exists, its current key is reused, unless --new-key is set
+func getCertificateKey() (crypto.Signer, []byte, error) {
+	keyType := strings.ToLower(cfgKeyType.String())
+	if cfgReuseKey.Bool() && !cfgNewKey.Bool() && cfgCertName.String() != "" {
+		l, err := getStorage().Lineage(cfgCertName.String())
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, err
+		}
+		if l != nil {
+			keyPem, err := ioutil.ReadFile(l.PrivKeyPath)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading current private key: %v", err)
+			}
+			key, err := util.ParsePrivateKey(keyPem)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error parsing current private key %s: %v", l.PrivKeyPath, err)
+			}
+			if cfgKeyType.IsSet() && util.KeyType(key) != keyType {
+				return nil, nil, fmt.Errorf("unable to change the --%s of certificate %s because --%s is set, use --%s to generate a new key",
+					FLAG_KEY_TYPE, l.Name, FLAG_REUSE_KEY, FLAG_NEW_KEY)
+			}
+			log.WithFields("name", l.Name, "path", l.PrivKeyPath).Debug("reusing private key")
+			return key, keyPem, nil
+		}
+	}
+
+	log.WithFields("keytype", keyType, "rsakeysize", cfgRSAKeySize.Int(), "curve", cfgEllipticCurve.String()).
+		Debug("generating private key")
+	key, err := util.GenerateKey(keyType, cfgRSAKeySize.Int(), strings.ToLower(cfgEllipticCurve.String()))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPem, err := util.EncodePrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, keyPem, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/eggsampler/certgot/authenticator"
+	"github.com/eggsampler/certgot/installer"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/storage"
+	"github.com/eggsampler/certgot/util"
+)
+
+// getStorage returns the certificate storage in the config directory
+func getStorage() *storage.Storage {
+	return storage.New(cfgConfigDir.String())
+}
+
+// getLineage returns the lineage named by --cert-name, or asks the user to choose one with the question
+func getLineage(question string) (*storage.Lineage, error) {
+	if cfgCertName.IsSet() {
+		return getStorage().Lineage(cfgCertName.String())
+	}
+	lineages, err := chooseLineages(question)
+	if errors.Is(err, errNonInteractive) {
+		return nil, fmt.Errorf("no certificate name provided, use the --%s flag", FLAG_CERT_NAME)
+	} else if err != nil {
+		return nil, err
+	}
+	if len(lineages) != 1 {
+		return nil, errors.New("select a single certificate")
+	}
+	return lineages[0], nil
+}
+
+// findLineageByCertPath returns the lineage the certificate file belongs to, either by its live symlink or a version
+// in the archive, or nil if the certificate isn't part of any lineage
+func findLineageByCertPath(certPath string) (*storage.Lineage, error) {
+	abs, err := filepath.Abs(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("error finding absolute path of %s: %v", certPath, err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving path %s: %v", certPath, err)
+	}
+	lineages, _ := loadLineages("")
+	for _, l := range lineages {
+		if abs == l.CertPath || abs == l.FullChainPath || filepath.Dir(resolved) == l.ArchiveDir {
+			log.WithFields("path", certPath, "name", l.Name).Debug("found lineage for certificate")
+			return l, nil
+		}
+	}
+	return nil, nil
+}
+
+// deleteLineage deletes all the files of the lineage, refusing to if the lineage is used in the nginx config unless
+// forced, as nginx would fail to start without them
+func deleteLineage(l *storage.Lineage) error {
+	if err := checkLocked(cfgConfigDir.String()); err != nil {
+		return err
+	}
+	if !cfgForceDelete.Bool() {
+		refs, err := nginxReferences(cfgNginxServerRoot.String(), []string{l.LiveDir(), l.ArchiveDir})
+		if err != nil {
+			return fmt.Errorf("error checking nginx config for certificate %s: %v", l.Name, err)
+		}
+		if len(refs) > 0 {
+			return fmt.Errorf("certificate %s is used in the nginx config (%s), use --%s to delete it anyway",
+				l.Name, strings.Join(refs, ", "), FLAG_FORCE_DELETE)
+		}
+	}
+	return getStorage().DeleteLineage(l)
+}
+
+// saveLineage saves the certificate and pem encoded key as a new version of the lineage named by --cert-name if it exists,
+// otherwise a new lineage is created, named after the first domain if no cert name is set
+func saveLineage(domains []string, keyPem []byte, certs []*x509.Certificate, auth authenticator.Authenticator, acct *storage.Account) (*storage.Lineage, error) {
+	cert, chain := encodeCertificates(certs)
+
+	store := getStorage()
+	certName := cfgCertName.String()
+	var l *storage.Lineage
+	var err error
+	if certName != "" {
+		l, err = store.Lineage(certName)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, err
+		}
+	}
+	if l != nil {
+		version, err := l.SaveVersion(cert, chain, keyPem)
+		if err != nil {
+			return nil, err
+		}
+		log.WithFields("name", l.Name, "version", version).Debug("saved new certificate version")
+	} else {
+		if certName == "" {
+			certName = domains[0]
+		}
+		l, err = store.NewLineage(certName, cert, chain, keyPem)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	setRenewalParams(l, auth, acct)
+	if err := l.Save(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// encodeCertificates pem encodes the leaf certificate and the rest of the chain
+func encodeCertificates(certs []*x509.Certificate) (cert, chain []byte) {
+	for i, c := range certs {
+		b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+		if i == 0 {
+			cert = b
+		} else {
+			chain = append(chain, b...)
+		}
+	}
+	return cert, chain
+}
+
+// setRenewalParams stores the options used to obtain the certificate in the lineage, using the same keys as certbot
+// The account is stored so renewals use the same account, even if there are others for the server
+func setRenewalParams(l *storage.Lineage, auth authenticator.Authenticator, acct *storage.Account) {
+	l.SetRenewalParam("authenticator", auth.Name())
+	l.SetRenewalParam("server", cfgServer.String())
+	l.SetRenewalParam("account", acct.ID)
+	// any error in the preferred challenges has already been returned while authenticating
+	prefChalls, _ := getPreferredChallenges()
+	l.SetRenewalParam("pref_challs", formatList(prefChalls))
+
+	keyType := strings.ToLower(cfgKeyType.String())
+	l.SetRenewalParam("key_type", keyType)
+	l.SetRenewalParam("rsa_key_size", "")
+	l.SetRenewalParam("elliptic_curve", "")
+	switch keyType {
+	case util.KeyTypeRSA:
+		l.SetRenewalParam("rsa_key_size", cfgRSAKeySize.String())
+	case util.KeyTypeECDSA:
+		l.SetRenewalParam("elliptic_curve", strings.ToLower(cfgEllipticCurve.String()))
+	}
+	l.SetRenewalParam("reuse_key", formatBool(cfgReuseKey.Bool()))
+	l.SetRenewalParam("must_staple", formatBool(cfgMustStaple.Bool()))
+
+	setInstallerParams(l)
+
+	switch a := auth.(type) {
+	case *authenticator.Standalone:
+		if cfgHTTP01Port.IsSet() {
+			l.SetRenewalParam("http01_port", cfgHTTP01Port.String())
+		}
+		l.SetRenewalParam("http01_address", cfgHTTP01Address.String())
+		if cfgTLSALPN01Port.IsSet() {
+			l.SetRenewalParam("https_port", cfgTLSALPN01Port.String())
+		}
+		l.SetRenewalParam("tls_alpn01_address", cfgTLSALPN01Address.String())
+	case *authenticator.Webroot:
+		l.SetRenewalParam("webroot_path", formatList(cfgWebrootPath.StringSlice()))
+		l.SetSubsection("webroot_map", a.Map)
+	case *authenticator.Manual:
+		l.SetRenewalParam("manual_auth_hook", a.AuthHook)
+		l.SetRenewalParam("manual_cleanup_hook", a.CleanupHook)
+	case *authenticator.RFC2136:
+		l.SetRenewalParam("dns_rfc2136_credentials", cfgDNSRFC2136Credentials.String())
+		if cfgDNSRFC2136PropagationSeconds.IsSet() {
+			l.SetRenewalParam("dns_rfc2136_propagation_seconds", cfgDNSRFC2136PropagationSeconds.String())
+		}
+	}
+}
+
+// setInstallerParams stores the installer used for the certificate and its options in the lineage, if one is set,
+// so renewed certificates are deployed with it
+func setInstallerParams(l *storage.Lineage) {
+	if !cfgInstaller.IsSet() {
+		return
+	}
+	l.SetRenewalParam("installer", cfgInstaller.String())
+	switch strings.ToLower(cfgInstaller.String()) {
+	case installer.NginxName:
+		l.SetRenewalParam("nginx_server_root", cfgNginxServerRoot.String())
+		l.SetRenewalParam("nginx_ctl", cfgNginxCtl.String())
+	case installer.ApacheName:
+		l.SetRenewalParam("apache_server_root", cfgApacheServerRoot.String())
+		l.SetRenewalParam("apache_ctl", cfgApacheCtl.String())
+	}
+}
+
+// formatList formats a list the same way certbot does in renewal config files, ie comma separated and with a
+// trailing comma for a single value so it is still read back as a list
+func formatList(values []string) string {
+	switch len(values) {
+	case 0:
+		return ""
+	case 1:
+		return values[0] + ","
+	}
+	return strings.Join(values, ", ")
+}
+
+// formatBool formats a bool the same way certbot does in renewal config files
+func formatBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+// parseList parses a list value from a renewal config file
+func parseList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
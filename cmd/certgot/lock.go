@@ -30,6 +30,17 @@ func setupLocks(dirs []string) error {
 	return nil
 }
 
+// checkLocked makes sure the lock file for the directory is held, before making changes in it
+func checkLocked(dir string) error {
+	path := filepath.Join(dir, ".certbot.lock")
+	for _, lf := range lockFiles {
+		if lf.Path() == path && lf.Locked() {
+			return nil
+		}
+	}
+	return fmt.Errorf("lock file %s is not held", path)
+}
+
 func cleanupLocks() (errors []error) {
 	for _, lf := range lockFiles {
 		log.WithField("lockfile", lf.Path()).Trace("cleaning up lock file")
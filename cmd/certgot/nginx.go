@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/parser/nginx"
+)
+
+// nginxReferences loads the nginx config in the server root, following includes like nginx, and returns the
+// locations of directives in any loaded file that reference any of the paths, or any file inside them if they are
+// directories, eg sites-enabled/default:12. Files nginx doesn't load, such as disabled sites, aren't checked.
+func nginxReferences(root string, paths []string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(root, nginx.MainFile)); errors.Is(err, os.ErrNotExist) {
+		log.WithField("root", root).Debug("no nginx config in server root")
+		return nil, nil
+	}
+	cfg, err := nginx.Load(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []string
+	for _, f := range cfg.Files {
+		for _, d := range directiveReferences(f.Directives, paths) {
+			if rel, err := filepath.Rel(root, d.File); err == nil {
+				d.File = rel
+			}
+			locations = append(locations, d.Location())
+		}
+	}
+	return locations, nil
+}
+
+// directiveReferences returns the directives, including any children, that have a parameter that is one of the paths,
+// or inside one of them
+func directiveReferences(dirs []nginx.Directive, paths []string) []nginx.Directive {
+	var refs []nginx.Directive
+	for _, d := range dirs {
+		if !d.Comment && referencesPath(d, paths) {
+			refs = append(refs, d)
+		}
+		refs = append(refs, directiveReferences(d.Children, paths)...)
+	}
+	return refs
+}
+
+func referencesPath(d nginx.Directive, paths []string) bool {
+	for _, param := range d.Parameters {
+		param = filepath.Clean(strings.Trim(param, `"'`))
+		for _, p := range paths {
+			if param == p || strings.HasPrefix(param, p+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/eggsampler/certgot/acme"
+	"github.com/eggsampler/certgot/authenticator"
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/util"
+)
+
+// getDomains returns the list of domains set, splitting any comma separated values and removing duplicates
+// Domains in the webroot map are also included, as each entry implies a -d flag
+func getDomains() []string {
+	var domains []string
+	seen := map[string]bool{}
+	for _, v := range append(cfgDomains.StringSlice(), webrootMapDomains()...) {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "" || seen[d] {
+				continue
+			}
+			seen[d] = true
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// getContacts returns the list of contacts to register an account with, asking the user for an email if needed
+func getContacts() ([]string, error) {
+	email := cfgEmail.String()
+	if email == "" && !cfgRegisterUnsafelyWithoutEmail.Bool() {
+		var err error
+		email, err = promptString("Enter email address (used for urgent renewal and security notices):")
+		if errors.Is(err, errNonInteractive) {
+			return nil, fmt.Errorf("no email address provided, use the --%s flag or --%s",
+				FLAG_EMAIL, FLAG_REGISTER_UNSAFELY_WITHOUT_EMAIL)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	var contacts []string
+	for _, e := range strings.Split(email, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		contacts = append(contacts, "mailto:"+e)
+	}
+	return contacts, nil
+}
+
+// agreeTOS checks the user has agreed to the terms of service, asking them if not
+func agreeTOS(client *acme.Client) error {
+	if cfgAgreeTOS.Bool() {
+		return nil
+	}
+	agreed, err := promptYesNo(fmt.Sprintf("Please read the Terms of Service at %s. You must agree in order to register with the ACME server. Do you agree?",
+		client.Directory.Meta.TermsOfService), false)
+	if errors.Is(err, errNonInteractive) {
+		return fmt.Errorf("the terms of service must be agreed to, use the --%s flag", FLAG_AGREE_TOS)
+	} else if err != nil {
+		return err
+	}
+	if !agreed {
+		return errors.New("must agree to the terms of service")
+	}
+	return nil
+}
+
+func newACMEClient() (*acme.Client, error) {
+	server := cfgServer.String()
+	log.WithField("server", server).Debug("creating acme client")
+	client, err := acme.NewClient(server, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to acme server %s: %w", server, err)
+	}
+	return client, nil
+}
+
+// obtainCertificate creates an order for the names in the csr, satisfies all the authorizations,
+// and returns the issued certificate chain
+func obtainCertificate(client *acme.Client, acct acme.Account, auth authenticator.Authenticator, csr *x509.CertificateRequest) ([]*x509.Certificate, error) {
+	var idents []acme.Identifier
+	for _, name := range util.CSRNames(csr) {
+		identType := acme.IdentifierDNS
+		if net.ParseIP(name) != nil {
+			identType = acme.IdentifierIP
+		}
+		idents = append(idents, acme.Identifier{Type: identType, Value: name})
+	}
+	order, err := client.NewOrder(acct, idents)
+	if err != nil {
+		return nil, err
+	}
+	ll := log.WithField("order", order.URL)
+	ll.Debug("created order")
+
+	if err := authorize(client, acct, order, auth); err != nil {
+		return nil, err
+	}
+
+	order, err = client.FinalizeOrder(acct, order, csr)
+	if err != nil {
+		return nil, err
+	}
+	ll.WithField("certificate", order.Certificate).Debug("finalized order")
+
+	return client.FetchCertificates(acct, order.Certificate)
+}
+
+// authorize makes sure every authorization for the order is valid, using the authenticator to solve any pending ones
+func authorize(client *acme.Client, acct acme.Account, order acme.Order, auth authenticator.Authenticator) error {
+	var pending []acme.Authorization
+	for _, authzURL := range order.Authorizations {
+		authz, err := client.FetchAuthorization(acct, authzURL)
+		if err != nil {
+			return err
+		}
+		ll := log.WithFields("authorization", authzURL, "identifier", authz.Identifier.Value, "status", authz.Status)
+		ll.Debug("fetched authorization")
+		switch authz.Status {
+		case acme.StatusValid:
+			continue
+		case acme.StatusPending:
+			pending = append(pending, authz)
+		default:
+			return fmt.Errorf("authorization for %s is %s", authz.Identifier.Value, authz.Status)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	return authenticate(client, acct, auth, pending)
+}
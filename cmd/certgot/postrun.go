@@ -6,6 +6,10 @@ import (
 )
 
 func doPostRun(ctx *cli.Context, err error) error {
+	if authErr := cleanupAuthenticator(); authErr != nil {
+		log.WithError(authErr).Error("cleaning up authenticator")
+	}
+
 	errs := cleanupLocks()
 	for _, v := range errs {
 		log.WithError(v).Debug("cleaning up locks")
@@ -46,6 +46,10 @@ func doPreRun(ctx *cli.Context) error {
 		return fmt.Errorf("error setting up lock files: %w", err)
 	}
 
+	if err := recoverCheckpoint(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
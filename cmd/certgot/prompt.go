@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eggsampler/certgot/cli"
+)
+
+var (
+	errNonInteractive = errors.New("input required, but running non-interactively")
+
+	stdinReader = bufio.NewReader(os.Stdin)
+)
+
+// isInteractive returns whether the user can be asked for input
+func isInteractive() bool {
+	if cfgNonInteractive.Bool() {
+		return false
+	}
+	return cfgForceInteractive.Bool() || cli.IsTerminal()
+}
+
+// promptString asks the user a question, returning the trimmed answer
+func promptString(question string) (string, error) {
+	if !isInteractive() {
+		return "", errNonInteractive
+	}
+	fmt.Print(question + " ")
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil && answer == "" {
+		return "", fmt.Errorf("error reading input: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// promptInstructions shows some instructions to the user, waiting for them to press enter once they are completed
+func promptInstructions(instructions string) error {
+	if !isInteractive() {
+		return errNonInteractive
+	}
+	fmt.Println(instructions)
+	_, err := promptString("Press Enter to Continue")
+	return err
+}
+
+// promptYesNo asks the user a yes or no question, returning defaultAnswer if nothing is entered
+func promptYesNo(question string, defaultAnswer bool) (bool, error) {
+	options := "(y/N)"
+	if defaultAnswer {
+		options = "(Y/n)"
+	}
+	for {
+		answer, err := promptString(question + " " + options + ":")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return defaultAnswer, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Println("Please answer y or n.")
+	}
+}
+
+// promptChoices asks the user to choose one or more of the options by number, returning the indexes chosen in order
+// An empty answer chooses nothing
+func promptChoices(question string, options []string) ([]int, error) {
+	for {
+		fmt.Println(question)
+		for i, o := range options {
+			fmt.Printf("%d: %s\n", i+1, o)
+		}
+		answer, err := promptString("Select the appropriate numbers separated by commas and/or spaces, or leave input blank to select none:")
+		if err != nil {
+			return nil, err
+		}
+		var chosen []int
+		seen := map[int]bool{}
+		valid := true
+		for _, f := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+			n, err := strconv.Atoi(f)
+			if err != nil || n < 1 || n > len(options) {
+				valid = false
+				break
+			}
+			if !seen[n-1] {
+				seen[n-1] = true
+				chosen = append(chosen, n-1)
+			}
+		}
+		if valid {
+			return chosen, nil
+		}
+		fmt.Printf("Please enter numbers between 1 and %d.\n", len(options))
+	}
+}
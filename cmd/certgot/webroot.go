@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eggsampler/certgot/authenticator"
+	"github.com/eggsampler/certgot/log"
+)
+
+// getWebrootMapConfig returns the parsed webroot map config, or an empty map if not set
+func getWebrootMapConfig() (map[string]string, error) {
+	s := cfgWebrootMap.String()
+	if s == "" {
+		return map[string]string{}, nil
+	}
+	return authenticator.ParseWebrootMap(s)
+}
+
+// webrootMapDomains returns the domains in the webroot map config, as each entry implies a -d flag
+// Any error parsing the webroot map is ignored here and returned when the authenticator is created
+func webrootMapDomains() []string {
+	m, err := getWebrootMapConfig()
+	if err != nil {
+		return nil
+	}
+	var domains []string
+	for d := range m {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// getWebrootMap returns the webroot path to use for each of the domains
+//
+// Follows the certbot semantics where each -w flag applies to the -d flags that follow it, eg
+// `-w /var/www/example -d example.com -d www.example.com -w /var/www/thing -d thing.net`
+// Any domains not preceded by a -w flag use the last webroot path provided, and entries in the webroot map take
+// precedence over any -w/-d entries
+func getWebrootMap(domains []string) (map[string]string, error) {
+	m := map[string]string{}
+
+	// walk the -w and -d flags in the order they were provided
+	type arg struct {
+		position int
+		webroot  string
+		domains  []string
+	}
+	var args []arg
+	for _, v := range flagWebrootPath.ValueList() {
+		args = append(args, arg{position: v.Position, webroot: v.Value})
+	}
+	for _, v := range flagDomains.ValueList() {
+		var ds []string
+		for _, d := range strings.Split(v.Value, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d != "" {
+				ds = append(ds, d)
+			}
+		}
+		args = append(args, arg{position: v.Position, domains: ds})
+	}
+	sort.Slice(args, func(i, j int) bool {
+		return args[i].position < args[j].position
+	})
+
+	var webroot string
+	var pending []string
+	domainBeforeWebroot := false
+	for _, a := range args {
+		if a.webroot == "" {
+			pending = append(pending, a.domains...)
+			continue
+		}
+		if webroot != "" {
+			if domainBeforeWebroot {
+				return nil, errors.New("if you specify multiple webroot paths, one of them must precede all domain flags")
+			}
+			for _, d := range pending {
+				if _, ok := m[d]; !ok {
+					m[d] = webroot
+				}
+			}
+			pending = nil
+		} else if len(pending) > 0 {
+			domainBeforeWebroot = true
+		}
+		webroot = a.webroot
+	}
+
+	// the last webroot path applies to any remaining domains, this also covers paths set in a config file
+	if paths := cfgWebrootPath.StringSlice(); len(paths) > 0 {
+		webroot = paths[len(paths)-1]
+	}
+
+	webrootMap, err := getWebrootMapConfig()
+	if err != nil {
+		return nil, err
+	}
+	for d, w := range webrootMap {
+		m[d] = w
+	}
+
+	for _, d := range domains {
+		if _, ok := m[d]; ok {
+			continue
+		}
+		if webroot == "" {
+			w, err := promptString(fmt.Sprintf("Input the webroot for %s:", d))
+			if errors.Is(err, errNonInteractive) || (err == nil && w == "") {
+				return nil, fmt.Errorf("missing webroot path for domain %s, use the --%s flag", d, FLAG_WEBROOT_PATH)
+			} else if err != nil {
+				return nil, err
+			}
+			webroot = w
+		}
+		m[d] = webroot
+	}
+
+	log.WithField("webrootmap", authenticator.FormatWebrootMap(m)).Debug("webroot map")
+	return m, nil
+}
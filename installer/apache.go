@@ -0,0 +1,663 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/parser/apache"
+)
+
+const (
+	ApacheName = "apache"
+
+	// OptionsSSLApacheName is the file the apache installer writes to the config dir and includes in every https
+	// VirtualHost it creates, named the same as certbot's so configs it has installed keep working
+	OptionsSSLApacheName = "options-ssl-apache.conf"
+
+	// DefaultApacheCtl is the apachectl binary used to test the config and restart apache
+	DefaultApacheCtl = "apachectl"
+
+	// sslVHostSuffix replaces the .conf extension of a VirtualHost's file to name the file its https copy is added
+	// to, the same as certbot
+	sslVHostSuffix = "-le-ssl.conf"
+)
+
+// optionsSSLApache is the recommended tls settings, the same as certbot's which are based on
+// https://ssl-config.mozilla.org
+const optionsSSLApache = `# This file contains important security parameters, and is included in every VirtualHost
+# certgot has installed a certificate in.
+
+SSLEngine on
+
+SSLProtocol             all -SSLv2 -SSLv3 -TLSv1 -TLSv1.1
+SSLCipherSuite          ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384:ECDHE-ECDSA-CHACHA20-POLY1305:ECDHE-RSA-CHACHA20-POLY1305:DHE-RSA-AES128-GCM-SHA256:DHE-RSA-AES256-GCM-SHA384
+SSLHonorCipherOrder     off
+SSLSessionTickets       off
+
+SSLOptions +StrictRequire
+
+# Add vhost name to log entries:
+LogFormat "%h %l %u %t \"%r\" %>s %b \"%{Referer}i\" \"%{User-agent}i\"" vhost_combined
+LogFormat "%v %h %l %u %t \"%r\" %>s %b" vhost_common
+`
+
+// Apache is an installer which adds certificates to the matching VirtualHosts in an apache config
+type Apache struct {
+	// ServerRoot is the directory containing the main apache config file, eg /etc/apache2 or /etc/httpd
+	ServerRoot string
+
+	// Ctl is the path of the apachectl binary
+	Ctl string
+
+	// ConfigDir is the directory the tls options file is written to
+	ConfigDir string
+
+	// HTTPSPort is the port used for the https VirtualHosts created from plain http ones
+	HTTPSPort int
+
+	// Choose asks the user which VirtualHosts to install the certificate for a domain in, when it's ambiguous,
+	// returning the indexes of the chosen VirtualHosts. If nil, an ambiguous match is an error.
+	Choose func(domain string, vhosts []string) ([]int, error)
+
+	// Reverter backs up every file before it's written, so the changes can be rolled back
+	Reverter *Reverter
+
+	config *apache.Config
+	// changed holds the paths of config files that have been modified since they were last saved
+	changed map[string]bool
+	// links are the symlinks to make when saving, from the path of the link to its target, which enable sites and
+	// modules the Debian way
+	links map[string]string
+}
+
+// NewApache loads the apache config in the server root, returning an installer using it
+func NewApache(serverRoot, ctl, configDir string, reverter *Reverter, choose func(domain string, vhosts []string) ([]int, error)) (*Apache, error) {
+	cfg, err := apache.Load(serverRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error loading apache config: %w", err)
+	}
+	return &Apache{
+		ServerRoot: serverRoot,
+		Ctl:        ctl,
+		ConfigDir:  configDir,
+		HTTPSPort:  defaultHTTPSPort,
+		Choose:     choose,
+		Reverter:   reverter,
+		config:     cfg,
+		changed:    map[string]bool{},
+		links:      map[string]string{},
+	}, nil
+}
+
+func (a *Apache) Name() string {
+	return ApacheName
+}
+
+// DeployCert sets the certificate in the VirtualHosts matching the domain, see chooseVirtualHosts.
+// A VirtualHost that doesn't serve https is copied into an https VirtualHost in a new -le-ssl.conf file next to its
+// own, which is enabled along with mod_ssl if needed.
+func (a *Apache) DeployCert(domain string, cert Certificate) error {
+	indexes, err := a.chooseVirtualHosts(domain)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return fmt.Errorf("could not find a VirtualHost for %s, add it to a ServerName or ServerAlias directive to install the certificate", domain)
+	}
+
+	// https VirtualHosts are only added to the end of files, so finding every node first keeps them valid, except
+	// for an https VirtualHost at the top level of a file another one is added to, so those are deployed first
+	var vhosts []*apache.Node
+	all := a.config.VirtualHosts()
+	for _, idx := range indexes {
+		vhosts = append(vhosts, all[idx])
+	}
+	sort.SliceStable(vhosts, func(i, j int) bool {
+		return a.isSSL(vhosts[i]) && !a.isSSL(vhosts[j])
+	})
+
+	for _, vhost := range vhosts {
+		log.WithFields("domain", domain, "vhost", vhost.Location()).Debug("deploying certificate")
+		if !a.isSSL(vhost) {
+			if err := a.enableSSL(); err != nil {
+				return err
+			}
+			if vhost, err = a.addSSLVirtualHost(vhost); err != nil {
+				return err
+			}
+		}
+		if err := a.setCert(vhost, cert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chooseVirtualHosts returns the indexes of the VirtualHosts to change for the domain, or none if no VirtualHost
+// matches. These are the best matches for the domain, preferring those that already serve https. If there is more
+// than one, the user is asked to choose.
+func (a *Apache) chooseVirtualHosts(domain string) ([]int, error) {
+	matches := a.config.MatchVirtualHosts(domain)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	var best, ssl []apache.VirtualHostMatch
+	for _, m := range matches {
+		if m.Rank != matches[0].Rank {
+			break
+		}
+		best = append(best, m)
+		if a.isSSL(m.VirtualHost) {
+			ssl = append(ssl, m)
+		}
+	}
+	if len(ssl) > 0 {
+		best = ssl
+	}
+
+	vhosts := a.config.VirtualHosts()
+	indexOf := func(m apache.VirtualHostMatch) int {
+		for i, v := range vhosts {
+			if v.Directive == m.VirtualHost.Directive {
+				return i
+			}
+		}
+		return -1
+	}
+	if len(best) == 1 {
+		return []int{indexOf(best[0])}, nil
+	}
+
+	var descs []string
+	for _, m := range best {
+		descs = append(descs, fmt.Sprintf("%s (%s %s)", m.VirtualHost.Location(), m.Type, m.Name))
+	}
+	if a.Choose == nil {
+		return nil, fmt.Errorf("unable to choose a VirtualHost for %s from: %s", domain, strings.Join(descs, ", "))
+	}
+	chosen, err := a.Choose(domain, descs)
+	if err != nil {
+		return nil, err
+	}
+	if len(chosen) == 0 {
+		return nil, fmt.Errorf("no VirtualHost chosen for %s", domain)
+	}
+	var indexes []int
+	for _, c := range chosen {
+		indexes = append(indexes, indexOf(best[c]))
+	}
+	return indexes, nil
+}
+
+// isSSL returns whether the VirtualHost serves https, including one added since the config was loaded, which
+// includes the tls options file before it has been written
+func (a *Apache) isSSL(vhost *apache.Node) bool {
+	return vhost.IsSSL() || hasApacheInclude(vhost, filepath.Join(a.ConfigDir, OptionsSSLApacheName))
+}
+
+// addSSLVirtualHost adds an https copy of the VirtualHost, wrapped in an <IfModule mod_ssl.c> section, to the
+// -le-ssl.conf file named after the VirtualHost's file, returning the node for the copy. The file is enabled if it
+// wasn't already loaded.
+func (a *Apache) addSSLVirtualHost(vhost *apache.Node) (*apache.Node, error) {
+	ssl := vhost.Directive.Copy()
+	ssl.Parameters = a.sslAddresses(vhost.Addresses())
+	ssl.Children = withoutHTTPSRedirects(ssl.Children)
+
+	path := sslVirtualHostPath(vhost.File)
+	f, err := a.sslVirtualHostFile(path)
+	if err != nil {
+		return nil, err
+	}
+	section := apache.Directive{
+		Name:       "IfModule",
+		Parameters: []string{"mod_ssl.c"},
+		Children:   []apache.Directive{ssl},
+	}
+	setDirectiveFile([]apache.Directive{section}, f.Path)
+	added := apache.NewRoot(f).AddChild(section)
+	a.changed[f.Path] = true
+	log.WithFields("vhost", vhost.Location(), "path", f.Path).Debug("added https VirtualHost")
+	return added.Children()[0], nil
+}
+
+// sslVirtualHostFile returns the file to add https VirtualHosts to, which may already be loaded through a symlink
+// to it. A file that isn't loaded is read if it exists, so its VirtualHosts are kept, and enabled.
+func (a *Apache) sslVirtualHostFile(path string) (*apache.File, error) {
+	for _, f := range a.config.Files {
+		if f.Path == path || resolveLink(f.Path) == path {
+			return f, nil
+		}
+	}
+	var dirs []apache.Directive
+	if _, err := os.Stat(path); err == nil {
+		if dirs, err = apache.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	a.enableSite(path)
+	return a.config.AddFile(path, dirs), nil
+}
+
+// sslVirtualHostPath returns the path of the file an https copy of a VirtualHost in the file is added to
+// A file enabled with a symlink, such as in sites-enabled, uses the name and directory of the file it links to.
+func sslVirtualHostPath(path string) string {
+	path = resolveLink(path)
+	name := strings.TrimSuffix(filepath.Base(path), ".conf") + sslVHostSuffix
+	return filepath.Join(filepath.Dir(path), name)
+}
+
+// resolveLink returns the path a symlink points to, or the path itself if it isn't a symlink
+func resolveLink(path string) string {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		return path
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return path
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return target
+}
+
+// enableSite makes sure apache loads a new file. A file in sites-available is enabled with a symlink in
+// sites-enabled like a2ensite, otherwise an Include directive for it is added to the main config file, unless an
+// existing Include already matches it.
+func (a *Apache) enableSite(path string) {
+	loaded := path
+	if filepath.Dir(path) == filepath.Join(a.config.Root, "sites-available") {
+		enabledDir := filepath.Join(a.config.Root, "sites-enabled")
+		if fi, err := os.Stat(enabledDir); err == nil && fi.IsDir() {
+			loaded = filepath.Join(enabledDir, filepath.Base(path))
+			if _, err := os.Lstat(loaded); err != nil {
+				a.links[loaded] = filepath.Join("..", "sites-available", filepath.Base(path))
+			}
+		}
+	}
+	if a.config.Included(loaded) {
+		return
+	}
+	log.WithField("path", path).Debug("including new apache config file")
+	root := apache.NewRoot(a.config.Main)
+	root.AddChild(newApacheDirective(a.config.Main.Path, "Include", a.apachePath(path)))
+	a.changed[a.config.Main.Path] = true
+}
+
+// apachePath returns the path apache uses for a file in the server root, when the config was loaded from a copy
+func (a *Apache) apachePath(path string) string {
+	if rel, err := filepath.Rel(a.config.Root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join(a.config.Prefix, rel)
+	}
+	return path
+}
+
+// enableSSL makes sure mod_ssl is loaded and apache listens on the https port
+func (a *Apache) enableSSL() error {
+	if err := a.enableModSSL(); err != nil {
+		return err
+	}
+	return a.addListen()
+}
+
+// enableModSSL makes sure mod_ssl is loaded, enabling it like a2enmod if the config has a mods-enabled directory
+func (a *Apache) enableModSSL() error {
+	for _, m := range a.config.FindAll("LoadModule") {
+		if len(m.Parameters) > 0 && m.Parameters[0] == "ssl_module" {
+			return nil
+		}
+	}
+	if _, ok := a.links[filepath.Join(a.config.Root, "mods-enabled", "ssl.load")]; ok {
+		return nil
+	}
+	_, availErr := os.Stat(filepath.Join(a.config.Root, "mods-available", "ssl.load"))
+	fi, enabledErr := os.Stat(filepath.Join(a.config.Root, "mods-enabled"))
+	if availErr != nil || enabledErr != nil || !fi.IsDir() {
+		return errors.New("mod_ssl is not loaded by the apache config, install and enable it to serve https")
+	}
+	return a.enableModule("ssl")
+}
+
+// enableModule links the module's .load and .conf files from mods-available into mods-enabled, along with the
+// modules listed as its dependencies in a "# Depends:" comment in its .load file
+func (a *Apache) enableModule(name string) error {
+	for _, ext := range []string{".load", ".conf"} {
+		available := filepath.Join(a.config.Root, "mods-available", name+ext)
+		enabled := filepath.Join(a.config.Root, "mods-enabled", name+ext)
+		if _, err := os.Stat(available); err != nil {
+			continue
+		}
+		if _, err := os.Lstat(enabled); err == nil {
+			continue
+		}
+		if _, ok := a.links[enabled]; ok {
+			continue
+		}
+		log.WithField("module", name+ext).Debug("enabling apache module")
+		a.links[enabled] = filepath.Join("..", "mods-available", name+ext)
+		if ext != ".load" {
+			continue
+		}
+		deps, err := moduleDepends(available)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			if err := a.enableModule(dep); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// moduleDepends returns the modules listed in a "# Depends:" comment of a Debian module's .load file
+func moduleDepends(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading apache module %s: %w", path, err)
+	}
+	var deps []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+		if !strings.HasPrefix(line, "Depends:") {
+			continue
+		}
+		deps = append(deps, strings.FieldsFunc(strings.TrimPrefix(line, "Depends:"), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	return deps, nil
+}
+
+// addListen adds a Listen directive for the https port after the last Listen directive, if there isn't one
+func (a *Apache) addListen() error {
+	port := strconv.Itoa(a.HTTPSPort)
+	listens := a.config.FindAll("Listen")
+	for _, l := range listens {
+		if len(l.Parameters) > 0 && apache.ParseAddress(l.Parameters[0]).Port == port {
+			return nil
+		}
+	}
+
+	params := []string{port}
+	if a.HTTPSPort != defaultHTTPSPort {
+		params = append(params, "https")
+	}
+	if len(listens) == 0 {
+		a.changed[a.config.Main.Path] = true
+		apache.NewRoot(a.config.Main).AddChild(newApacheDirective(a.config.Main.Path, "Listen", params...))
+		return nil
+	}
+	last := listens[len(listens)-1]
+	if _, err := last.InsertAfter(newApacheDirective(last.File, "Listen", params...)); err != nil {
+		return last.Errorf("error adding Listen %s: %v", port, err)
+	}
+	a.changed[last.File] = true
+	return nil
+}
+
+// sslAddresses returns the addresses of a VirtualHost with the https port
+func (a *Apache) sslAddresses(addrs []apache.Address) []string {
+	port := strconv.Itoa(a.HTTPSPort)
+	var params []string
+	seen := map[string]bool{}
+	for _, addr := range addrs {
+		addr.Port = port
+		if s := addr.String(); !seen[s] {
+			seen[s] = true
+			params = append(params, s)
+		}
+	}
+	if len(params) == 0 {
+		params = []string{"*:" + port}
+	}
+	return params
+}
+
+// withoutHTTPSRedirects returns the directives without any redirects to https, which would loop when copied into an
+// https VirtualHost, along with the RewriteCond directives for a removed RewriteRule
+func withoutHTTPSRedirects(dirs []apache.Directive) []apache.Directive {
+	if dirs == nil {
+		return nil
+	}
+	kept := []apache.Directive{}
+	for _, d := range dirs {
+		d.Children = withoutHTTPSRedirects(d.Children)
+		if !redirectsToHTTPS(d) {
+			kept = append(kept, d)
+			continue
+		}
+		if strings.EqualFold(d.Name, "RewriteRule") {
+			for len(kept) > 0 && !kept[len(kept)-1].Comment && strings.EqualFold(kept[len(kept)-1].Name, "RewriteCond") {
+				kept = kept[:len(kept)-1]
+			}
+		}
+	}
+	return kept
+}
+
+// redirectsToHTTPS returns whether the directive is a Redirect or RewriteRule to an https url
+func redirectsToHTTPS(d apache.Directive) bool {
+	if d.Comment || d.Blank {
+		return false
+	}
+	switch strings.ToLower(d.Name) {
+	case "redirect", "redirectmatch", "redirectpermanent", "rewriterule":
+	default:
+		return false
+	}
+	for _, p := range d.Parameters {
+		if strings.HasPrefix(strings.ToLower(unquote(p)), "https://") {
+			return true
+		}
+	}
+	return false
+}
+
+// setCert sets the certificate in the VirtualHost, and includes the tls options in a VirtualHost that doesn't
+// already serve https with its own settings. The full chain is used as the certificate, which apache 2.4.8 and
+// later read the chain from, so any SSLCertificateChainFile is removed.
+// Deploying the same certificate again changes nothing, so it can be redeployed after renewing.
+func (a *Apache) setCert(vhost *apache.Node, cert Certificate) error {
+	if err := a.setDirective(vhost, "SSLCertificateFile", cert.FullChainPath); err != nil {
+		return err
+	}
+	if err := a.setDirective(vhost, "SSLCertificateKeyFile", cert.KeyPath); err != nil {
+		return err
+	}
+	for c := vhost.Child("SSLCertificateChainFile"); c != nil; c = vhost.Child("SSLCertificateChainFile") {
+		a.changed[c.File] = true
+		if err := c.Remove(); err != nil {
+			return c.Errorf("error removing SSLCertificateChainFile: %v", err)
+		}
+	}
+
+	optionsPath := filepath.Join(a.ConfigDir, OptionsSSLApacheName)
+	if !vhost.IsSSL() && !hasApacheInclude(vhost, optionsPath) {
+		a.changed[vhost.File] = true
+		vhost.AddChild(newApacheDirective(vhost.File, "Include", optionsPath))
+	}
+	return nil
+}
+
+// setDirective sets a directive with a single value in the VirtualHost, adding it if it isn't already set
+// Any other directives with the same name are removed, which may be in an included file.
+func (a *Apache) setDirective(vhost *apache.Node, name, value string) error {
+	for {
+		var found []*apache.Node
+		for _, c := range vhost.Children() {
+			if strings.EqualFold(c.Name, name) {
+				found = append(found, c)
+			}
+		}
+		if len(found) <= 1 {
+			break
+		}
+		// removing invalidates the other nodes in the same section or file, so find them again after each
+		last := found[len(found)-1]
+		a.changed[last.File] = true
+		if err := last.Remove(); err != nil {
+			return last.Errorf("error removing %s: %v", name, err)
+		}
+	}
+
+	c := vhost.Child(name)
+	if c == nil {
+		a.changed[vhost.File] = true
+		vhost.AddChild(newApacheDirective(vhost.File, name, value))
+		return nil
+	}
+	if len(c.Parameters) == 1 && unquote(c.Parameters[0]) == value {
+		return nil
+	}
+	a.changed[c.File] = true
+	c.Parameters = []string{apacheQuote(value)}
+	return nil
+}
+
+// hasApacheInclude returns whether the VirtualHost includes the file
+func hasApacheInclude(vhost *apache.Node, path string) bool {
+	for _, c := range vhost.Children() {
+		if (strings.EqualFold(c.Name, "Include") || strings.EqualFold(c.Name, "IncludeOptional")) && len(c.Parameters) == 1 &&
+			filepath.Clean(unquote(c.Parameters[0])) == filepath.Clean(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// newApacheDirective returns a directive to add to the file, quoting any parameters that need it
+func newApacheDirective(file, name string, params ...string) apache.Directive {
+	d := apache.Directive{Name: name, File: file}
+	for _, p := range params {
+		d.Parameters = append(d.Parameters, apacheQuote(p))
+	}
+	return d
+}
+
+// setDirectiveFile sets the file of the directives and their children
+func setDirectiveFile(dirs []apache.Directive, path string) {
+	for i := range dirs {
+		dirs[i].File = path
+		setDirectiveFile(dirs[i].Children, path)
+	}
+}
+
+func apacheQuote(param string) string {
+	if param != "" && !strings.ContainsAny(param, " \t\r\n\"'\\<>#") {
+		return param
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(param) + `"`
+}
+
+// SupportedEnhancements returns no enhancements, as the apache installer only deploys certificates
+func (a *Apache) SupportedEnhancements() []string {
+	return nil
+}
+
+func (a *Apache) Enhance(domain, enhancement string, cert Certificate) error {
+	return fmt.Errorf("the %s installer does not support the %s enhancement", ApacheName, enhancement)
+}
+
+// Save writes the modified apache config files, along with the tls options file if it doesn't already exist, then
+// makes the symlinks enabling sites and modules. Every file and link is backed up into the reverter's temporary
+// checkpoint first.
+func (a *Apache) Save() error {
+	if len(a.changed) == 0 && len(a.links) == 0 {
+		return nil
+	}
+	if err := a.writeSSLFiles(); err != nil {
+		return err
+	}
+
+	var paths []string
+	for path := range a.changed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		f := a.config.File(path)
+		if f == nil {
+			return fmt.Errorf("modified apache config file %s was not loaded", path)
+		}
+		log.WithField("path", path).Debug("writing apache config file")
+		if err := a.writeFile(path, apache.Format(f.Directives)); err != nil {
+			return fmt.Errorf("error writing apache config file %s: %w", path, err)
+		}
+		delete(a.changed, path)
+	}
+
+	var links []string
+	for link := range a.links {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	for _, link := range links {
+		log.WithFields("link", link, "target", a.links[link]).Debug("enabling apache config file")
+		if err := a.Reverter.AddToTempCheckpoint(link); err != nil {
+			return err
+		}
+		if err := os.Symlink(a.links[link], link); err != nil {
+			return fmt.Errorf("error enabling apache config file: %w", err)
+		}
+		delete(a.links, link)
+	}
+	return nil
+}
+
+// writeSSLFiles writes the tls options file to the config dir, leaving any existing file as it may have been
+// changed by the user
+func (a *Apache) writeSSLFiles() error {
+	if err := os.MkdirAll(a.ConfigDir, 0755); err != nil {
+		return fmt.Errorf("error making directory %s: %w", a.ConfigDir, err)
+	}
+	path := filepath.Join(a.ConfigDir, OptionsSSLApacheName)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := a.writeFile(path, []byte(optionsSSLApache)); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeFile backs up the file to the temporary checkpoint, then writes it, keeping its mode if it exists
+func (a *Apache) writeFile(path string, data []byte) error {
+	if err := a.Reverter.AddToTempCheckpoint(path); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	return ioutil.WriteFile(path, data, mode)
+}
+
+// Rollback reverts the reverter's temporary checkpoint, restoring the files written and removing the links made
+// since it was last finalized, and reloads the config
+func (a *Apache) Rollback() error {
+	if err := a.Reverter.RevertTempCheckpoint(); err != nil {
+		return err
+	}
+
+	cfg, err := apache.Load(a.ServerRoot)
+	if err != nil {
+		return fmt.Errorf("error loading apache config: %w", err)
+	}
+	a.config = cfg
+	a.changed = map[string]bool{}
+	a.links = map[string]string{}
+	return nil
+}
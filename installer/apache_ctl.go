@@ -0,0 +1,93 @@
+package installer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+)
+
+// apacheErrorRegexp matches the line apache logs before a config error, eg
+// AH00526: Syntax error on line 12 of /etc/apache2/sites-enabled/000-default.conf:
+var apacheErrorRegexp = regexp.MustCompile(`Syntax error on line (\d+) of (.+):$`)
+
+// ApacheError is an error from running apachectl, with the location in the config it refers to if there is one
+type ApacheError struct {
+	// Message is the error logged by apache, or how running apachectl failed
+	Message string
+	// File and Line are the location of the error in the config, if apache reported one
+	File string
+	Line int
+	// Text is the config line at the location
+	Text string
+	// Output is everything apachectl printed
+	Output string
+}
+
+func (e *ApacheError) Error() string {
+	if e.File == "" {
+		return e.Message
+	}
+	s := fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	if e.Text != "" {
+		s += "\n\t" + e.Text
+	}
+	return s
+}
+
+// ConfigTest runs apachectl configtest to check the saved config
+func (a *Apache) ConfigTest() error {
+	if err := a.run("configtest"); err != nil {
+		return fmt.Errorf("apache config test failed: %w", err)
+	}
+	return nil
+}
+
+// Restart gracefully restarts apache so it uses the saved config, without dropping open connections
+func (a *Apache) Restart() error {
+	if err := a.run("graceful"); err != nil {
+		return fmt.Errorf("error restarting apache: %w", err)
+	}
+	return nil
+}
+
+// run runs apachectl, which finds the main config file itself
+func (a *Apache) run(args ...string) error {
+	ll := log.WithFields("ctl", a.Ctl, "args", args)
+	ll.Debug("running apachectl")
+	out, err := exec.Command(a.Ctl, args...).CombinedOutput()
+	if err == nil {
+		ll.WithField("output", string(out)).Trace("ran apachectl")
+		return nil
+	}
+	ll.WithError(err).WithField("output", string(out)).Debug("running apachectl failed")
+	return newApacheError(out, err)
+}
+
+// newApacheError returns an error with the syntax error apache logged, which is on the line after the location
+func newApacheError(out []byte, runErr error) *ApacheError {
+	e := &ApacheError{
+		Message: runErr.Error(),
+		Output:  string(out),
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := apacheErrorRegexp.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		e.File = m[2]
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Text = configLine(e.File, e.Line)
+		if scanner.Scan() && strings.TrimSpace(scanner.Text()) != "" {
+			e.Message = strings.TrimSpace(scanner.Text())
+		}
+		break
+	}
+	return e
+}
@@ -0,0 +1,121 @@
+package installer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeApacheCtl logs its arguments next to itself, and fails printing the contents of a .fail file next to itself if
+// there is one, as apachectl finds the config itself
+const fakeApacheCtl = `#!/bin/sh
+echo "$@" >> "$0.log"
+if [ -f "$0.fail" ]; then
+	cat "$0.fail" >&2
+	exit 1
+fi
+echo "Syntax OK" >&2
+`
+
+func setupFakeApache(t *testing.T, files, links map[string]string) (*Apache, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake apachectl uses a posix shell")
+	}
+	root := t.TempDir()
+	writeApacheRoot(t, root, files, links)
+	ctl := filepath.Join(t.TempDir(), "apachectl")
+	if err := ioutil.WriteFile(ctl, []byte(fakeApacheCtl), 0755); err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewApache(root, ctl, t.TempDir(), NewReverter(t.TempDir()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, ctl
+}
+
+func TestApache_ConfigTest(t *testing.T) {
+	a, ctl := setupFakeApache(t, map[string]string{
+		"httpd.conf": "Include site.conf\n",
+		"site.conf":  "<VirtualHost *:80>\n    ServerName example.com\n    bad_directive on\n</VirtualHost>\n",
+	}, nil)
+	if err := a.ConfigTest(); err != nil {
+		t.Fatalf("ConfigTest() error = %v", err)
+	}
+	if err := a.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	args, err := ioutil.ReadFile(ctl + ".log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "configtest\ngraceful\n"; string(args) != want {
+		t.Errorf("apachectl run with %q, want %q", args, want)
+	}
+
+	site := filepath.Join(a.ServerRoot, "site.conf")
+	output := "AH00526: Syntax error on line 3 of " + site + ":\n" +
+		"Invalid command 'bad_directive', perhaps misspelled or defined by a module not included in the server configuration\n" +
+		"Action 'configtest' failed.\n"
+	if err := ioutil.WriteFile(ctl+".fail", []byte(output), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = a.ConfigTest()
+	var aerr *ApacheError
+	if !errors.As(err, &aerr) {
+		t.Fatalf("ConfigTest() error = %v, want an ApacheError", err)
+	}
+	if aerr.File != site || aerr.Line != 3 || aerr.Text != "bad_directive on" || !strings.HasPrefix(aerr.Message, "Invalid command 'bad_directive'") {
+		t.Errorf("ConfigTest() error = %+v", aerr)
+	}
+	if want := site + ":3: Invalid command"; !strings.Contains(err.Error(), want) {
+		t.Errorf("ConfigTest() error = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestApache_Rollback(t *testing.T) {
+	a, _ := setupFakeApache(t, debianApache, debianApacheLinks)
+	if err := a.DeployCert("example.com", testCert); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	created := []string{
+		filepath.Join(a.ServerRoot, "sites-available", "example.com-le-ssl.conf"),
+		filepath.Join(a.ServerRoot, "sites-enabled", "example.com-le-ssl.conf"),
+		filepath.Join(a.ServerRoot, "mods-enabled", "ssl.load"),
+		filepath.Join(a.ConfigDir, OptionsSSLApacheName),
+	}
+	for _, path := range created {
+		if _, err := os.Lstat(path); err != nil {
+			t.Fatalf("Save() did not create %s: %v", path, err)
+		}
+	}
+
+	if err := a.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	for _, path := range created {
+		if _, err := os.Lstat(path); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Rollback() did not remove %s", path)
+		}
+	}
+	if vhosts := a.config.VirtualHosts(); len(vhosts) != 1 || vhosts[0].IsSSL() {
+		t.Errorf("Rollback() did not reload the config")
+	}
+}
+
+func TestApache_Run_Missing(t *testing.T) {
+	a := &Apache{ServerRoot: t.TempDir(), Ctl: filepath.Join(t.TempDir(), "missing")}
+	err := a.ConfigTest()
+	var aerr *ApacheError
+	if !errors.As(err, &aerr) || aerr.File != "" {
+		t.Errorf("ConfigTest() error = %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package installer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeApacheRoot writes the files, keyed by slash separated paths relative to the root, and makes the symlinks
+func writeApacheRoot(t *testing.T, root string, files, links map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range links {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(filepath.FromSlash(target), path); err != nil {
+			t.Skipf("unable to make symlinks: %v", err)
+		}
+	}
+}
+
+func TestApache_DeployCert(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		domain  string
+		choose  func(domain string, vhosts []string) ([]int, error)
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name: "http only",
+			files: map[string]string{
+				"httpd.conf": "LoadModule ssl_module modules/mod_ssl.so\nListen 80\nInclude conf.d/*.conf\n",
+				"conf.d/example.com.conf": `<VirtualHost *:80 [::]:80>
+    ServerName example.com
+    DocumentRoot /var/www
+    RewriteEngine on
+    RewriteCond %{SERVER_NAME} =example.com
+    RewriteRule ^ https://%{SERVER_NAME}%{REQUEST_URI} [END,NE,R=permanent]
+    Redirect /old "https://example.com/new"
+</VirtualHost>
+`,
+			},
+			domain: "example.com",
+			want: map[string]string{
+				"httpd.conf": "LoadModule ssl_module modules/mod_ssl.so\nListen 80\nListen 443\nInclude conf.d/*.conf\n",
+				"conf.d/example.com-le-ssl.conf": `<IfModule mod_ssl.c>
+<VirtualHost *:443 [::]:443>
+    ServerName example.com
+    DocumentRoot /var/www
+    RewriteEngine on
+    SSLCertificateFile /etc/letsencrypt/live/example.com/fullchain.pem
+    SSLCertificateKeyFile /etc/letsencrypt/live/example.com/privkey.pem
+    Include CONFIG_DIR/options-ssl-apache.conf
+</VirtualHost>
+</IfModule>
+`,
+			},
+		},
+		{
+			name: "not included",
+			files: map[string]string{
+				"httpd.conf": `LoadModule ssl_module modules/mod_ssl.so
+<IfModule mod_ssl.c>
+    Listen 443 https
+</IfModule>
+<VirtualHost 192.0.2.1>
+    ServerAlias www.example.com
+</VirtualHost>
+`,
+			},
+			domain: "www.example.com",
+			want: map[string]string{
+				"httpd.conf": `LoadModule ssl_module modules/mod_ssl.so
+<IfModule mod_ssl.c>
+    Listen 443 https
+</IfModule>
+<VirtualHost 192.0.2.1>
+    ServerAlias www.example.com
+</VirtualHost>
+Include ROOT/httpd-le-ssl.conf
+`,
+				"httpd-le-ssl.conf": `<IfModule mod_ssl.c>
+<VirtualHost 192.0.2.1:443>
+    ServerAlias www.example.com
+    SSLCertificateFile /etc/letsencrypt/live/example.com/fullchain.pem
+    SSLCertificateKeyFile /etc/letsencrypt/live/example.com/privkey.pem
+    Include CONFIG_DIR/options-ssl-apache.conf
+</VirtualHost>
+</IfModule>
+`,
+			},
+		},
+		{
+			name: "existing https",
+			files: map[string]string{
+				"httpd.conf": `LoadModule ssl_module modules/mod_ssl.so
+<VirtualHost *:80>
+    ServerName example.com
+</VirtualHost>
+<VirtualHost *:443>
+    ServerName example.com
+    SSLEngine on
+    SSLCertificateFile old/cert.pem
+    SSLCertificateChainFile old/chain.pem
+    SSLCertificateKeyFile old/privkey.pem
+</VirtualHost>
+`,
+			},
+			domain: "example.com",
+			want: map[string]string{
+				"httpd.conf": `LoadModule ssl_module modules/mod_ssl.so
+<VirtualHost *:80>
+    ServerName example.com
+</VirtualHost>
+<VirtualHost *:443>
+    ServerName example.com
+    SSLEngine on
+    SSLCertificateFile /etc/letsencrypt/live/example.com/fullchain.pem
+    SSLCertificateKeyFile /etc/letsencrypt/live/example.com/privkey.pem
+</VirtualHost>
+`,
+			},
+		},
+		{
+			name: "chosen",
+			files: map[string]string{
+				"httpd.conf": `LoadModule ssl_module modules/mod_ssl.so
+Listen 443
+<VirtualHost *:443>
+    ServerName example.net
+    ServerAlias example.com
+    SSLEngine on
+</VirtualHost>
+<VirtualHost *:443>
+    ServerAlias example.com
+    SSLEngine on
+</VirtualHost>
+`,
+			},
+			domain: "example.com",
+			choose: func(domain string, vhosts []string) ([]int, error) {
+				if len(vhosts) != 2 || !strings.HasSuffix(vhosts[1], "httpd.conf:8 (ServerAlias example.com)") {
+					return nil, os.ErrInvalid
+				}
+				return []int{1}, nil
+			},
+			want: map[string]string{
+				"httpd.conf": `LoadModule ssl_module modules/mod_ssl.so
+Listen 443
+<VirtualHost *:443>
+    ServerName example.net
+    ServerAlias example.com
+    SSLEngine on
+</VirtualHost>
+<VirtualHost *:443>
+    ServerAlias example.com
+    SSLEngine on
+    SSLCertificateFile /etc/letsencrypt/live/example.com/fullchain.pem
+    SSLCertificateKeyFile /etc/letsencrypt/live/example.com/privkey.pem
+</VirtualHost>
+`,
+			},
+		},
+		{
+			name: "ambiguous",
+			files: map[string]string{
+				"httpd.conf": "<VirtualHost *:80>\n    ServerName example.com\n</VirtualHost>\n<VirtualHost *:8080>\n    ServerName example.com\n</VirtualHost>\n",
+			},
+			domain:  "example.com",
+			wantErr: "unable to choose a VirtualHost for example.com",
+		},
+		{
+			name: "no match",
+			files: map[string]string{
+				"httpd.conf": "<VirtualHost *:80>\n    ServerName example.net\n</VirtualHost>\n",
+			},
+			domain:  "example.com",
+			wantErr: "could not find a VirtualHost for example.com",
+		},
+		{
+			name: "no mod_ssl",
+			files: map[string]string{
+				"httpd.conf": "<VirtualHost *:80>\n    ServerName example.com\n</VirtualHost>\n",
+			},
+			domain:  "example.com",
+			wantErr: "mod_ssl is not loaded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			configDir := t.TempDir()
+			writeApacheRoot(t, root, tt.files, nil)
+			a, err := NewApache(root, DefaultApacheCtl, configDir, NewReverter(t.TempDir()), tt.choose)
+			if err != nil {
+				t.Fatalf("NewApache() error = %v", err)
+			}
+			err = a.DeployCert(tt.domain, testCert)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DeployCert() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeployCert() error = %v", err)
+			}
+			if err := a.Save(); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			r := strings.NewReplacer("CONFIG_DIR", configDir, "ROOT", root)
+			for name, want := range tt.want {
+				got, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want = r.Replace(want); string(got) != want {
+					t.Errorf("DeployCert() %s got:\n%s\nwant:\n%s", name, got, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(configDir, OptionsSSLApacheName)); err != nil {
+				t.Errorf("Save() did not write %s: %v", OptionsSSLApacheName, err)
+			}
+		})
+	}
+}
+
+// debianApache is a cut down Debian apache config, with example.com enabled in sites-enabled and mod_ssl available
+// but not enabled
+var debianApache = map[string]string{
+	"apache2.conf": `IncludeOptional mods-enabled/*.load
+IncludeOptional mods-enabled/*.conf
+Include ports.conf
+IncludeOptional sites-enabled/*.conf
+`,
+	"ports.conf":                        "Listen 80\n\n<IfModule ssl_module>\n\tListen 443\n</IfModule>\n",
+	"mods-available/ssl.load":           "# Depends: setenvif mime socache_shmcb\nLoadModule ssl_module /usr/lib/apache2/modules/mod_ssl.so\n",
+	"mods-available/ssl.conf":           "<IfModule mod_ssl.c>\n\tSSLRandomSeed startup builtin\n</IfModule>\n",
+	"mods-available/socache_shmcb.load": "LoadModule socache_shmcb_module /usr/lib/apache2/modules/mod_socache_shmcb.so\n",
+	"mods-available/alias.load":         "LoadModule alias_module /usr/lib/apache2/modules/mod_alias.so\n",
+	"sites-available/example.com.conf":  "<VirtualHost *:80>\n    ServerName example.com\n    ServerAlias www.example.com\n</VirtualHost>\n",
+}
+
+var debianApacheLinks = map[string]string{
+	"mods-enabled/alias.load":        "../mods-available/alias.load",
+	"sites-enabled/example.com.conf": "../sites-available/example.com.conf",
+}
+
+func TestApache_DeployCert_Debian(t *testing.T) {
+	root := t.TempDir()
+	writeApacheRoot(t, root, debianApache, debianApacheLinks)
+	a, err := NewApache(root, DefaultApacheCtl, t.TempDir(), NewReverter(t.TempDir()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second domain uses the https VirtualHost added for the first
+	for _, domain := range []string{"example.com", "www.example.com"} {
+		if err := a.DeployCert(domain, testCert); err != nil {
+			t.Fatalf("DeployCert(%s) error = %v", domain, err)
+		}
+	}
+	if err := a.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	ssl, err := ioutil.ReadFile(filepath.Join(root, "sites-available", "example.com-le-ssl.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(ssl), "<VirtualHost *:443>"); got != 1 {
+		t.Errorf("DeployCert() added %d https VirtualHosts, want 1:\n%s", got, ssl)
+	}
+	wantLinks := map[string]string{
+		"sites-enabled/example.com-le-ssl.conf": "../sites-available/example.com-le-ssl.conf",
+		"mods-enabled/ssl.load":                 "../mods-available/ssl.load",
+		"mods-enabled/ssl.conf":                 "../mods-available/ssl.conf",
+		"mods-enabled/socache_shmcb.load":       "../mods-available/socache_shmcb.load",
+	}
+	for name, want := range wantLinks {
+		got, err := os.Readlink(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil || got != filepath.FromSlash(want) {
+			t.Errorf("Save() link %s got %q, %v, want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"apache2.conf", "ports.conf"} {
+		if got, _ := ioutil.ReadFile(filepath.Join(root, name)); string(got) != debianApache[name] {
+			t.Errorf("Save() changed %s:\n%s", name, got)
+		}
+	}
+
+	// redeploying after the config is reloaded uses the enabled https VirtualHost and changes nothing
+	a, err = NewApache(root, DefaultApacheCtl, a.ConfigDir, a.Reverter, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeployCert("example.com", testCert); err != nil {
+		t.Fatalf("DeployCert() redeploy error = %v", err)
+	}
+	if len(a.changed) != 0 || len(a.links) != 0 {
+		t.Errorf("DeployCert() redeploy changed %v and linked %v", a.changed, a.links)
+	}
+}
+
+func TestModuleDepends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ssl.load")
+	if err := ioutil.WriteFile(path, []byte("# Depends: setenvif, mime socache_shmcb\nLoadModule ssl_module mod_ssl.so\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deps, err := moduleDepends(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(deps, " "); got != "setenvif mime socache_shmcb" {
+		t.Errorf("moduleDepends() got %q", got)
+	}
+}
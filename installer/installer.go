@@ -0,0 +1,49 @@
+package installer
+
+// Certificate is the files of a certificate lineage, as referenced from webserver configs
+type Certificate struct {
+	CertPath      string
+	ChainPath     string
+	FullChainPath string
+	KeyPath       string
+}
+
+const (
+	// EnhanceRedirect redirects http requests to https
+	EnhanceRedirect = "redirect"
+	// EnhanceHSTS adds the Strict-Transport-Security header to https responses
+	EnhanceHSTS = "hsts"
+	// EnhanceUIR adds the Content-Security-Policy upgrade-insecure-requests header to https responses
+	EnhanceUIR = "uir"
+	// EnhanceStapleOCSP enables OCSP stapling
+	EnhanceStapleOCSP = "staple-ocsp"
+)
+
+// Installer configures a webserver to serve certificates
+type Installer interface {
+	// Name is the name of the installer, as used in the --installer flag
+	Name() string
+
+	// DeployCert configures the webserver to serve the certificate for the domain
+	// Changes are only made in memory until Save is called.
+	DeployCert(domain string, cert Certificate) error
+
+	// SupportedEnhancements returns the enhancements the installer can make, as named by the Enhance constants
+	SupportedEnhancements() []string
+
+	// Enhance makes the enhancement to the webserver config serving the certificate for the domain, doing nothing
+	// if it has already been made. Changes are only made in memory until Save is called.
+	Enhance(domain, enhancement string, cert Certificate) error
+
+	// Save writes any changes made to the webserver config, backing up the files into a temporary checkpoint
+	Save() error
+
+	// Rollback restores the webserver config files saved since the last finalized checkpoint
+	Rollback() error
+
+	// ConfigTest checks that the saved webserver config is valid
+	ConfigTest() error
+
+	// Restart reloads the webserver so it uses the saved config
+	Restart() error
+}
@@ -0,0 +1,435 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/parser/nginx"
+)
+
+const (
+	NginxName = "nginx"
+
+	// OptionsSSLNginxName and SSLDHParamsName are the files the nginx installer writes to the config dir and uses
+	// in every https server block, named the same as certbot's so configs it has installed keep working
+	OptionsSSLNginxName = "options-ssl-nginx.conf"
+	SSLDHParamsName     = "ssl-dhparams.pem"
+
+	// DefaultNginxCtl is the nginx binary used to test the config and reload nginx
+	DefaultNginxCtl = "nginx"
+
+	defaultHTTPSPort = 443
+)
+
+// optionsSSLNginx is the recommended tls settings, the same as certbot's which are based on
+// https://ssl-config.mozilla.org
+const optionsSSLNginx = `# This file contains important security parameters, and is included in every server block
+# certgot has installed a certificate in.
+
+ssl_session_cache shared:le_nginx_SSL:10m;
+ssl_session_timeout 1440m;
+ssl_session_tickets off;
+
+ssl_protocols TLSv1.2 TLSv1.3;
+ssl_prefer_server_ciphers off;
+
+ssl_ciphers "ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384:ECDHE-ECDSA-CHACHA20-POLY1305:ECDHE-RSA-CHACHA20-POLY1305:DHE-RSA-AES128-GCM-SHA256:DHE-RSA-AES256-GCM-SHA384";
+`
+
+// sslDHParams is the ffdhe2048 group from https://tools.ietf.org/html/rfc7919
+const sslDHParams = `-----BEGIN DH PARAMETERS-----
+MIIBCAKCAQEA//////////+t+FRYortKmq/cViAnPTzx2LnFg84tNpWp4TZBFGQz
++8yTnc4kmz75fS/jY2MMddj2gbICrsRhetPfHtXV/WVhJDP1H18GbtCFY2VVPe0a
+87VXE15/V8k1mE8McODmi3fipona8+/och3xWKE2rec1MKzKT0g6eXq8CrGCsyT7
+YdEIqUuyyOP7uWrat2DX9GgdT0Kj3jlN9K5W7edjcrsZCwenyO4KbXCeAvzhzffi
+7MA0BM0oNC9hkXL+nOmFg/+OTxIy7vKBg8P+OxtMb61zO7X8vC7CIAXFjvGDfRaD
+ssbzSibBsu/6iGtCOGEoXJf//////////wIBAg==
+-----END DH PARAMETERS-----
+`
+
+// Nginx is an installer which adds certificates to the matching server blocks in an nginx config
+type Nginx struct {
+	// ServerRoot is the directory containing the main nginx config file, eg /etc/nginx
+	ServerRoot string
+
+	// Ctl is the path of the nginx binary
+	Ctl string
+
+	// ConfigDir is the directory the tls options and dh params files are written to
+	ConfigDir string
+
+	// HTTPSPort is the port used when adding https to a server block
+	HTTPSPort int
+
+	// Choose asks the user which server blocks to install the certificate for a domain in, when it's ambiguous,
+	// returning the indexes of the chosen servers. If nil, an ambiguous match is an error.
+	Choose func(domain string, servers []string) ([]int, error)
+
+	// Reverter backs up every file before it's written, so the changes can be rolled back
+	Reverter *Reverter
+
+	config *nginx.Config
+	// changed holds the paths of config files that have been modified since they were last saved
+	changed map[string]bool
+}
+
+// NewNginx loads the nginx config in the server root, returning an installer using it
+func NewNginx(serverRoot, ctl, configDir string, reverter *Reverter, choose func(domain string, servers []string) ([]int, error)) (*Nginx, error) {
+	cfg, err := nginx.Load(serverRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error loading nginx config: %w", err)
+	}
+	return &Nginx{
+		ServerRoot: serverRoot,
+		Ctl:        ctl,
+		ConfigDir:  configDir,
+		HTTPSPort:  defaultHTTPSPort,
+		Choose:     choose,
+		Reverter:   reverter,
+		config:     cfg,
+		changed:    map[string]bool{},
+	}, nil
+}
+
+func (n *Nginx) Name() string {
+	return NginxName
+}
+
+// DeployCert sets the certificate in the server blocks matching the domain, see chooseServers.
+// A server block that doesn't serve https is duplicated into an https server block if it only listens on port 80,
+// otherwise https is added to it.
+func (n *Nginx) DeployCert(domain string, cert Certificate) error {
+	indexes, err := n.chooseServers(domain, nil)
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return fmt.Errorf("could not find a server block for %s, add it to a server_name directive to install the certificate", domain)
+	}
+	// server blocks may be added after each server, so go backwards to keep the indexes of the rest valid
+	sort.Sort(sort.Reverse(sort.IntSlice(indexes)))
+	for _, idx := range indexes {
+		server := n.config.ServerBlocks()[idx]
+		log.WithFields("domain", domain, "server", server.Location()).Debug("deploying certificate")
+		if err := n.deploy(server, cert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chooseServers returns the indexes of the server blocks to change for the domain, or none if no server block
+// matches. These are the best matches for the domain that are kept by the filter, if not nil, preferring those that
+// already serve https. If there is more than one, or only a default server matches, the user is asked to choose.
+func (n *Nginx) chooseServers(domain string, keep func(m nginx.ServerMatch) bool) ([]int, error) {
+	var matches []nginx.ServerMatch
+	for _, m := range n.config.MatchServers(domain) {
+		if keep == nil || keep(m) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	var best, ssl []nginx.ServerMatch
+	for _, m := range matches {
+		if m.Rank != matches[0].Rank {
+			break
+		}
+		best = append(best, m)
+		if m.Server.IsSSL() {
+			ssl = append(ssl, m)
+		}
+	}
+	if len(ssl) > 0 {
+		best = ssl
+	}
+
+	servers := n.config.ServerBlocks()
+	indexOf := func(m nginx.ServerMatch) int {
+		for i, s := range servers {
+			if s.Directive == m.Server.Directive {
+				return i
+			}
+		}
+		return -1
+	}
+	if len(best) == 1 && best[0].Type != nginx.MatchDefault {
+		return []int{indexOf(best[0])}, nil
+	}
+
+	var descs []string
+	for _, m := range best {
+		desc := fmt.Sprintf("%s (%s)", m.Server.Location(), m.Type)
+		if m.Name != "" {
+			desc = fmt.Sprintf("%s (%s %s)", m.Server.Location(), m.Type, m.Name)
+		}
+		descs = append(descs, desc)
+	}
+	if n.Choose == nil {
+		return nil, fmt.Errorf("unable to choose a server block for %s from: %s", domain, strings.Join(descs, ", "))
+	}
+	chosen, err := n.Choose(domain, descs)
+	if err != nil {
+		return nil, err
+	}
+	if len(chosen) == 0 {
+		return nil, fmt.Errorf("no server block chosen for %s", domain)
+	}
+	var indexes []int
+	for _, c := range chosen {
+		indexes = append(indexes, indexOf(best[c]))
+	}
+	return indexes, nil
+}
+
+// deploy enables https in the server block if needed, and sets the certificate
+// Deploying the same certificate again changes nothing, so it can be redeployed after renewing.
+func (n *Nginx) deploy(server *nginx.Node, cert Certificate) error {
+	listens, err := server.ListenAddresses()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case server.IsSSL():
+	case httpOnly(listens):
+		ssl := server.Directive.Copy()
+		ssl.Children = n.sslListens(ssl, listens)
+		inserted, err := server.InsertAfter(ssl)
+		if err != nil {
+			return server.Errorf("error adding https server block: %v", err)
+		}
+		n.changed[server.File] = true
+		server = inserted
+	default:
+		n.changed[server.File] = true
+		server.AddChild(newDirective(server, "listen", strconv.Itoa(n.HTTPSPort), "ssl"))
+	}
+
+	if err := n.setDirective(server, "ssl_certificate", cert.FullChainPath); err != nil {
+		return err
+	}
+	if err := n.setDirective(server, "ssl_certificate_key", cert.KeyPath); err != nil {
+		return err
+	}
+
+	optionsPath := filepath.Join(n.ConfigDir, OptionsSSLNginxName)
+	if !hasInclude(server, optionsPath) {
+		n.changed[server.File] = true
+		server.AddChild(newDirective(server, "include", optionsPath))
+	}
+	if server.Child("ssl_dhparam") == nil {
+		n.changed[server.File] = true
+		server.AddChild(newDirective(server, "ssl_dhparam", filepath.Join(n.ConfigDir, SSLDHParamsName)))
+	}
+	return nil
+}
+
+// setDirective sets a directive with a single value in the server block, adding it if it isn't already set
+// Any other directives with the same name are removed, which may be in an included file.
+func (n *Nginx) setDirective(server *nginx.Node, name, value string) error {
+	for {
+		var found []*nginx.Node
+		for _, c := range server.Children() {
+			if c.Name == name {
+				found = append(found, c)
+			}
+		}
+		if len(found) <= 1 {
+			break
+		}
+		// removing invalidates the other nodes in the same block or file, so find them again after each
+		last := found[len(found)-1]
+		n.changed[last.File] = true
+		if err := last.Remove(); err != nil {
+			return last.Errorf("error removing %s: %v", name, err)
+		}
+	}
+
+	c := server.Child(name)
+	if c == nil {
+		n.changed[server.File] = true
+		server.AddChild(newDirective(server, name, value))
+		return nil
+	}
+	if len(c.Parameters) == 1 && unquote(c.Parameters[0]) == value {
+		return nil
+	}
+	n.changed[c.File] = true
+	c.Parameters = []string{quote(value)}
+	return nil
+}
+
+// sslListens returns the children of the server block with its listen directives replaced by https ones on the
+// same addresses
+func (n *Nginx) sslListens(server nginx.Directive, listens []nginx.Listen) []nginx.Directive {
+	var ssl []nginx.Directive
+	seen := map[string]bool{}
+	for _, l := range listens {
+		addr := strconv.Itoa(n.HTTPSPort)
+		switch {
+		case l.IPv6:
+			addr = "[" + l.Address + "]:" + addr
+		case l.Address != "":
+			addr = l.Address + ":" + addr
+		}
+		params := append([]string{addr, "ssl"}, l.Options...)
+		if l.HTTP2 {
+			params = append(params, "http2")
+		}
+		if key := strings.Join(params, " "); !seen[key] {
+			seen[key] = true
+			ssl = append(ssl, nginx.Directive{Name: "listen", Parameters: params, File: server.File})
+		}
+	}
+
+	var children []nginx.Directive
+	added := false
+	for _, c := range server.Children {
+		if c.Name == "listen" && !c.Comment {
+			if !added {
+				children = append(children, ssl...)
+				added = true
+			}
+			continue
+		}
+		children = append(children, c)
+	}
+	if !added {
+		children = append(ssl, children...)
+	}
+	return children
+}
+
+// httpOnly returns whether every listen address is plain http on port 80
+func httpOnly(listens []nginx.Listen) bool {
+	for _, l := range listens {
+		if l.SSL || l.Port != 80 {
+			return false
+		}
+	}
+	return true
+}
+
+// hasInclude returns whether the server block includes the file
+func hasInclude(server *nginx.Node, path string) bool {
+	for _, c := range server.Children() {
+		if c.Name == "include" && len(c.Parameters) == 1 && filepath.Clean(unquote(c.Parameters[0])) == filepath.Clean(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// newDirective returns a directive to add to the block, quoting any parameters that need it
+func newDirective(block *nginx.Node, name string, params ...string) nginx.Directive {
+	d := nginx.Directive{Name: name, File: block.File}
+	for _, p := range params {
+		d.Parameters = append(d.Parameters, quote(p))
+	}
+	return d
+}
+
+func quote(param string) string {
+	if param != "" && !strings.ContainsAny(param, " \t\r\n;{}#'\"\\$") {
+		return param
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(param) + `"`
+}
+
+func unquote(param string) string {
+	if len(param) >= 2 && (param[0] == '"' || param[0] == '\'') && param[len(param)-1] == param[0] {
+		return param[1 : len(param)-1]
+	}
+	return param
+}
+
+// Save writes the modified nginx config files, along with the tls options and dh params files if they don't
+// already exist. Every file is backed up into the reverter's temporary checkpoint first.
+func (n *Nginx) Save() error {
+	if len(n.changed) == 0 {
+		return nil
+	}
+	if err := n.writeSSLFiles(); err != nil {
+		return err
+	}
+
+	var paths []string
+	for path := range n.changed {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		f := n.config.File(path)
+		if f == nil {
+			return fmt.Errorf("modified nginx config file %s was not loaded", path)
+		}
+		log.WithField("path", path).Debug("writing nginx config file")
+		if err := n.writeFile(path, nginx.Format(f.Directives)); err != nil {
+			return fmt.Errorf("error writing nginx config file %s: %w", path, err)
+		}
+		delete(n.changed, path)
+	}
+	return nil
+}
+
+// writeSSLFiles writes the tls options and dh params files to the config dir, leaving any existing files as they
+// may have been changed by the user
+func (n *Nginx) writeSSLFiles() error {
+	if err := os.MkdirAll(n.ConfigDir, 0755); err != nil {
+		return fmt.Errorf("error making directory %s: %w", n.ConfigDir, err)
+	}
+	files := map[string]string{
+		OptionsSSLNginxName: optionsSSLNginx,
+		SSLDHParamsName:     sslDHParams,
+	}
+	for name, contents := range files {
+		path := filepath.Join(n.ConfigDir, name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := n.writeFile(path, []byte(contents)); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// writeFile backs up the file to the temporary checkpoint, then writes it, keeping its mode if it exists
+func (n *Nginx) writeFile(path string, data []byte) error {
+	if err := n.Reverter.AddToTempCheckpoint(path); err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	return ioutil.WriteFile(path, data, mode)
+}
+
+// Rollback reverts the reverter's temporary checkpoint, restoring the files written since it was last finalized,
+// and reloads the config
+func (n *Nginx) Rollback() error {
+	if err := n.Reverter.RevertTempCheckpoint(); err != nil {
+		return err
+	}
+
+	cfg, err := nginx.Load(n.ServerRoot)
+	if err != nil {
+		return fmt.Errorf("error loading nginx config: %w", err)
+	}
+	n.config = cfg
+	n.changed = map[string]bool{}
+	return nil
+}
@@ -0,0 +1,110 @@
+package installer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/parser/nginx"
+)
+
+// nginxErrorRegexp matches an error logged by nginx about the config, eg
+// nginx: [emerg] unknown directive "foo" in /etc/nginx/nginx.conf:12
+var nginxErrorRegexp = regexp.MustCompile(`\[(?:emerg|alert|crit|error)\] (.*?)(?: in (\S+):(\d+))?$`)
+
+// NginxError is an error from running nginx, with the location in the config it refers to if there is one
+type NginxError struct {
+	// Message is the error logged by nginx, or how running it failed
+	Message string
+	// File and Line are the location of the error in the config, if nginx reported one
+	File string
+	Line int
+	// Text is the config line at the location
+	Text string
+	// Output is everything nginx printed
+	Output string
+}
+
+func (e *NginxError) Error() string {
+	if e.File == "" {
+		return e.Message
+	}
+	s := fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	if e.Text != "" {
+		s += "\n\t" + e.Text
+	}
+	return s
+}
+
+// ConfigTest runs nginx -t to check the saved config
+func (n *Nginx) ConfigTest() error {
+	if err := n.run("-t"); err != nil {
+		return fmt.Errorf("nginx config test failed: %w", err)
+	}
+	return nil
+}
+
+// Restart reloads nginx so it uses the saved config
+func (n *Nginx) Restart() error {
+	if err := n.run("-s", "reload"); err != nil {
+		return fmt.Errorf("error reloading nginx: %w", err)
+	}
+	return nil
+}
+
+// run runs the nginx binary using the main config file in the server root
+func (n *Nginx) run(args ...string) error {
+	args = append([]string{"-c", filepath.Join(n.ServerRoot, nginx.MainFile)}, args...)
+	ll := log.WithFields("ctl", n.Ctl, "args", args)
+	ll.Debug("running nginx")
+	out, err := exec.Command(n.Ctl, args...).CombinedOutput()
+	if err == nil {
+		ll.WithField("output", string(out)).Trace("ran nginx")
+		return nil
+	}
+	ll.WithError(err).WithField("output", string(out)).Debug("running nginx failed")
+	return newNginxError(out, err)
+}
+
+// newNginxError returns an error with the first error nginx logged, and where in the config it is
+func newNginxError(out []byte, runErr error) *NginxError {
+	e := &NginxError{
+		Message: runErr.Error(),
+		Output:  string(out),
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := nginxErrorRegexp.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		e.Message = m[1]
+		if m[2] != "" {
+			e.File = m[2]
+			e.Line, _ = strconv.Atoi(m[3])
+			e.Text = configLine(e.File, e.Line)
+		}
+		break
+	}
+	return e
+}
+
+// configLine returns the trimmed line of the file, or an empty string if it can't be read
+func configLine(path string, line int) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(string(data), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
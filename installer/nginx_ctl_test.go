@@ -0,0 +1,123 @@
+package installer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeNginx logs its arguments next to itself, and fails the config test if any file has a bad_directive
+const fakeNginx = `#!/bin/sh
+echo "$@" >> "$0.log"
+if [ "$3" = "-t" ]; then
+	match=$(grep -rn bad_directive "$(dirname "$2")" | head -n 1)
+	if [ -n "$match" ]; then
+		echo "nginx: [emerg] unknown directive \"bad_directive\" in $(echo "$match" | cut -d: -f1,2)" >&2
+		echo "nginx: configuration file $2 test failed" >&2
+		exit 1
+	fi
+	echo "nginx: configuration file $2 test is successful" >&2
+fi
+`
+
+func setupFakeNginx(t *testing.T, files map[string]string) (*Nginx, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake nginx uses a posix shell")
+	}
+	root := t.TempDir()
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctl := filepath.Join(t.TempDir(), "nginx")
+	if err := ioutil.WriteFile(ctl, []byte(fakeNginx), 0755); err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewNginx(root, ctl, t.TempDir(), NewReverter(t.TempDir()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, ctl + ".log"
+}
+
+func TestNginx_ConfigTest(t *testing.T) {
+	n, logPath := setupFakeNginx(t, map[string]string{
+		"nginx.conf": "http {\n    include site.conf;\n}\n",
+		"site.conf":  "server {\n    server_name example.com;\n}\n",
+	})
+	if err := n.ConfigTest(); err != nil {
+		t.Fatalf("ConfigTest() error = %v", err)
+	}
+	if err := n.Restart(); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	args, err := ioutil.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := filepath.Join(n.ServerRoot, "nginx.conf")
+	if want := "-c " + conf + " -t\n-c " + conf + " -s reload\n"; string(args) != want {
+		t.Errorf("nginx run with %q, want %q", args, want)
+	}
+
+	site := filepath.Join(n.ServerRoot, "site.conf")
+	if err := ioutil.WriteFile(site, []byte("server {\n    server_name example.com;\n    bad_directive on;\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = n.ConfigTest()
+	var nerr *NginxError
+	if !errors.As(err, &nerr) {
+		t.Fatalf("ConfigTest() error = %v, want an NginxError", err)
+	}
+	if nerr.File != site || nerr.Line != 3 || nerr.Text != "bad_directive on;" || nerr.Message != `unknown directive "bad_directive"` {
+		t.Errorf("ConfigTest() error = %+v", nerr)
+	}
+	if want := site + `:3: unknown directive "bad_directive"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("ConfigTest() error = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestNginx_Rollback(t *testing.T) {
+	config := "http {\n    server {\n        listen 8080;\n        server_name example.com;\n    }\n}\n"
+	n, _ := setupFakeNginx(t, map[string]string{
+		"nginx.conf": config,
+	})
+	if err := n.DeployCert("example.com", testCert); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Save(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(n.ServerRoot, "nginx.conf")
+	if got, _ := ioutil.ReadFile(path); string(got) == config {
+		t.Fatal("Save() did not change the config")
+	}
+
+	if err := n.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if got, _ := ioutil.ReadFile(path); string(got) != config {
+		t.Errorf("Rollback() config got = %q, want %q", got, config)
+	}
+	if _, err := os.Stat(filepath.Join(n.ConfigDir, OptionsSSLNginxName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Rollback() did not remove the created %s", OptionsSSLNginxName)
+	}
+	if n.config.ServerBlocks()[0].IsSSL() {
+		t.Errorf("Rollback() did not reload the config")
+	}
+}
+
+func TestNginx_Run_Missing(t *testing.T) {
+	n := &Nginx{ServerRoot: t.TempDir(), Ctl: filepath.Join(t.TempDir(), "missing")}
+	err := n.ConfigTest()
+	var nerr *NginxError
+	if !errors.As(err, &nerr) || nerr.File != "" {
+		t.Errorf("ConfigTest() error = %v", err)
+	}
+}
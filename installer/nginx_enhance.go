@@ -0,0 +1,240 @@
+package installer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/parser/nginx"
+)
+
+const (
+	// redirectURL is where http requests are redirected to, the same host and path over https
+	redirectURL = "https://$host$request_uri"
+
+	hstsHeader = "Strict-Transport-Security"
+	hstsValue  = "max-age=31536000"
+	cspHeader  = "Content-Security-Policy"
+	uirValue   = "upgrade-insecure-requests"
+)
+
+func (n *Nginx) SupportedEnhancements() []string {
+	return []string{EnhanceRedirect, EnhanceHSTS, EnhanceUIR, EnhanceStapleOCSP}
+}
+
+// Enhance makes the enhancement to the server blocks serving https for the domain, or for a redirect, the server
+// blocks serving plain http for it
+func (n *Nginx) Enhance(domain, enhancement string, cert Certificate) error {
+	log.WithFields("domain", domain, "enhancement", enhancement).Debug("enhancing nginx config")
+	switch enhancement {
+	case EnhanceRedirect:
+		return n.redirect(domain)
+	case EnhanceHSTS:
+		return n.enhanceSSLServers(domain, func(server *nginx.Node) error {
+			return n.addHeader(server, hstsHeader, hstsValue, "always")
+		})
+	case EnhanceUIR:
+		return n.enhanceSSLServers(domain, func(server *nginx.Node) error {
+			return n.addHeader(server, cspHeader, uirValue)
+		})
+	case EnhanceStapleOCSP:
+		if cert.ChainPath == "" {
+			return fmt.Errorf("the certificate chain is needed for OCSP stapling")
+		}
+		return n.enhanceSSLServers(domain, func(server *nginx.Node) error {
+			if err := n.setDirective(server, "ssl_stapling", "on"); err != nil {
+				return err
+			}
+			if err := n.setDirective(server, "ssl_stapling_verify", "on"); err != nil {
+				return err
+			}
+			return n.setDirective(server, "ssl_trusted_certificate", cert.ChainPath)
+		})
+	}
+	return fmt.Errorf("the %s installer does not support the %s enhancement", NginxName, enhancement)
+}
+
+// enhanceSSLServers calls the func for each of the https server blocks chosen for the domain
+func (n *Nginx) enhanceSSLServers(domain string, enhance func(server *nginx.Node) error) error {
+	indexes, err := n.chooseServers(domain, func(m nginx.ServerMatch) bool {
+		return m.Server.IsSSL()
+	})
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return fmt.Errorf("could not find an https server block for %s, install a certificate for it first", domain)
+	}
+	for _, idx := range indexes {
+		if err := enhance(n.config.ServerBlocks()[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addHeader adds an add_header directive to the server block, unless it already adds the header
+// An existing header with a different value is left alone, except for a Content-Security-Policy header which would
+// need to be merged with the value.
+func (n *Nginx) addHeader(server *nginx.Node, name, value string, options ...string) error {
+	for _, c := range server.Children() {
+		if c.Name != "add_header" || len(c.Parameters) < 2 || !strings.EqualFold(unquote(c.Parameters[0]), name) {
+			continue
+		}
+		if strings.EqualFold(name, cspHeader) && !strings.Contains(unquote(c.Parameters[1]), value) {
+			return c.Errorf("a %s header is already set, add %s to it instead", name, value)
+		}
+		log.WithFields("server", server.Location(), "header", name).Debug("header already added")
+		return nil
+	}
+	n.changed[server.File] = true
+	server.AddChild(nginx.Directive{
+		Name:       "add_header",
+		Parameters: append([]string{name, `"` + value + `"`}, options...),
+		File:       server.File,
+	})
+	return nil
+}
+
+// redirect redirects plain http requests for the domain to https. A plain http server block matching the domain
+// by name has an if block added that redirects the domain, unless it already redirects. If there isn't one, a
+// server block that only redirects is added after the https server block for the domain.
+func (n *Nginx) redirect(domain string) error {
+	var ssl *nginx.Node
+	for _, m := range n.config.MatchServers(domain) {
+		if m.Server.IsSSL() {
+			ssl = m.Server
+			break
+		}
+	}
+	if ssl == nil {
+		return fmt.Errorf("could not find an https server block for %s, install a certificate for it first", domain)
+	}
+
+	// a default server only serves the domain by chance, so is ignored rather than redirecting everything
+	indexes, err := n.chooseServers(domain, func(m nginx.ServerMatch) bool {
+		return m.Type != nginx.MatchDefault && servesHTTP(m.Server)
+	})
+	if err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return n.addRedirectServer(ssl, domain)
+	}
+
+	for _, idx := range indexes {
+		server := n.config.ServerBlocks()[idx]
+		if hasRedirect(server, domain) {
+			log.WithFields("domain", domain, "server", server.Location()).Debug("server block already redirects")
+			continue
+		}
+		// a server block serving both http and https can only redirect the http requests
+		cond := []string{"($host", "=", domain + ")"}
+		if server.IsSSL() {
+			cond = []string{"($scheme", "!=", `"https")`}
+		}
+		redirect := nginx.Directive{
+			Name:       "if",
+			Parameters: cond,
+			Children:   []nginx.Directive{{Name: "return", Parameters: []string{"301", redirectURL}}},
+		}
+		// redirect before any other rewrite directives in the server block are run, so straight after the names
+		var names *nginx.Node
+		for _, c := range server.Children() {
+			if c.Name == "server_name" {
+				names = c
+			}
+		}
+		if names == nil {
+			return server.Errorf("server block matching %s has no server_name directive", domain)
+		}
+		redirect.File = names.File
+		redirect.Children[0].File = names.File
+		n.changed[names.File] = true
+		if _, err := names.InsertAfter(redirect); err != nil {
+			return names.Errorf("error adding redirect: %v", err)
+		}
+	}
+	return nil
+}
+
+// addRedirectServer adds a server block after the https server block which redirects plain http requests for the
+// domain, listening on ipv6 too if the https server block does
+func (n *Nginx) addRedirectServer(ssl *nginx.Node, domain string) error {
+	listens, err := ssl.ListenAddresses()
+	if err != nil {
+		return err
+	}
+	server := nginx.Directive{Name: "server", File: ssl.File}
+	server.Children = append(server.Children, nginx.Directive{Name: "listen", Parameters: []string{"80"}, File: ssl.File})
+	for _, l := range listens {
+		if l.IPv6 {
+			server.Children = append(server.Children, nginx.Directive{Name: "listen", Parameters: []string{"[::]:80"}, File: ssl.File})
+			break
+		}
+	}
+	server.Children = append(server.Children,
+		nginx.Directive{Name: "server_name", Parameters: []string{domain}, File: ssl.File},
+		nginx.Directive{Name: "return", Parameters: []string{"301", redirectURL}, File: ssl.File})
+
+	log.WithFields("domain", domain, "server", ssl.Location()).Debug("adding redirect server block")
+	n.changed[ssl.File] = true
+	if _, err := ssl.InsertAfter(server); err != nil {
+		return ssl.Errorf("error adding redirect server block: %v", err)
+	}
+	return nil
+}
+
+// servesHTTP returns whether the server block listens for plain http on any address
+func servesHTTP(server *nginx.Node) bool {
+	if c := server.Child("ssl"); c != nil && len(c.Parameters) == 1 && unquote(c.Parameters[0]) == "on" {
+		return false
+	}
+	listens, err := server.ListenAddresses()
+	if err != nil {
+		return false
+	}
+	for _, l := range listens {
+		if !l.SSL {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRedirect returns whether the server block already redirects to https, either for every request or with an
+// if block checking the scheme or the domain
+func hasRedirect(server *nginx.Node, domain string) bool {
+	for _, c := range server.Children() {
+		switch c.Name {
+		case "return":
+			if isHTTPSRedirect(c.Parameters) {
+				return true
+			}
+		case "if":
+			cond := strings.ToLower(strings.Join(c.Parameters, " "))
+			if !strings.Contains(cond, "$scheme") && !strings.Contains(cond, strings.ToLower(domain)) {
+				continue
+			}
+			for _, r := range c.Children() {
+				if r.Name == "return" && isHTTPSRedirect(r.Parameters) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// isHTTPSRedirect returns whether the parameters of a return directive redirect to https
+func isHTTPSRedirect(params []string) bool {
+	if len(params) != 2 {
+		return false
+	}
+	code, err := strconv.Atoi(params[0])
+	if err != nil || code < 301 || code > 308 {
+		return false
+	}
+	return strings.HasPrefix(strings.ToLower(unquote(params[1])), "https://")
+}
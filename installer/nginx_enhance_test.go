@@ -0,0 +1,264 @@
+package installer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNginx_Enhance(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string
+		domain       string
+		enhancements []string
+		want         string
+		wantErr      string
+	}{
+		{
+			name: "redirect http server",
+			config: `http {
+    server {
+        listen 80;
+        server_name example.com www.example.com;
+        root /var/www;
+    }
+    server {
+        listen 443 ssl;
+        server_name example.com www.example.com;
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceRedirect},
+			want: `http {
+    server {
+        listen 80;
+        server_name example.com www.example.com;
+        if ($host = example.com) {
+            return 301 https://$host$request_uri;
+        }
+        root /var/www;
+    }
+    server {
+        listen 443 ssl;
+        server_name example.com www.example.com;
+    }
+}
+`,
+		},
+		{
+			name: "redirect new server",
+			config: `http {
+    server {
+        listen 443 ssl;
+        listen [::]:443 ssl;
+        server_name example.com;
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceRedirect},
+			want: `http {
+    server {
+        listen 443 ssl;
+        listen [::]:443 ssl;
+        server_name example.com;
+    }
+    server {
+        listen 80;
+        listen [::]:80;
+        server_name example.com;
+        return 301 https://$host$request_uri;
+    }
+}
+`,
+		},
+		{
+			name: "redirect http and https server",
+			config: `http {
+    server {
+        listen 80;
+        listen 443 ssl;
+        server_name example.com;
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceRedirect},
+			want: `http {
+    server {
+        listen 80;
+        listen 443 ssl;
+        server_name example.com;
+        if ($scheme != "https") {
+            return 301 https://$host$request_uri;
+        }
+    }
+}
+`,
+		},
+		{
+			name: "existing redirect",
+			config: `http {
+    server {
+        listen 80;
+        server_name example.com;
+        return 302 "https://example.com$request_uri";
+    }
+    server {
+        listen 443 ssl;
+        server_name example.com;
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceRedirect},
+			want: `http {
+    server {
+        listen 80;
+        server_name example.com;
+        return 302 "https://example.com$request_uri";
+    }
+    server {
+        listen 443 ssl;
+        server_name example.com;
+    }
+}
+`,
+		},
+		{
+			name: "headers and stapling",
+			config: `http {
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        ssl_stapling off;
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceHSTS, EnhanceUIR, EnhanceStapleOCSP},
+			want: `http {
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        ssl_stapling on;
+        add_header Strict-Transport-Security "max-age=31536000" always;
+        add_header Content-Security-Policy "upgrade-insecure-requests";
+        ssl_stapling_verify on;
+        ssl_trusted_certificate /etc/letsencrypt/live/example.com/chain.pem;
+    }
+}
+`,
+		},
+		{
+			name: "existing headers",
+			config: `http {
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        add_header strict-transport-security "max-age=600";
+        add_header Content-Security-Policy "default-src 'self'; upgrade-insecure-requests";
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceHSTS, EnhanceUIR},
+			want: `http {
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        add_header strict-transport-security "max-age=600";
+        add_header Content-Security-Policy "default-src 'self'; upgrade-insecure-requests";
+    }
+}
+`,
+		},
+		{
+			name: "other content security policy",
+			config: `http {
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        add_header Content-Security-Policy "default-src 'self'";
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceUIR},
+			wantErr:      "a Content-Security-Policy header is already set",
+		},
+		{
+			name: "no https",
+			config: `http {
+    server {
+        listen 80;
+        server_name example.com;
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{EnhanceRedirect},
+			wantErr:      "could not find an https server block for example.com",
+		},
+		{
+			name: "unsupported",
+			config: `http {
+    server {
+        listen 443 ssl;
+        server_name example.com;
+    }
+}
+`,
+			domain:       "example.com",
+			enhancements: []string{"unknown"},
+			wantErr:      "does not support the unknown enhancement",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, "nginx.conf")
+			if err := ioutil.WriteFile(path, []byte(tt.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			configDir := t.TempDir()
+
+			// enhancing the saved config again should change nothing
+			for i := 0; i < 2; i++ {
+				n, err := NewNginx(root, DefaultNginxCtl, configDir, NewReverter(t.TempDir()), nil)
+				if err != nil {
+					t.Fatalf("NewNginx() error = %v", err)
+				}
+				for _, e := range tt.enhancements {
+					err = n.Enhance(tt.domain, e, testCert)
+					if err != nil {
+						break
+					}
+				}
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("Enhance() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Enhance() error = %v", err)
+				}
+				if err := n.Save(); err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+				got, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Fatalf("Enhance() pass %d config got:\n%s\nwant:\n%s", i+1, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package installer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testCert = Certificate{
+	CertPath:      "/etc/letsencrypt/live/example.com/cert.pem",
+	ChainPath:     "/etc/letsencrypt/live/example.com/chain.pem",
+	FullChainPath: "/etc/letsencrypt/live/example.com/fullchain.pem",
+	KeyPath:       "/etc/letsencrypt/live/example.com/privkey.pem",
+}
+
+func TestNginx_DeployCert(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		domain  string
+		choose  func(domain string, servers []string) ([]int, error)
+		want    string
+		wantErr string
+	}{
+		{
+			name: "http only",
+			config: `http {
+    server {
+        listen 80;
+        listen [::]:80 ipv6only=on;
+        server_name example.com;
+        root /var/www;
+    }
+}
+`,
+			domain: "example.com",
+			want: `http {
+    server {
+        listen 80;
+        listen [::]:80 ipv6only=on;
+        server_name example.com;
+        root /var/www;
+    }
+    server {
+        listen 443 ssl;
+        listen [::]:443 ssl ipv6only=on;
+        server_name example.com;
+        root /var/www;
+        ssl_certificate /etc/letsencrypt/live/example.com/fullchain.pem;
+        ssl_certificate_key /etc/letsencrypt/live/example.com/privkey.pem;
+        include CONFIG_DIR/options-ssl-nginx.conf;
+        ssl_dhparam CONFIG_DIR/ssl-dhparams.pem;
+    }
+}
+`,
+		},
+		{
+			name: "existing https",
+			config: `http {
+    server {
+        server_name example.com;
+    }
+    server {
+        listen 443 ssl;
+        server_name www.example.com example.com;
+        ssl_certificate old/fullchain.pem;
+        ssl_certificate_key old/privkey.pem;
+        ssl_dhparam dhparams.pem;
+    }
+}
+`,
+			domain: "example.com",
+			want: `http {
+    server {
+        server_name example.com;
+    }
+    server {
+        listen 443 ssl;
+        server_name www.example.com example.com;
+        ssl_certificate /etc/letsencrypt/live/example.com/fullchain.pem;
+        ssl_certificate_key /etc/letsencrypt/live/example.com/privkey.pem;
+        ssl_dhparam dhparams.pem;
+        include CONFIG_DIR/options-ssl-nginx.conf;
+    }
+}
+`,
+		},
+		{
+			name: "other port",
+			config: `http {
+    server {
+        listen 8080;
+        server_name *.example.com;
+    }
+}
+`,
+			domain: "www.example.com",
+			want: `http {
+    server {
+        listen 8080;
+        server_name *.example.com;
+        listen 443 ssl;
+        ssl_certificate /etc/letsencrypt/live/example.com/fullchain.pem;
+        ssl_certificate_key /etc/letsencrypt/live/example.com/privkey.pem;
+        include CONFIG_DIR/options-ssl-nginx.conf;
+        ssl_dhparam CONFIG_DIR/ssl-dhparams.pem;
+    }
+}
+`,
+		},
+		{
+			name: "chosen",
+			config: `http {
+    server {
+        listen 8080;
+        server_name example.com;
+    }
+    server {
+        listen 8081;
+        server_name example.com;
+    }
+}
+`,
+			domain: "example.com",
+			choose: func(domain string, servers []string) ([]int, error) {
+				if len(servers) != 2 || !strings.HasSuffix(servers[1], "nginx.conf:6 (exact example.com)") {
+					return nil, os.ErrInvalid
+				}
+				return []int{1}, nil
+			},
+			want: `http {
+    server {
+        listen 8080;
+        server_name example.com;
+    }
+    server {
+        listen 8081;
+        server_name example.com;
+        listen 443 ssl;
+        ssl_certificate /etc/letsencrypt/live/example.com/fullchain.pem;
+        ssl_certificate_key /etc/letsencrypt/live/example.com/privkey.pem;
+        include CONFIG_DIR/options-ssl-nginx.conf;
+        ssl_dhparam CONFIG_DIR/ssl-dhparams.pem;
+    }
+}
+`,
+		},
+		{
+			name: "ambiguous",
+			config: `http {
+    server {
+        server_name example.com;
+    }
+    server {
+        server_name example.com;
+    }
+}
+`,
+			domain:  "example.com",
+			wantErr: "unable to choose a server block for example.com",
+		},
+		{
+			name: "default server only",
+			config: `http {
+    server {
+        listen 80 default_server;
+    }
+}
+`,
+			domain:  "example.com",
+			wantErr: "unable to choose a server block",
+		},
+		{
+			name: "no match",
+			config: `http {
+    server {
+        server_name example.net;
+    }
+}
+`,
+			domain:  "example.com",
+			wantErr: "could not find a server block for example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			configDir := t.TempDir()
+			path := filepath.Join(root, "nginx.conf")
+			if err := ioutil.WriteFile(path, []byte(tt.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+			n, err := NewNginx(root, DefaultNginxCtl, configDir, NewReverter(t.TempDir()), tt.choose)
+			if err != nil {
+				t.Fatalf("NewNginx() error = %v", err)
+			}
+			err = n.DeployCert(tt.domain, testCert)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DeployCert() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DeployCert() error = %v", err)
+			}
+			if err := n.Save(); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			got, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.ReplaceAll(tt.want, "CONFIG_DIR", configDir); string(got) != want {
+				t.Errorf("DeployCert() config got:\n%s\nwant:\n%s", got, want)
+			}
+			if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+				t.Errorf("Save() changed the file mode")
+			}
+			for _, name := range []string{OptionsSSLNginxName, SSLDHParamsName} {
+				if _, err := os.Stat(filepath.Join(configDir, name)); err != nil {
+					t.Errorf("Save() did not write %s: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNginx_DeployCert_Twice(t *testing.T) {
+	root := t.TempDir()
+	config := "http {\n    server {\n        server_name example.com www.example.com;\n    }\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(root, "nginx.conf"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	n, err := NewNginx(root, DefaultNginxCtl, t.TempDir(), NewReverter(t.TempDir()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the second domain uses the https server block added for the first
+	for _, domain := range []string{"example.com", "www.example.com"} {
+		if err := n.DeployCert(domain, testCert); err != nil {
+			t.Fatalf("DeployCert(%s) error = %v", domain, err)
+		}
+	}
+	servers := n.config.ServerBlocks()
+	if len(servers) != 2 || servers[0].IsSSL() || !servers[1].IsSSL() {
+		t.Fatalf("DeployCert() got %d servers", len(servers))
+	}
+	if got := len(servers[1].FindAll("ssl_certificate")); got != 1 {
+		t.Errorf("DeployCert() got %d ssl_certificate directives, want 1", got)
+	}
+}
+
+func TestNginx_DeployCert_Redeploy(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "nginx.conf")
+	config := "http {\n    server {\n        listen 80;\n        server_name example.com;\n    }\n}\n"
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configDir := t.TempDir()
+
+	var saved []byte
+	for i := 0; i < 2; i++ {
+		n, err := NewNginx(root, DefaultNginxCtl, configDir, NewReverter(t.TempDir()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.DeployCert("example.com", testCert); err != nil {
+			t.Fatalf("DeployCert() error = %v", err)
+		}
+		// deploying the certificate into the saved config again shouldn't change anything
+		if i == 1 && len(n.changed) > 0 {
+			t.Errorf("DeployCert() again changed %v", n.changed)
+		}
+		if err := n.Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 && string(got) != string(saved) {
+			t.Errorf("DeployCert() again got:\n%s\nwant:\n%s", got, saved)
+		}
+		saved = got
+	}
+}
@@ -0,0 +1,315 @@
+package installer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eggsampler/certgot/log"
+)
+
+const (
+	// BackupsDir and TempCheckpointDir are the directories in the work dir that checkpoints are stored in
+	BackupsDir        = "backups"
+	TempCheckpointDir = "temp_checkpoint"
+
+	// the files in each checkpoint, the same as certbot's
+	filePathsName    = "FILEPATHS"
+	newFilesName     = "NEW_FILES"
+	changesSinceName = "CHANGES_SINCE"
+)
+
+// Reverter backs up the files changed by installers into checkpoints, so the changes can be reverted, using the
+// same layout as certbot. Files are backed up into a temporary checkpoint before they're changed, which is reverted
+// if the changes don't work, or finalized into a numbered checkpoint in the backups dir once they do. Finalized
+// checkpoints can then be rolled back, newest first.
+type Reverter struct {
+	// WorkDir is the directory the checkpoints are stored in
+	WorkDir string
+}
+
+// Checkpoint is a finalized checkpoint
+type Checkpoint struct {
+	Dir   string
+	Time  time.Time
+	Title string
+	// Changed are the files that were backed up, and New are the files that were created
+	Changed []string
+	New     []string
+}
+
+// NewReverter returns a reverter storing checkpoints in the work dir
+func NewReverter(workDir string) *Reverter {
+	return &Reverter{
+		WorkDir: workDir,
+	}
+}
+
+func (r *Reverter) tempDir() string {
+	return filepath.Join(r.WorkDir, TempCheckpointDir)
+}
+
+func (r *Reverter) backupsDir() string {
+	return filepath.Join(r.WorkDir, BackupsDir)
+}
+
+// HasTempCheckpoint returns whether there are changes in the temporary checkpoint
+func (r *Reverter) HasTempCheckpoint() bool {
+	_, err := os.Stat(r.tempDir())
+	return err == nil
+}
+
+// AddToTempCheckpoint backs up the files into the temporary checkpoint before they are changed. Files already in the
+// checkpoint keep their first backup, and files that don't exist yet are recorded as new so they are removed when
+// the checkpoint is reverted.
+func (r *Reverter) AddToTempCheckpoint(paths ...string) error {
+	dir := r.tempDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error making checkpoint directory %s: %w", dir, err)
+	}
+	changed, newFiles, err := readCheckpointFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		if containsString(changed, abs) || containsString(newFiles, abs) {
+			continue
+		}
+
+		fi, err := os.Stat(abs)
+		if errors.Is(err, os.ErrNotExist) {
+			log.WithField("path", abs).Trace("recording new file in checkpoint")
+			if err := appendLine(filepath.Join(dir, newFilesName), abs); err != nil {
+				return err
+			}
+			newFiles = append(newFiles, abs)
+			continue
+		} else if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(abs)
+		if err != nil {
+			return err
+		}
+		log.WithField("path", abs).Trace("backing up file to checkpoint")
+		if err := ioutil.WriteFile(backupPath(dir, abs, len(changed)), data, fi.Mode().Perm()); err != nil {
+			return fmt.Errorf("error backing up %s: %w", abs, err)
+		}
+		// only list the file once it's backed up, so an interrupted backup isn't restored
+		if err := appendLine(filepath.Join(dir, filePathsName), abs); err != nil {
+			return err
+		}
+		changed = append(changed, abs)
+	}
+	return nil
+}
+
+// RevertTempCheckpoint restores the files in the temporary checkpoint and removes any new files, then removes the
+// checkpoint. It does nothing if there is no temporary checkpoint.
+func (r *Reverter) RevertTempCheckpoint() error {
+	if !r.HasTempCheckpoint() {
+		return nil
+	}
+	log.Debug("reverting temporary checkpoint")
+	return revertCheckpoint(r.tempDir())
+}
+
+// FinalizeCheckpoint moves the temporary checkpoint into the backups dir, so the changes in it are kept and can be
+// rolled back later. It does nothing if there is no temporary checkpoint.
+func (r *Reverter) FinalizeCheckpoint(title string) error {
+	if !r.HasTempCheckpoint() {
+		return nil
+	}
+	dir := r.tempDir()
+	changed, newFiles, err := readCheckpointFiles(dir)
+	if err != nil {
+		return err
+	}
+	manifest := []string{"-- " + title + " --"}
+	for _, path := range changed {
+		manifest = append(manifest, "changed "+path)
+	}
+	for _, path := range newFiles {
+		manifest = append(manifest, "created "+path)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, changesSinceName), []byte(strings.Join(manifest, "\n")+"\n"), 0600); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(r.backupsDir(), 0700); err != nil {
+		return err
+	}
+	// checkpoints are named by the time they're finalized, like certbot
+	now := time.Now()
+	for {
+		target := filepath.Join(r.backupsDir(), fmt.Sprintf("%d.%06d", now.Unix(), now.Nanosecond()/1000))
+		if _, err := os.Stat(target); errors.Is(err, os.ErrNotExist) {
+			log.WithFields("checkpoint", target, "title", title).Debug("finalizing checkpoint")
+			return os.Rename(dir, target)
+		}
+		now = now.Add(time.Microsecond)
+	}
+}
+
+// Checkpoints returns the finalized checkpoints, newest first
+func (r *Reverter) Checkpoints() ([]Checkpoint, error) {
+	infos, err := ioutil.ReadDir(r.backupsDir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var checkpoints []Checkpoint
+	for _, fi := range infos {
+		t, ok := parseCheckpointTime(fi.Name())
+		if !fi.IsDir() || !ok {
+			continue
+		}
+		cp := Checkpoint{
+			Dir:  filepath.Join(r.backupsDir(), fi.Name()),
+			Time: t,
+		}
+		if cp.Changed, cp.New, err = readCheckpointFiles(cp.Dir); err != nil {
+			return nil, err
+		}
+		if lines, err := readLines(filepath.Join(cp.Dir, changesSinceName)); err == nil && len(lines) > 0 {
+			cp.Title = strings.TrimSuffix(strings.TrimPrefix(lines[0], "-- "), " --")
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Time.After(checkpoints[j].Time)
+	})
+	return checkpoints, nil
+}
+
+// parseCheckpointTime parses the name of a checkpoint dir, a unix timestamp with a fractional part
+func parseCheckpointTime(name string) (time.Time, bool) {
+	parts := strings.SplitN(name, ".", 2)
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var nsec int64
+	if len(parts) == 2 {
+		frac := (parts[1] + "000000000")[:9]
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, false
+		}
+	}
+	return time.Unix(sec, nsec), true
+}
+
+// RollbackCheckpoints reverts the newest finalized checkpoints in reverse order, returning those rolled back
+// Any temporary checkpoint is reverted first, as it holds the most recent changes.
+func (r *Reverter) RollbackCheckpoints(count int) ([]Checkpoint, error) {
+	if count < 1 {
+		return nil, fmt.Errorf("invalid number of checkpoints to roll back: %d", count)
+	}
+	if err := r.RevertTempCheckpoint(); err != nil {
+		return nil, err
+	}
+	checkpoints, err := r.Checkpoints()
+	if err != nil {
+		return nil, err
+	}
+	if count > len(checkpoints) {
+		count = len(checkpoints)
+	}
+	for i, cp := range checkpoints[:count] {
+		log.WithFields("checkpoint", cp.Dir, "title", cp.Title).Debug("rolling back checkpoint")
+		if err := revertCheckpoint(cp.Dir); err != nil {
+			return checkpoints[:i], fmt.Errorf("error rolling back checkpoint %s: %w", cp.Dir, err)
+		}
+	}
+	return checkpoints[:count], nil
+}
+
+// revertCheckpoint restores the backed up files and removes new files in the checkpoint, then removes it
+func revertCheckpoint(dir string) error {
+	changed, newFiles, err := readCheckpointFiles(dir)
+	if err != nil {
+		return err
+	}
+	for i, path := range changed {
+		data, err := ioutil.ReadFile(backupPath(dir, path, i))
+		if err != nil {
+			return fmt.Errorf("error reading backup of %s: %w", path, err)
+		}
+		log.WithField("path", path).Trace("restoring file from checkpoint")
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("error restoring %s: %w", path, err)
+		}
+	}
+	for _, path := range newFiles {
+		log.WithField("path", path).Trace("removing new file from checkpoint")
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing %s: %w", path, err)
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// backupPath is the path of the backup of a file in a checkpoint, named like certbot with the index of the file
+func backupPath(dir, path string, idx int) string {
+	return filepath.Join(dir, fmt.Sprintf("%s_%d", filepath.Base(path), idx))
+}
+
+// readCheckpointFiles returns the backed up and new files listed in a checkpoint
+func readCheckpointFiles(dir string) (changed, newFiles []string, err error) {
+	if changed, err = readLines(filepath.Join(dir, filePathsName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	if newFiles, err = readLines(filepath.Join(dir, newFilesName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	return changed, newFiles, nil
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func appendLine(path, line string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package installer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReverter(t *testing.T) {
+	dir := t.TempDir()
+	r := NewReverter(t.TempDir())
+	a := filepath.Join(dir, "a.conf")
+	b := filepath.Join(dir, "b.conf")
+	writeFile := func(path, contents string) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(contents), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkFile := func(path, want string) {
+		t.Helper()
+		got, err := ioutil.ReadFile(path)
+		if want == "" {
+			if !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("%s exists, want it removed", path)
+			}
+			return
+		}
+		if err != nil || string(got) != want {
+			t.Errorf("%s got = %q (%v), want %q", path, got, err, want)
+		}
+	}
+	writeFile(a, "a1")
+
+	// first checkpoint changes a and creates b
+	if err := r.AddToTempCheckpoint(a, b); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(a, "a2")
+	writeFile(b, "b2")
+	// adding a file again keeps the first backup
+	if err := r.AddToTempCheckpoint(a); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(a, "a3")
+	if err := r.FinalizeCheckpoint("first"); err != nil {
+		t.Fatal(err)
+	}
+	if r.HasTempCheckpoint() {
+		t.Fatal("FinalizeCheckpoint() left the temporary checkpoint")
+	}
+
+	// second checkpoint changes both
+	if err := r.AddToTempCheckpoint(a, b); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(a, "a4")
+	writeFile(b, "b4")
+	if err := r.FinalizeCheckpoint("second"); err != nil {
+		t.Fatal(err)
+	}
+
+	// temporary changes are reverted
+	if err := r.AddToTempCheckpoint(a); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(a, "a5")
+	if err := r.RevertTempCheckpoint(); err != nil {
+		t.Fatal(err)
+	}
+	checkFile(a, "a4")
+	if r.HasTempCheckpoint() {
+		t.Fatal("RevertTempCheckpoint() left the temporary checkpoint")
+	}
+
+	checkpoints, err := r.Checkpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(checkpoints) != 2 || checkpoints[0].Title != "second" || checkpoints[1].Title != "first" {
+		t.Fatalf("Checkpoints() got = %+v", checkpoints)
+	}
+	if !reflect.DeepEqual(checkpoints[1].Changed, []string{a}) || !reflect.DeepEqual(checkpoints[1].New, []string{b}) {
+		t.Errorf("Checkpoints() first changed %v, created %v", checkpoints[1].Changed, checkpoints[1].New)
+	}
+
+	rolledBack, err := r.RollbackCheckpoints(1)
+	if err != nil || len(rolledBack) != 1 || rolledBack[0].Title != "second" {
+		t.Fatalf("RollbackCheckpoints(1) got = %+v, %v", rolledBack, err)
+	}
+	checkFile(a, "a3")
+	checkFile(b, "b2")
+
+	// rolling back more checkpoints than exist rolls back all of them
+	rolledBack, err = r.RollbackCheckpoints(5)
+	if err != nil || len(rolledBack) != 1 {
+		t.Fatalf("RollbackCheckpoints(5) got = %+v, %v", rolledBack, err)
+	}
+	checkFile(a, "a1")
+	checkFile(b, "")
+	if fi, err := os.Stat(a); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("rolling back changed the file mode")
+	}
+	if checkpoints, _ := r.Checkpoints(); len(checkpoints) != 0 {
+		t.Errorf("Checkpoints() got %d after rolling back all", len(checkpoints))
+	}
+
+	if _, err := r.RollbackCheckpoints(0); err == nil {
+		t.Errorf("RollbackCheckpoints(0) did not error")
+	}
+}
+
+func TestParseCheckpointTime(t *testing.T) {
+	tests := []struct {
+		name string
+		want int64
+		ok   bool
+	}{
+		{name: "1600000000.123456", want: 1600000000123456000, ok: true},
+		{name: "1600000000.1234567891", want: 1600000000123456789, ok: true},
+		{name: "1600000000", want: 1600000000000000000, ok: true},
+		{name: "1600000000.5", want: 1600000000500000000, ok: true},
+		{name: "notatime"},
+		{name: "1600000000.x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCheckpointTime(tt.name)
+			if ok != tt.ok || (ok && got.UnixNano() != tt.want) {
+				t.Errorf("parseCheckpointTime() got = %v, %v, want %v, %v", got.UnixNano(), ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package apache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var exampleConfig = `
+<VirtualHost *:80>
+	ServerName example.com
+	ServerAlias www.example.com
+	DocumentRoot /var/www/example.com
+
+	<Directory "/var/www/example.com">
+		Options -Indexes \
+		        +FollowSymLinks
+		Require all granted
+	</Directory>
+
+	# logs
+	CustomLog ${APACHE_LOG_DIR}/access.log "%h %l %u %t \"%r\" %>s %b"
+</VirtualHost>
+`
+
+func TestParse(t *testing.T) {
+	testList := []struct {
+		testName   string
+		input      string
+		fileName   string
+		hasError   bool
+		errorStr   string
+		expectsDir bool
+		equalCheck []interface{}
+	}{
+		{
+			testName:   "no input",
+			expectsDir: true,
+		},
+		{
+			testName:   "simplest directive",
+			input:      "Hello",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "Hello"},
+			},
+		},
+		{
+			testName:   "simple directive",
+			input:      "Hello_World\n",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "Hello_World"},
+			},
+		},
+		{
+			testName:   "directive with parameters",
+			input:      " Listen  192.0.2.1:443   https \n",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "Listen", Parameters: []string{"192.0.2.1:443", "https"}},
+			},
+		},
+		{
+			testName:   "multiple directives",
+			input:      "Listen 80\r\n\r\nListen 443\n",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "Listen", Parameters: []string{"80"}},
+				Directive{Name: "Listen", Parameters: []string{"443"}},
+			},
+		},
+		{
+			testName: "invalid directive",
+			input:    "<VirtualHost",
+			hasError: true,
+		},
+		{
+			testName:   "line continuation",
+			input:      "ServerAlias a.example.com \\\n    b.example.com\\\n c.example.com\n",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "ServerAlias", Parameters: []string{"a.example.com", "b.example.com", "c.example.com"}},
+			},
+		},
+		{
+			testName:   "quoted parameters",
+			input:      `Header set X-Test "a \"quoted\" value" 'single quoted'`,
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "Header", Parameters: []string{"set", "X-Test", `"a \"quoted\" value"`, "'single quoted'"}},
+			},
+		},
+		{
+			testName:   "unterminated quote",
+			input:      `DocumentRoot "/var/www`,
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "DocumentRoot", Parameters: []string{`"/var/www`}},
+			},
+		},
+		{
+			testName:   "comment",
+			input:      "# hello world",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "hello world", Comment: true},
+			},
+		},
+		{
+			testName:   "empty comment",
+			input:      "#\n\t#\n",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Comment: true},
+				Directive{Comment: true},
+			},
+		},
+		{
+			testName:   "hash in parameters",
+			input:      "ServerName example.com # not a comment",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "ServerName", Parameters: []string{"example.com", "#", "not", "a", "comment"}},
+			},
+		},
+		{
+			testName:   "empty section",
+			input:      "<VirtualHost *:80>\n</VirtualHost>",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "VirtualHost", Parameters: []string{"*:80"}, Children: []Directive{}},
+			},
+		},
+		{
+			testName:   "nested sections",
+			input:      "<IfModule mod_ssl.c>\n<VirtualHost _default_:443 [::]:443>\nSSLEngine on\n</VirtualHost>\n</IfModule>\n",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "IfModule", Parameters: []string{"mod_ssl.c"}, Children: []Directive{
+					{Name: "VirtualHost", Parameters: []string{"_default_:443", "[::]:443"}, Children: []Directive{
+						{Name: "SSLEngine", Parameters: []string{"on"}},
+					}},
+				}},
+			},
+		},
+		{
+			testName:   "quoted section parameter",
+			input:      "<Directory \"/var/www/a>b\">\n</directory>\n",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "Directory", Parameters: []string{`"/var/www/a>b"`}, Children: []Directive{}},
+			},
+		},
+		{
+			testName:   "section without parameters",
+			input:      "<Location>\n</Location>",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "Location", Children: []Directive{}},
+			},
+		},
+		{
+			testName:   "negated section",
+			input:      "<IfModule !mod_ssl.c>\n    Listen 80\n</IfModule>",
+			expectsDir: true,
+			equalCheck: []interface{}{
+				Directive{Name: "IfModule", Parameters: []string{"!mod_ssl.c"}, Children: []Directive{
+					{Name: "Listen", Parameters: []string{"80"}},
+				}},
+			},
+		},
+		{
+			testName:   "mismatched section",
+			input:      "<VirtualHost *:80>\n</Directory>\n",
+			hasError:   true,
+			errorStr:   "section <VirtualHost> closed by </Directory>",
+			expectsDir: true,
+		},
+		{
+			testName: "unclosed section",
+			input:    "<VirtualHost *:80>\nServerName example.com\n",
+			hasError: true,
+		},
+		{
+			testName: "section on one line",
+			input:    "<VirtualHost *:80> ServerName example.com </VirtualHost>",
+			hasError: true,
+		},
+		{
+			testName:   "example config",
+			input:      exampleConfig,
+			expectsDir: true,
+		},
+	}
+
+	for _, currentTest := range testList {
+		output, err := Parse(currentTest.fileName, []byte(currentTest.input))
+		if currentTest.hasError == (err == nil) {
+			if err != nil {
+				fmt.Println(caretError(err, currentTest.input))
+			}
+			t.Fatalf("test %q: expected error %v, got: %v", currentTest.testName, currentTest.hasError, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), currentTest.errorStr) {
+			t.Fatalf("test %q: expected %q in error: %v", currentTest.testName, currentTest.errorStr, err)
+		}
+		directives, ok := output.([]interface{})
+		if currentTest.expectsDir != ok {
+			t.Fatalf("test %q: expects directive %t, got: %t", currentTest.testName, currentTest.expectsDir, ok)
+		}
+		if currentTest.equalCheck != nil {
+			if !reflect.DeepEqual(withoutSource(directives), currentTest.equalCheck) {
+				t.Fatalf("test %q: directive mismatch\n expects: %+v\n got: %+v",
+					currentTest.testName, currentTest.equalCheck, directives)
+			}
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	fileList := []struct {
+		fileName   string
+		hasError   bool
+		errorStr   string
+		expectsDir bool
+		equalCheck []Directive
+	}{
+		{
+			fileName:   filepath.Join("testdata", "broken.conf"),
+			hasError:   true,
+			errorStr:   "section <VirtualHost> closed by </Directory>",
+			expectsDir: true,
+		},
+		{
+			fileName:   filepath.Join("testdata", "comments.conf"),
+			expectsDir: true,
+			equalCheck: []Directive{
+				{Comment: true, Name: "a comment at the start of a file"},
+				{Comment: true},
+				{Comment: true, Name: "indented with tabs"},
+				{Name: "ServerName", Parameters: []string{"example.com", "#", "not", "a", "comment,", "apache", "only", "has", "whole", "line", "comments"}},
+				{Comment: true, Name: "a comment continued \\\n  onto the next line"},
+			},
+		},
+		{
+			fileName:   filepath.Join("testdata", "edge_cases.conf"),
+			expectsDir: true,
+			equalCheck: []Directive{
+				{Comment: true, Name: "This is not a valid apache config file but it tests edge cases in valid apache syntax"},
+				{Name: "IfModule", Parameters: []string{"!mod_ssl.c"}, Children: []Directive{}},
+				{Name: "ifmodule", Parameters: []string{"mod_rewrite.c"}, Children: []Directive{
+					{Name: "RewriteEngine", Parameters: []string{"on"}},
+					{Name: "RewriteRule", Parameters: []string{`"^/a b/(.*)$"`, "'/c d/$1'", "[R=302,L]"}},
+				}},
+				{Name: "VirtualHost", Parameters: []string{`"*:443"`, "192.0.2.1:443"}, Children: []Directive{
+					{Name: "ServerName", Parameters: []string{"edge.example.com"}},
+					{Name: "ServerAlias", Parameters: []string{"a.example.com", "b.example.com", "c.example.com"}},
+					{Name: "Header", Parameters: []string{"always", "set", "X-Quoted", `"a \"quoted\" value"`}},
+					{Name: "Directory", Parameters: []string{`"/var/www/with spaces"`}, Children: []Directive{
+						{Name: "IfModule", Parameters: []string{"mod_authz_core.c"}, Children: []Directive{
+							{Name: "Require", Parameters: []string{"all", "granted"}},
+						}},
+					}},
+					{Name: "Location", Parameters: []string{"/"}, Children: []Directive{}},
+				}},
+				{Name: "Define", Parameters: []string{"NoTrailingNewline"}},
+			},
+		},
+		{
+			fileName:   filepath.Join("testdata", "debian_apache_2_4", "apache2", "apache2.conf"),
+			expectsDir: true,
+		},
+		{
+			fileName:   filepath.Join("testdata", "debian_apache_2_4", "apache2", "sites-available", "example.com.conf"),
+			expectsDir: true,
+		},
+		{
+			fileName:   filepath.Join("testdata", "rhel_httpd_2_4", "httpd", "conf", "httpd.conf"),
+			expectsDir: true,
+		},
+		{
+			fileName:   filepath.Join("testdata", "rhel_httpd_2_4", "httpd", "conf.d", "ssl.conf"),
+			expectsDir: true,
+		},
+	}
+
+	for _, currentTest := range fileList {
+		output, err := ParseFile(currentTest.fileName)
+		if currentTest.hasError == (err == nil) {
+			if err != nil {
+				input, err2 := ioutil.ReadFile(currentTest.fileName)
+				if err2 != nil {
+					panic(err)
+				}
+				fmt.Println(caretError(err, string(input)))
+			}
+			t.Fatalf("test %q: expected error %v, got: %v\n output: %+v",
+				currentTest.fileName, currentTest.hasError, err, output)
+		}
+		if err != nil && !strings.Contains(err.Error(), currentTest.errorStr) {
+			t.Fatalf("test %q: expected %q in error: %v", currentTest.fileName, currentTest.errorStr, err)
+		}
+		directives := toDirectiveSlice(output)
+		if currentTest.expectsDir != (len(directives) > 0) {
+			t.Fatalf("test %q: expects directive %t, got: %d\n output: %#v",
+				currentTest.fileName, currentTest.expectsDir, len(directives), directives)
+		}
+		if currentTest.equalCheck != nil {
+			if !reflect.DeepEqual(withoutSource(directives), currentTest.equalCheck) {
+				t.Fatalf("test %q: directive mismatch\n expects: %#v\n got: %#v",
+					currentTest.fileName, currentTest.equalCheck, withoutSource(directives))
+			}
+		}
+	}
+}
+
+func TestCaretError(t *testing.T) {
+	input := "<VirtualHost *:80>\n    ServerName example.com\n</Directory>\n"
+	_, err := Parse("", []byte(input))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	got := caretError(err, input)
+	if !strings.HasPrefix(got, "<VirtualHost *:80>\n^\n1:1") {
+		t.Errorf("caretError() got:\n%s", got)
+	}
+}
+
+// withoutSource removes the original formatting and positions from parsed directives, so they can be compared to
+// expected values
+func withoutSource(v interface{}) interface{} {
+	switch dirs := v.(type) {
+	case []interface{}:
+		var out []interface{}
+		for _, d := range dirs {
+			out = append(out, withoutSource(d))
+		}
+		return out
+	case []Directive:
+		// sections keep their empty, non nil, children
+		out := make([]Directive, 0, len(dirs))
+		for _, d := range dirs {
+			out = append(out, withoutSource(d).(Directive))
+		}
+		return out
+	case Directive:
+		dirs.format = nil
+		dirs.File = ""
+		dirs.Start = Position{}
+		dirs.End = Position{}
+		if dirs.Children != nil {
+			dirs.Children = withoutSource(dirs.Children).([]Directive)
+		}
+		return dirs
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+//go:generate pigeon -o apache.peg.go apache.peg
+
+package apache
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Directive is a directive, section or comment in an apache config. Sections such as <VirtualHost *:80> have the
+// directives inside them as Children, which is never nil for a section. Parameters keep any quotes.
+type Directive struct {
+	Name       string
+	Parameters []string
+	Comment    bool
+	Blank      bool
+	Children   []Directive
+
+	// File is the path of the file the directive was parsed from, if read from a file
+	File string
+	// Start and End are the positions of the first character and just after the last character of the directive,
+	// excluding any surrounding whitespace. Both are zero for directives that weren't parsed.
+	Start Position
+	End   Position
+
+	// Includes are the files matched by an Include or IncludeOptional directive, set when loaded with Load
+	Includes []*File
+
+	// format is the original text of a parsed directive, used to print it back unchanged
+	format *directiveFormat
+}
+
+// Position is a location in a config file
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // starting at 1
+	Column int // in characters, starting at 1
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Location returns the file and line the directive starts at, eg sites-enabled/000-default.conf:12
+func (d Directive) Location() string {
+	if d.File == "" {
+		return strconv.Itoa(d.Start.Line)
+	}
+	return d.File + ":" + strconv.Itoa(d.Start.Line)
+}
+
+// Errorf returns an error prefixed with the location of the directive
+func (d Directive) Errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("%s: %s", d.Location(), fmt.Sprintf(format, a...))
+}
+
+// ReadFile parses the file, setting the file of every directive
+func ReadFile(filename string) ([]Directive, error) {
+	v, err := ParseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dirs := toDirectiveSlice(v)
+	setFile(dirs, filename)
+	return dirs, nil
+}
+
+func setFile(dirs []Directive, filename string) {
+	for i := range dirs {
+		dirs[i].File = filename
+		setFile(dirs[i].Children, filename)
+	}
+}
+
+func toString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func toStringSlice(v interface{}) []string {
+	var ss []string
+	for _, vv := range toIfaceSlice(v) {
+		s := toString(vv)
+		if s == "" {
+			continue
+		}
+		ss = append(ss, s)
+	}
+	return ss
+}
+
+func toIfaceSlice(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	vv, _ := v.([]interface{})
+	return vv
+}
+
+func toDirectiveSlice(v interface{}) []Directive {
+	if v == nil {
+		return nil
+	}
+	vv, _ := v.([]interface{})
+	var d []Directive
+	for _, vvv := range vv {
+		vvvv, ok := vvv.(Directive)
+		if ok {
+			d = append(d, vvvv)
+		}
+	}
+	return d
+}
@@ -0,0 +1,91 @@
+package apache
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile_Positions(t *testing.T) {
+	fileName := filepath.Join("testdata", "edge_cases.conf")
+	dirs, err := ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		dir      Directive
+		start    Position
+		end      Position
+		location string
+	}{
+		{
+			name:     "comment",
+			dir:      dirs[0],
+			start:    Position{Offset: 0, Line: 1, Column: 1},
+			end:      Position{Offset: 87, Line: 1, Column: 88},
+			location: fileName + ":1",
+		},
+		{
+			name:     "section",
+			dir:      dirs[3],
+			start:    Position{Offset: 229, Line: 11, Column: 1},
+			end:      Position{Offset: 631, Line: 24, Column: 15},
+			location: fileName + ":11",
+		},
+		{
+			name:     "continued",
+			dir:      dirs[3].Children[1],
+			start:    Position{Offset: 301, Line: 13, Column: 5},
+			end:      Position{Offset: 390, Line: 15, Column: 30},
+			location: fileName + ":13",
+		},
+		{
+			name:     "nested",
+			dir:      dirs[3].Children[3].Children[0],
+			start:    Position{Offset: 490, Line: 18, Column: 9},
+			end:      Position{Offset: 569, Line: 20, Column: 20},
+			location: fileName + ":18",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.dir.File != fileName {
+				t.Errorf("File got = %q, want %q", tt.dir.File, fileName)
+			}
+			if tt.dir.Start != tt.start {
+				t.Errorf("Start got = %#v, want %#v", tt.dir.Start, tt.start)
+			}
+			if tt.dir.End != tt.end {
+				t.Errorf("End got = %#v, want %#v", tt.dir.End, tt.end)
+			}
+			if got := tt.dir.Location(); got != tt.location {
+				t.Errorf("Location() got = %q, want %q", got, tt.location)
+			}
+		})
+	}
+
+	err = dirs[3].Errorf("virtual host has no %s", "DocumentRoot")
+	if want := fileName + ":11: virtual host has no DocumentRoot"; err.Error() != want {
+		t.Errorf("Errorf() got = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestParse_PositionColumns(t *testing.T) {
+	output, err := Parse("", []byte("# héllo wörld\nFoo 'bär' \\\n  Bar\n\tBaz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := toDirectiveSlice(output)
+	if len(dirs) != 3 {
+		t.Fatalf("expected 3 directives, got %d", len(dirs))
+	}
+	// columns count characters, offsets count bytes
+	want := Position{Offset: 34, Line: 3, Column: 6}
+	if dirs[1].End != want {
+		t.Errorf("End got = %#v, want %#v", dirs[1].End, want)
+	}
+	if got := dirs[2].Location(); got != "4" {
+		t.Errorf("Location() got = %q, want %q", got, "4")
+	}
+}
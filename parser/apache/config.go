@@ -0,0 +1,307 @@
+package apache
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MainFiles are the names of the main config file in the apache server root, as used by Debian and RHEL
+var MainFiles = []string{"apache2.conf", "httpd.conf", filepath.Join("conf", "httpd.conf")}
+
+// Config is an apache config loaded from a server root, made up of the main config file and every file it includes
+type Config struct {
+	// Root is the directory the config was loaded from
+	Root string
+	// Prefix is the ServerRoot used by apache, which relative include paths are resolved from
+	Prefix string
+	// Main is the main config file
+	Main *File
+	// Files are all the loaded files, in the order they were first included, starting with the main file
+	Files []*File
+}
+
+// File is a single parsed config file
+type File struct {
+	Path       string
+	Directives []Directive
+}
+
+// Load loads the main config file in the server root, and every file it includes
+func Load(root string) (*Config, error) {
+	return LoadPrefix(root, root)
+}
+
+// LoadPrefix loads the main config file in root, and every file it includes, where apache itself uses prefix as its
+// ServerRoot, eg /etc/apache2 or /etc/httpd. Absolute include paths inside the prefix are loaded from root instead,
+// so a copy of a config can be loaded from another directory.
+func LoadPrefix(root, prefix string) (*Config, error) {
+	cfg := &Config{
+		Root:   filepath.Clean(root),
+		Prefix: filepath.Clean(prefix),
+	}
+	l := &loader{
+		cfg:     cfg,
+		files:   map[string]*File{},
+		loading: map[string]bool{},
+	}
+	path, err := mainFile(cfg.Root)
+	if err != nil {
+		return nil, err
+	}
+	main, err := l.load(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Main = main
+	return cfg, nil
+}
+
+// mainFile returns the path of the first of the MainFiles that exists in the root
+func mainFile(root string) (string, error) {
+	for _, name := range MainFiles {
+		path := filepath.Join(root, name)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no main config file found in %s, looked for %s", root, strings.Join(MainFiles, ", "))
+}
+
+// File returns the loaded file with the path, or nil if it wasn't loaded
+func (c *Config) File(path string) *File {
+	path = filepath.Clean(path)
+	for _, f := range c.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// AddFile adds a new file to the config, which isn't included by any other file until an Include directive for it
+// is added or it's otherwise matched by one, see Included
+func (c *Config) AddFile(path string, dirs []Directive) *File {
+	path = filepath.Clean(path)
+	setFile(dirs, path)
+	f := &File{
+		Path:       path,
+		Directives: dirs,
+	}
+	c.Files = append(c.Files, f)
+	return f
+}
+
+// Included returns whether the path would be loaded by any of the Include or IncludeOptional directives in the
+// config, as a file, a match of a wildcard or a file inside an included directory
+func (c *Config) Included(path string) bool {
+	path = filepath.Clean(path)
+	for _, f := range c.Files {
+		if c.included(f.Directives, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Config) included(dirs []Directive, path string) bool {
+	for _, d := range dirs {
+		if d.Comment {
+			continue
+		}
+		if !strings.EqualFold(d.Name, "Include") && !strings.EqualFold(d.Name, "IncludeOptional") {
+			if c.included(d.Children, path) {
+				return true
+			}
+			continue
+		}
+		if len(d.Parameters) != 1 {
+			continue
+		}
+		include := c.includePath(unquote(d.Parameters[0]))
+		if include == path || strings.HasPrefix(path, include+string(filepath.Separator)) {
+			return true
+		}
+		if !strings.ContainsAny(include, "*?[") {
+			continue
+		}
+		// like apr_fnmatch, wildcards don't match hidden files
+		hidden := strings.HasPrefix(filepath.Base(path), ".") && !strings.HasPrefix(filepath.Base(include), ".")
+		if ok, _ := filepath.Match(include, path); ok && !hidden {
+			return true
+		}
+	}
+	return false
+}
+
+// includePath returns the path of the file, directory or pattern an include parameter refers to in the root
+func (c *Config) includePath(include string) string {
+	path := filepath.FromSlash(include)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.Prefix, path)
+	}
+	// load files from the root instead of where apache sees them
+	if rel, err := filepath.Rel(c.Prefix, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		path = filepath.Join(c.Root, rel)
+	}
+	return path
+}
+
+type loader struct {
+	cfg   *Config
+	files map[string]*File
+	// loading are the files currently being loaded, to detect include cycles
+	loading map[string]bool
+}
+
+// load parses the file and resolves its includes, a file that is included more than once is only loaded once
+func (l *loader) load(path string) (*File, error) {
+	if f, ok := l.files[path]; ok {
+		return f, nil
+	}
+	dirs, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{
+		Path:       path,
+		Directives: dirs,
+	}
+	l.files[path] = f
+	l.cfg.Files = append(l.cfg.Files, f)
+
+	l.loading[path] = true
+	defer delete(l.loading, path)
+	if err := l.includes(f.Directives); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// includes loads the files matched by any Include or IncludeOptional directives, setting the included files on the
+// directive. Directive names are case insensitive in apache.
+func (l *loader) includes(dirs []Directive) error {
+	for i := range dirs {
+		d := &dirs[i]
+		if d.Comment {
+			continue
+		}
+		optional := strings.EqualFold(d.Name, "IncludeOptional")
+		if !optional && !strings.EqualFold(d.Name, "Include") {
+			if err := l.includes(d.Children); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(d.Parameters) != 1 {
+			return d.Errorf("%s takes one argument", d.Name)
+		}
+
+		paths, err := l.resolve(unquote(d.Parameters[0]), optional)
+		if err != nil {
+			return d.Errorf("%v", err)
+		}
+		d.Includes = nil
+		for _, path := range paths {
+			if l.loading[path] {
+				return d.Errorf("include cycle, %s is already being loaded", path)
+			}
+			f, err := l.load(path)
+			if err != nil {
+				return err
+			}
+			d.Includes = append(d.Includes, f)
+		}
+	}
+	return nil
+}
+
+// resolve returns the files an include parameter refers to like apache, expanding any glob pattern and reading
+// directories recursively. Missing files and patterns that match nothing are an error unless the include is
+// optional.
+func (l *loader) resolve(include string, optional bool) ([]string, error) {
+	path := l.cfg.includePath(include)
+	if !strings.ContainsAny(path, "*?[") {
+		fi, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				if optional {
+					return nil, nil
+				}
+				return nil, fmt.Errorf("included file %s does not exist", path)
+			}
+			return nil, err
+		}
+		if fi.IsDir() {
+			return readDir(path)
+		}
+		return []string{path}, nil
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %s: %v", include, err)
+	}
+	hidden := strings.HasPrefix(filepath.Base(path), ".")
+	var paths []string
+	for _, m := range matches {
+		// like apr_fnmatch, wildcards don't match hidden files
+		if !hidden && strings.HasPrefix(filepath.Base(m), ".") {
+			continue
+		}
+		fi, err := os.Stat(m)
+		if err != nil {
+			continue
+		}
+		if !fi.IsDir() {
+			paths = append(paths, m)
+			continue
+		}
+		files, err := readDir(m)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+	if len(paths) == 0 && !optional {
+		return nil, fmt.Errorf("no matches for the wildcard %s", include)
+	}
+	return paths, nil
+}
+
+// readDir returns every file in the directory and its subdirectories, in sorted order
+func readDir(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+	var paths []string
+	for _, fi := range infos {
+		path := filepath.Join(dir, fi.Name())
+		if !fi.IsDir() {
+			paths = append(paths, path)
+			continue
+		}
+		files, err := readDir(path)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+	return paths, nil
+}
+
+// unquote removes any quotes around a parameter
+func unquote(param string) string {
+	if len(param) >= 2 && (param[0] == '"' || param[0] == '\'') && param[len(param)-1] == param[0] {
+		return param[1 : len(param)-1]
+	}
+	return param
+}
@@ -0,0 +1,239 @@
+package apache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad_Debian(t *testing.T) {
+	root := filepath.Join("testdata", "debian_apache_2_4", "apache2")
+	cfg, err := LoadPrefix(root, "/etc/apache2")
+	if err != nil {
+		t.Fatalf("LoadPrefix() error = %v", err)
+	}
+
+	var got []string
+	for _, f := range cfg.Files {
+		rel, _ := filepath.Rel(root, f.Path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	// mods-enabled/*.load doesn't match ssl.load as it isn't enabled
+	want := []string{
+		"apache2.conf",
+		"mods-enabled/alias.load",
+		"mods-enabled/dir.load",
+		"mods-enabled/alias.conf",
+		"mods-enabled/dir.conf",
+		"ports.conf",
+		"conf-enabled/charset.conf",
+		"conf-enabled/security.conf",
+		"sites-enabled/000-default.conf",
+		"sites-enabled/example.com.conf",
+		"sites-enabled/example.net.conf",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("LoadPrefix() files got = %v, want %v", got, want)
+	}
+	if cfg.Main != cfg.Files[0] {
+		t.Errorf("LoadPrefix() main file is not the first file")
+	}
+
+	// every directive keeps the file it was loaded from
+	for _, f := range cfg.Files {
+		var check func(dirs []Directive)
+		check = func(dirs []Directive) {
+			for _, d := range dirs {
+				if d.File != f.Path {
+					t.Errorf("directive %s at %s has file %s, want %s", d.Name, d.Location(), d.File, f.Path)
+				}
+				check(d.Children)
+			}
+		}
+		check(f.Directives)
+	}
+
+	// the include directive points at the included files
+	var sitesInclude *Directive
+	for i, d := range cfg.Main.Directives {
+		if d.Name == "IncludeOptional" && d.Parameters[0] == "sites-enabled/*.conf" {
+			sitesInclude = &cfg.Main.Directives[i]
+		}
+	}
+	if sitesInclude == nil {
+		t.Fatal("sites-enabled include not found")
+	}
+	if len(sitesInclude.Includes) != 3 || sitesInclude.Includes[0] != cfg.File(filepath.Join(root, "sites-enabled", "000-default.conf")) {
+		t.Errorf("include directive has wrong files: %v", sitesInclude.Includes)
+	}
+}
+
+func TestLoad_RHEL(t *testing.T) {
+	root := filepath.Join("testdata", "rhel_httpd_2_4", "httpd")
+	cfg, err := LoadPrefix(root, "/etc/httpd")
+	if err != nil {
+		t.Fatalf("LoadPrefix() error = %v", err)
+	}
+	var got []string
+	for _, f := range cfg.Files {
+		rel, _ := filepath.Rel(root, f.Path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	// the hidden conf.d/.example.org.conf isn't matched by the wildcard
+	want := []string{
+		"conf/httpd.conf",
+		"conf.modules.d/00-base.conf",
+		"conf.modules.d/00-mpm.conf",
+		"conf.modules.d/00-ssl.conf",
+		"conf.d/example.org.conf",
+		"conf.d/ssl.conf",
+		"conf.d/welcome.conf",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadPrefix() files got = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "missing include",
+			files: map[string]string{
+				"apache2.conf": "Listen 80\nInclude missing.conf",
+			},
+			wantErr: "apache2.conf:2: included file",
+		},
+		{
+			name: "wildcard matches nothing",
+			files: map[string]string{
+				"apache2.conf": "include sites-enabled/*.conf",
+			},
+			wantErr: "apache2.conf:1: no matches for the wildcard sites-enabled/*.conf",
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"httpd.conf": "Include a.conf",
+				"a.conf":     "<IfModule mod_ssl.c>\n    IncludeOptional b/*.conf\n</IfModule>",
+				"b/b.conf":   "Include a.conf",
+			},
+			wantErr: "b.conf:1: include cycle",
+		},
+		{
+			name: "invalid include",
+			files: map[string]string{
+				"apache2.conf": "Include a.conf b.conf",
+			},
+			wantErr: "apache2.conf:1: Include takes one argument",
+		},
+		{
+			name: "unparsable include",
+			files: map[string]string{
+				"apache2.conf": "Include a.conf",
+				"a.conf":       "<VirtualHost *:80>",
+			},
+			wantErr: "a.conf:1:19",
+		},
+		{
+			name: "no main file",
+			files: map[string]string{
+				"a.conf": "",
+			},
+			wantErr: "no main config file found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+			_, err := Load(root)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Includes(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"apache2.conf":           "Include common.conf\n<VirtualHost *:80>\n    include common.conf\n</VirtualHost>\nIncludeOptional missing.conf\nIncludeOptional missing/*.conf\nInclude \"conf dir\"\n",
+		"common.conf":            "ServerTokens Prod\n",
+		"conf dir/b.conf":        "Listen 80\n",
+		"conf dir/a/nested.conf": "Listen 81\n",
+		"conf dir/.hidden":       "Listen 82\n",
+	}
+	writeFiles(t, root, files)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	var got []string
+	for _, f := range cfg.Files {
+		rel, _ := filepath.Rel(root, f.Path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	// directories are read recursively in sorted order, including hidden files
+	want := []string{"apache2.conf", "common.conf", "conf dir/.hidden", "conf dir/a/nested.conf", "conf dir/b.conf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() files got = %v, want %v", got, want)
+	}
+	dirs := cfg.Main.Directives
+	if dirs[0].Includes[0] != dirs[1].Children[0].Includes[0] {
+		t.Errorf("file included twice was loaded twice")
+	}
+	if len(dirs[2].Includes) != 0 || len(dirs[3].Includes) != 0 {
+		t.Errorf("optional includes of missing files got %v and %v", dirs[2].Includes, dirs[3].Includes)
+	}
+}
+
+func TestConfig_Included(t *testing.T) {
+	root := filepath.Join("testdata", "debian_apache_2_4", "apache2")
+	cfg, err := LoadPrefix(root, "/etc/apache2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(root, "ports.conf"), true},
+		{filepath.Join(root, "sites-enabled", "example.com-le-ssl.conf"), true},
+		{filepath.Join(root, "sites-enabled", ".example.com-le-ssl.conf"), false},
+		{filepath.Join(root, "sites-enabled", "example.com"), false},
+		{filepath.Join(root, "sites-available", "example.com-le-ssl.conf"), false},
+		{filepath.Join(root, "mods-enabled", "ssl.load"), true},
+	}
+	for _, tt := range tests {
+		if got := cfg.Included(tt.path); got != tt.want {
+			t.Errorf("Included(%s) got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	path := filepath.Join(root, "sites-available", "example.com-le-ssl.conf")
+	f := cfg.AddFile(path, []Directive{{Name: "Listen", Parameters: []string{"443"}}})
+	if cfg.File(path) != f || f.Directives[0].File != path {
+		t.Errorf("AddFile() didn't add the file")
+	}
+}
+
+// writeFiles writes the files, keyed by slash separated paths relative to root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package apache
+
+import (
+	"testing"
+)
+
+func TestNode_Edit(t *testing.T) {
+	input := `# site
+<VirtualHost *:80>
+    ServerName example.com
+    SSLCertificateFile old.pem
+</VirtualHost>
+`
+	output, err := Parse("", []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &File{Directives: toDirectiveSlice(output)}
+	root := NewRoot(f)
+	vhost := root.FindAll("virtualhost")[0]
+
+	copied := vhost.Directive.Copy()
+	copied.Parameters = []string{"*:443"}
+	ssl, err := vhost.InsertAfter(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ssl.Child("SSLCertificateFile").Remove(); err != nil {
+		t.Fatal(err)
+	}
+	ssl.AddChild(Directive{Name: "SSLCertificateFile", Parameters: []string{"new.pem"}})
+	root.AddChild(Directive{Name: "Listen", Parameters: []string{"443"}})
+
+	want := `# site
+<VirtualHost *:80>
+    ServerName example.com
+    SSLCertificateFile old.pem
+</VirtualHost>
+<VirtualHost *:443>
+    ServerName example.com
+    SSLCertificateFile new.pem
+</VirtualHost>
+Listen 443
+`
+	// the root node edits the file's directives
+	if got := string(Format(f.Directives)); got != want {
+		t.Errorf("Format() got = %q, want %q", got, want)
+	}
+
+	if err := root.Child("listen").Remove(); err != nil {
+		t.Fatal(err)
+	}
+	ssl = root.FindAll("VirtualHost")[1]
+	if err := ssl.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(Format(f.Directives)); got != input {
+		t.Errorf("Format() after Remove() got = %q, want %q", got, input)
+	}
+	if err := ssl.Remove(); err == nil {
+		t.Errorf("Remove() of a removed directive did not error")
+	}
+}
@@ -0,0 +1,268 @@
+package apache
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// indentUnit is used to indent new directives when there are no parsed siblings to copy the indentation from
+const indentUnit = "    "
+
+// directiveFormat is the original text surrounding a parsed directive, split so that any whitespace between
+// directives is owned by exactly one of them. The whitespace up to and including the first newline after a
+// directive belongs to it, the rest belongs to the next directive (or to the closing tag of the section).
+type directiveFormat struct {
+	// start and end are the offsets of the directive text in the parsed input, excluding surrounding whitespace
+	start, end int
+
+	before string // whitespace before the directive
+	text   string // the directive text as parsed, or just the opening tag for sections
+	open   string // sections only, whitespace after the opening tag
+	close  string // sections only, whitespace before the closing tag
+	tag    string // sections only, the closing tag as parsed
+	after  string // whitespace after the directive
+
+	// section is whether the directive was parsed as a section
+	section bool
+	// indent is the indentation of the directive if it started on a new line
+	indent   string
+	indented bool
+
+	// the directive as parsed, to detect changes
+	name    string
+	params  []string
+	comment bool
+}
+
+// newFormat records the position of a directive matched at offset, the surrounding whitespace is set once the
+// whole input has been parsed
+func newFormat(offset int, text []byte) *directiveFormat {
+	trimmed := bytes.TrimLeft(text, " \t\r\n")
+	start := offset + len(text) - len(trimmed)
+	return &directiveFormat{
+		start: start,
+		end:   start + len(bytes.TrimRight(trimmed, " \t\r\n")),
+	}
+}
+
+// changed returns whether the directive has been modified since it was parsed
+func (f *directiveFormat) changed(d Directive) bool {
+	if d.Name != f.name || d.Comment != f.comment || len(d.Parameters) != len(f.params) {
+		return true
+	}
+	for i := range d.Parameters {
+		if d.Parameters[i] != f.params[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// formatDirectives sets the format of all parsed directives from the full input
+func formatDirectives(v interface{}, input []byte) []interface{} {
+	dirs := toDirectiveSlice(v)
+	lines := []int{0}
+	for i, b := range input {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	setFormat(string(input), lines, dirs, 0, len(input), nil)
+	out := make([]interface{}, 0, len(dirs))
+	for _, d := range dirs {
+		out = append(out, d)
+	}
+	return out
+}
+
+// setFormat assigns the whitespace between from and to amongst the directives, and sets their positions using the
+// offsets of the start of each line. Any whitespace before the first directive up to the first newline belongs to
+// the opening tag of the parent section.
+func setFormat(input string, lines []int, dirs []Directive, from, to int, parent *directiveFormat) {
+	var owner *string
+	if parent != nil {
+		owner = &parent.open
+	}
+	pos := from
+	for i := range dirs {
+		f := dirs[i].format
+		if f == nil {
+			continue
+		}
+		head, rest := splitGap(input[pos:f.start], owner != nil)
+		if owner != nil {
+			*owner = head
+		}
+		f.before = rest
+		if idx := strings.LastIndexByte(rest, '\n'); idx >= 0 || strings.HasSuffix(head, "\n") || f.start == 0 {
+			f.indent = rest[idx+1:]
+			f.indented = true
+		}
+
+		dirs[i].Start = positionAt(input, lines, f.start)
+		dirs[i].End = positionAt(input, lines, f.end)
+
+		d := dirs[i]
+		f.name = d.Name
+		f.params = append([]string(nil), d.Parameters...)
+		f.comment = d.Comment
+		f.section = !d.Comment && input[f.start] == '<'
+		if f.section {
+			// the closing tag is the last one in the section, and the opening tag ends at the last > before the
+			// first child or the closing tag
+			closeStart := f.start + strings.LastIndex(input[f.start:f.end], "</")
+			f.tag = input[closeStart:f.end]
+			headerEnd := closeStart
+			if len(d.Children) > 0 && d.Children[0].format != nil {
+				headerEnd = d.Children[0].format.start
+			}
+			headerEnd = f.start + strings.LastIndexByte(input[f.start:headerEnd], '>') + 1
+			f.text = input[f.start:headerEnd]
+			setFormat(input, lines, d.Children, headerEnd, closeStart, f)
+		} else {
+			f.text = input[f.start:f.end]
+		}
+
+		owner = &f.after
+		pos = f.end
+	}
+
+	gap := input[pos:to]
+	if parent == nil {
+		// any trailing whitespace at the end of the file belongs to the last directive
+		if owner != nil {
+			*owner = gap
+		}
+		return
+	}
+	head, rest := splitGap(gap, true)
+	*owner = head
+	parent.close = rest
+}
+
+// positionAt returns the position of the offset in the input, using the offsets of the start of each line
+func positionAt(input string, lines []int, offset int) Position {
+	line := sort.Search(len(lines), func(i int) bool {
+		return lines[i] > offset
+	})
+	return Position{
+		Offset: offset,
+		Line:   line,
+		Column: utf8.RuneCountInString(input[lines[line-1]:offset]) + 1,
+	}
+}
+
+// splitGap splits whitespace after the first newline, or returns it all as the head if there is no newline
+func splitGap(gap string, hasOwner bool) (string, string) {
+	if !hasOwner {
+		return "", gap
+	}
+	idx := strings.IndexByte(gap, '\n')
+	if idx < 0 {
+		return gap, ""
+	}
+	return gap[:idx+1], gap[idx+1:]
+}
+
+// Print writes the directives as an apache config to w
+// Parsed directives that haven't been modified are written exactly as they were parsed, including comments,
+// blank lines, quoting, line continuations and indentation. Modified or new directives are formatted on a single
+// line and indented to match their siblings.
+func Print(w io.Writer, directives []Directive) error {
+	_, err := w.Write(Format(directives))
+	return err
+}
+
+// Format returns the directives as an apache config, as per Print
+func Format(directives []Directive) []byte {
+	p := &printer{}
+	p.list(directives, "")
+	return p.buf.Bytes()
+}
+
+type printer struct {
+	buf bytes.Buffer
+}
+
+func (p *printer) list(dirs []Directive, indent string) {
+	for _, d := range dirs {
+		if d.format != nil && d.format.indented {
+			indent = d.format.indent
+			break
+		}
+	}
+	for _, d := range dirs {
+		p.directive(d, indent)
+	}
+}
+
+func (p *printer) directive(d Directive, indent string) {
+	if d.Blank {
+		p.startLine("")
+		p.buf.WriteString("\n")
+		return
+	}
+
+	f := d.format
+	section := d.Children != nil || (f != nil && f.section)
+	if f != nil {
+		p.startLine(f.before)
+	} else {
+		p.startLine(indent)
+	}
+	if f != nil && f.section == section && !f.changed(d) {
+		p.buf.WriteString(f.text)
+	} else {
+		p.buf.WriteString(directiveText(d, section))
+	}
+
+	if section {
+		if f != nil && f.section {
+			p.buf.WriteString(f.open)
+		}
+		p.list(d.Children, indent+indentUnit)
+		if f != nil && f.section {
+			p.startLine(f.close)
+		} else {
+			p.startLine(indent)
+		}
+		if f != nil && f.section && f.name == d.Name {
+			p.buf.WriteString(f.tag)
+		} else {
+			p.buf.WriteString("</" + d.Name + ">")
+		}
+	}
+
+	if f != nil {
+		p.buf.WriteString(f.after)
+	} else {
+		p.buf.WriteString("\n")
+	}
+}
+
+// startLine makes sure the output is at the start of a new line, as every directive is on its own line, then
+// writes the whitespace before the directive
+func (p *printer) startLine(before string) {
+	if b := p.buf.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+		p.buf.WriteString("\n")
+	}
+	p.buf.WriteString(before)
+}
+
+// directiveText formats a directive, or just the opening tag for sections
+func directiveText(d Directive, section bool) string {
+	if d.Comment {
+		if d.Name == "" {
+			return "#"
+		}
+		return "# " + d.Name
+	}
+	s := strings.Join(append([]string{d.Name}, d.Parameters...), " ")
+	if section {
+		return "<" + s + ">"
+	}
+	return s
+}
@@ -0,0 +1,215 @@
+package apache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat_RoundTrip(t *testing.T) {
+	err := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		input, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		output, err := Parse(path, input)
+		if err != nil {
+			// not every test file is valid
+			return nil
+		}
+		t.Run(path, func(t *testing.T) {
+			if got := Format(toDirectiveSlice(output)); !bytes.Equal(got, input) {
+				t.Errorf("Format() mismatch\n got: %q\n want: %q", got, input)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormat_RoundTripInput(t *testing.T) {
+	inputs := []string{
+		"",
+		"Hello",
+		" Hello_World  \n ",
+		"Hello 'foo bar'   world\r\nfoo bar\r\n",
+		"Hello \\\n  world",
+		"<VirtualHost *:80>\n</VirtualHost>",
+		" <IfModule  mod_ssl.c > \n\t<VirtualHost *:443>\n\t\tSSLEngine on\n\t</VirtualHost>  \n</ifmodule >\n",
+		"# hello world",
+		" # \r\n Hello world",
+		exampleConfig,
+	}
+	for _, input := range inputs {
+		output, err := Parse("", []byte(input))
+		if err != nil {
+			t.Fatalf("error parsing %q: %v", input, err)
+		}
+		if got := string(Format(toDirectiveSlice(output))); got != input {
+			t.Errorf("Format() got = %q, want %q", got, input)
+		}
+	}
+}
+
+func TestFormat_Edit(t *testing.T) {
+	input := `# example.com
+<VirtualHost *:80>
+	ServerName example.com
+	ServerAlias www.example.com \
+	            example.net
+
+	<Directory /var/www>
+		Require all granted
+	</Directory>
+</VirtualHost>
+`
+	tests := []struct {
+		name string
+		edit func(dirs []Directive) []Directive
+		want string
+	}{
+		{
+			name: "change parameters",
+			edit: func(dirs []Directive) []Directive {
+				dirs[1].Children[1].Parameters = []string{"www.example.com"}
+				return dirs
+			},
+			want: `# example.com
+<VirtualHost *:80>
+	ServerName example.com
+	ServerAlias www.example.com
+
+	<Directory /var/www>
+		Require all granted
+	</Directory>
+</VirtualHost>
+`,
+		},
+		{
+			name: "change section parameters",
+			edit: func(dirs []Directive) []Directive {
+				dirs[1].Parameters = []string{"*:443"}
+				return dirs
+			},
+			want: "# example.com\n<VirtualHost *:443>" + input[len("# example.com\n<VirtualHost *:80>"):],
+		},
+		{
+			name: "remove directive",
+			edit: func(dirs []Directive) []Directive {
+				dirs[1].Children = dirs[1].Children[:2]
+				return dirs
+			},
+			want: `# example.com
+<VirtualHost *:80>
+	ServerName example.com
+	ServerAlias www.example.com \
+	            example.net
+</VirtualHost>
+`,
+		},
+		{
+			name: "add directives",
+			edit: func(dirs []Directive) []Directive {
+				dir := &dirs[1].Children[2]
+				dir.Children = append(dir.Children, Directive{Name: "Options", Parameters: []string{"-Indexes"}})
+				dirs[1].Children = append(dirs[1].Children,
+					Directive{Blank: true},
+					Directive{Name: "IfModule", Parameters: []string{"mod_rewrite.c"}, Children: []Directive{
+						{Name: "RewriteEngine", Parameters: []string{"on"}},
+					}},
+				)
+				return dirs
+			},
+			want: `# example.com
+<VirtualHost *:80>
+	ServerName example.com
+	ServerAlias www.example.com \
+	            example.net
+
+	<Directory /var/www>
+		Require all granted
+		Options -Indexes
+	</Directory>
+
+	<IfModule mod_rewrite.c>
+	    RewriteEngine on
+	</IfModule>
+</VirtualHost>
+`,
+		},
+		{
+			name: "add section",
+			edit: func(dirs []Directive) []Directive {
+				return append(dirs, Directive{Name: "VirtualHost", Parameters: []string{"*:443"}, Children: []Directive{
+					{Name: "ServerName", Parameters: []string{"example.com"}},
+					{Name: "SSLCertificateFile", Parameters: []string{`"/etc/letsencrypt/live/example.com/fullchain.pem"`}},
+				}})
+			},
+			want: input + `<VirtualHost *:443>
+    ServerName example.com
+    SSLCertificateFile "/etc/letsencrypt/live/example.com/fullchain.pem"
+</VirtualHost>
+`,
+		},
+		{
+			name: "change comment",
+			edit: func(dirs []Directive) []Directive {
+				dirs[0].Name = "managed by certgot"
+				return dirs
+			},
+			want: "# managed by certgot" + input[len("# example.com"):],
+		},
+		{
+			name: "rename section",
+			edit: func(dirs []Directive) []Directive {
+				dirs[1].Children[2].Name = "DirectoryMatch"
+				return dirs
+			},
+			want: `# example.com
+<VirtualHost *:80>
+	ServerName example.com
+	ServerAlias www.example.com \
+	            example.net
+
+	<DirectoryMatch /var/www>
+		Require all granted
+	</DirectoryMatch>
+</VirtualHost>
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Parse("", []byte(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dirs := tt.edit(toDirectiveSlice(output))
+			var buf bytes.Buffer
+			if err := Print(&buf, dirs); err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Print() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_EditWithoutTrailingNewline(t *testing.T) {
+	output, err := Parse("", []byte("Listen 80"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := append(toDirectiveSlice(output), Directive{Name: "Listen", Parameters: []string{"443"}})
+	if got, want := string(Format(dirs)), "Listen 80\nListen 443\n"; got != want {
+		t.Errorf("Format() got = %q, want %q", got, want)
+	}
+}
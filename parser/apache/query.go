@@ -0,0 +1,216 @@
+package apache
+
+import (
+	"strings"
+)
+
+// Node is a directive in a config tree, linked to the section it's in
+// The directive points into the parsed directives, so changes to it are made in place. Nodes are only valid until
+// the slice of directives they point into is modified.
+type Node struct {
+	*Directive
+	Parent *Node
+
+	// siblings is the slice the directive is in, either the parent's children or a file
+	siblings *[]Directive
+	// children is the slice of a file for the root node of its tree, otherwise the directive's children are used
+	children *[]Directive
+}
+
+// NewRoot returns the root node of a file, which has an empty directive with the file's directives as children
+func NewRoot(f *File) *Node {
+	return &Node{Directive: &Directive{}, children: &f.Directives}
+}
+
+// Tree returns the root node of the config, whose children are the directives in the main file
+func (c *Config) Tree() *Node {
+	return NewRoot(c.Main)
+}
+
+// FindAll returns every directive in the config with the name, ignoring case
+func (c *Config) FindAll(name string) []*Node {
+	return c.Tree().FindAll(name)
+}
+
+// VirtualHosts returns every VirtualHost section in the loaded files, including any inside other sections such as
+// <IfModule>, in the order the files were loaded. The parent of a VirtualHost in an included file is the section it
+// is in within that file, not the section of the Include directive.
+func (c *Config) VirtualHosts() []*Node {
+	var vhosts []*Node
+	for _, f := range c.Files {
+		vhosts = append(vhosts, virtualHosts(&f.Directives, nil)...)
+	}
+	return vhosts
+}
+
+func virtualHosts(dirs *[]Directive, parent *Node) []*Node {
+	var vhosts []*Node
+	for i := range *dirs {
+		d := &(*dirs)[i]
+		if d.Comment || d.Blank || d.Children == nil {
+			continue
+		}
+		n := &Node{Directive: d, Parent: parent, siblings: dirs}
+		if strings.EqualFold(d.Name, "VirtualHost") {
+			vhosts = append(vhosts, n)
+			continue
+		}
+		vhosts = append(vhosts, virtualHosts(&d.Children, n)...)
+	}
+	return vhosts
+}
+
+func (n *Node) childDirectives() *[]Directive {
+	if n.children != nil {
+		return n.children
+	}
+	return &n.Directive.Children
+}
+
+// Children returns the directives in the section, excluding comments. The directives in any included files follow
+// the Include directive, with the section as their parent.
+func (n *Node) Children() []*Node {
+	return children(n.childDirectives(), n)
+}
+
+func children(dirs *[]Directive, parent *Node) []*Node {
+	var nodes []*Node
+	for i := range *dirs {
+		d := &(*dirs)[i]
+		if d.Comment || d.Blank {
+			continue
+		}
+		nodes = append(nodes, &Node{Directive: d, Parent: parent, siblings: dirs})
+		for _, f := range d.Includes {
+			nodes = append(nodes, children(&f.Directives, parent)...)
+		}
+	}
+	return nodes
+}
+
+// Child returns the first child directive with the name, ignoring case, or nil if there isn't one
+func (n *Node) Child(name string) *Node {
+	for _, c := range n.Children() {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// FindAll returns every descendant directive with the name, ignoring case, in the order they appear in the config
+func (n *Node) FindAll(name string) []*Node {
+	var nodes []*Node
+	for _, c := range n.Children() {
+		if strings.EqualFold(c.Name, name) {
+			nodes = append(nodes, c)
+		}
+		nodes = append(nodes, c.FindAll(name)...)
+	}
+	return nodes
+}
+
+// ServerNames returns the name in the ServerName directive, without any scheme or port, followed by the names in
+// any ServerAlias directives, all without quotes
+func (n *Node) ServerNames() []string {
+	var names []string
+	if c := n.Child("ServerName"); c != nil && len(c.Parameters) > 0 {
+		names = append(names, hostName(unquote(c.Parameters[0])))
+	}
+	for _, c := range n.Children() {
+		if !strings.EqualFold(c.Name, "ServerAlias") {
+			continue
+		}
+		for _, param := range c.Parameters {
+			names = append(names, unquote(param))
+		}
+	}
+	return names
+}
+
+// hostName returns the host of a ServerName, which may be given as [scheme://]host[:port]
+func hostName(name string) string {
+	if idx := strings.Index(name, "://"); idx >= 0 {
+		name = name[idx+3:]
+	}
+	if strings.HasPrefix(name, "[") {
+		if end := strings.IndexByte(name, ']'); end >= 0 {
+			return name[:end+1]
+		}
+		return name
+	}
+	if idx := strings.LastIndexByte(name, ':'); idx >= 0 {
+		return name[:idx]
+	}
+	return name
+}
+
+// IsSSL returns whether the VirtualHost serves https, with an SSLEngine on directive in it or a file it includes
+func (n *Node) IsSSL() bool {
+	for _, c := range n.Children() {
+		if strings.EqualFold(c.Name, "SSLEngine") && len(c.Parameters) == 1 && strings.EqualFold(unquote(c.Parameters[0]), "on") {
+			return true
+		}
+	}
+	return false
+}
+
+// Address is a parsed address of a VirtualHost or a Listen directive
+type Address struct {
+	// Host is the ip address, host name, * or _default_. IPv6 addresses include the square brackets.
+	Host string
+	// Port is the port, * for any port, or empty if there isn't one
+	Port string
+}
+
+func (a Address) String() string {
+	if a.Port == "" {
+		return a.Host
+	}
+	if a.Host == "" {
+		return a.Port
+	}
+	return a.Host + ":" + a.Port
+}
+
+// ParseAddress parses an address of a VirtualHost, eg *:80, [::1]:443 or _default_, or a Listen directive, which may
+// just be a port
+func ParseAddress(addr string) Address {
+	addr = unquote(addr)
+	if strings.HasPrefix(addr, "[") {
+		end := strings.IndexByte(addr, ']')
+		if end >= 0 && strings.HasPrefix(addr[end+1:], ":") {
+			return Address{Host: addr[:end+1], Port: addr[end+2:]}
+		}
+		return Address{Host: addr}
+	}
+	idx := strings.LastIndexByte(addr, ':')
+	switch {
+	case idx >= 0:
+		return Address{Host: addr[:idx], Port: addr[idx+1:]}
+	case isDigits(addr):
+		return Address{Port: addr}
+	}
+	return Address{Host: addr}
+}
+
+// Addresses returns the parsed addresses of the VirtualHost
+func (n *Node) Addresses() []Address {
+	var addrs []Address
+	for _, param := range n.Parameters {
+		addrs = append(addrs, ParseAddress(param))
+	}
+	return addrs
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
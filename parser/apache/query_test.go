@@ -0,0 +1,106 @@
+package apache
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestConfig_VirtualHosts(t *testing.T) {
+	tests := []struct {
+		root string
+		want []string
+	}{
+		{
+			root: filepath.Join("debian_apache_2_4", "apache2"),
+			want: []string{
+				"sites-enabled/000-default.conf:1",
+				"sites-enabled/example.com.conf:1",
+				"sites-enabled/example.net.conf:1",
+			},
+		},
+		{
+			root: filepath.Join("rhel_httpd_2_4", "httpd"),
+			want: []string{
+				"conf.d/example.org.conf:1",
+				"conf.d/ssl.conf:14",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.root, func(t *testing.T) {
+			root := filepath.Join("testdata", tt.root)
+			cfg, err := Load(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, vhost := range cfg.VirtualHosts() {
+				rel, _ := filepath.Rel(root, vhost.File)
+				got = append(got, filepath.ToSlash(rel)+":"+strconv.Itoa(vhost.Start.Line))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VirtualHosts() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_VirtualHost(t *testing.T) {
+	cfg, err := Load(filepath.Join("testdata", "rhel_httpd_2_4", "httpd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vhosts := cfg.VirtualHosts()
+
+	http := vhosts[0]
+	if got, want := http.ServerNames(), []string{"example.org", "www.example.org", "static.example.org"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ServerNames() got %v, want %v", got, want)
+	}
+	if got, want := http.Addresses(), []Address{{Host: "192.0.2.10", Port: "80"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Addresses() got %v, want %v", got, want)
+	}
+	if http.IsSSL() {
+		t.Errorf("IsSSL() got true for %s", http.Location())
+	}
+	if c := http.Child("ifmodule"); c == nil || c.Child("REWRITEENGINE") == nil {
+		t.Errorf("Child() didn't find directives ignoring case")
+	}
+
+	ssl := vhosts[1]
+	if len(ssl.ServerNames()) != 0 {
+		t.Errorf("ServerNames() got %v, want none", ssl.ServerNames())
+	}
+	if !ssl.IsSSL() {
+		t.Errorf("IsSSL() got false for %s", ssl.Location())
+	}
+	if got := len(cfg.FindAll("LoadModule")); got != 11 {
+		t.Errorf("FindAll() got %d LoadModule directives, want 11", got)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want Address
+	}{
+		{"*:80", Address{Host: "*", Port: "80"}},
+		{"_default_", Address{Host: "_default_"}},
+		{"_default_:443", Address{Host: "_default_", Port: "443"}},
+		{`"192.0.2.1:8080"`, Address{Host: "192.0.2.1", Port: "8080"}},
+		{"[::]:443", Address{Host: "[::]", Port: "443"}},
+		{"[2001:db8::1]", Address{Host: "[2001:db8::1]"}},
+		{"443", Address{Port: "443"}},
+		{"example.com:*", Address{Host: "example.com", Port: "*"}},
+	}
+	for _, tt := range tests {
+		got := ParseAddress(tt.addr)
+		if got != tt.want {
+			t.Errorf("ParseAddress(%q) got %+v, want %+v", tt.addr, got, tt.want)
+		}
+		if want := tt.addr; want[0] != '"' && got.String() != want {
+			t.Errorf("Address.String() got %q, want %q", got.String(), want)
+		}
+	}
+}
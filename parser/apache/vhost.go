@@ -0,0 +1,135 @@
+package apache
+
+import (
+	"sort"
+	"strings"
+)
+
+// MatchType is how a VirtualHost matched a domain, in order of preference
+type MatchType int
+
+const (
+	// MatchServerName is a ServerName equal to the domain
+	MatchServerName MatchType = iota
+	// MatchServerAlias is a ServerAlias equal to the domain
+	MatchServerAlias
+	// MatchWildcard is a ServerAlias with wildcards matching the domain, eg *.example.com or www?.example.com
+	MatchWildcard
+)
+
+func (t MatchType) String() string {
+	switch t {
+	case MatchServerName:
+		return "ServerName"
+	case MatchServerAlias:
+		return "ServerAlias"
+	case MatchWildcard:
+		return "wildcard ServerAlias"
+	}
+	return "unknown"
+}
+
+// VirtualHostMatch is a VirtualHost that serves a domain
+type VirtualHostMatch struct {
+	VirtualHost *Node
+	Type        MatchType
+	// Name is the server name or alias that matched
+	Name string
+	// Rank orders the matches, starting at 0 for the best. Matches with the same rank are equally good, so it's
+	// ambiguous which VirtualHost should be used.
+	Rank int
+}
+
+// MatchVirtualHosts returns the VirtualHosts in the config that serve the domain, as per MatchVirtualHosts
+func (c *Config) MatchVirtualHosts(domain string) []VirtualHostMatch {
+	return MatchVirtualHosts(c.VirtualHosts(), domain)
+}
+
+// MatchVirtualHosts returns the VirtualHosts with a ServerName or ServerAlias matching the domain, ignoring case,
+// ranked by a ServerName, then a ServerAlias, then the longest wildcard ServerAlias. Apache itself uses the first
+// matching VirtualHost for an address, so VirtualHosts matching equally well are left in the order of the config.
+// Each VirtualHost is only returned once, with its best match.
+func MatchVirtualHosts(vhosts []*Node, domain string) []VirtualHostMatch {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	var matches []VirtualHostMatch
+	for _, vhost := range vhosts {
+		var best *VirtualHostMatch
+		hasName := vhost.Child("ServerName") != nil
+		for i, name := range vhost.ServerNames() {
+			m, ok := matchName(name, domain, hasName && i == 0)
+			if !ok {
+				continue
+			}
+			if best == nil || m.better(*best) {
+				m.VirtualHost = vhost
+				best = &m
+			}
+		}
+		if best != nil {
+			matches = append(matches, *best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].better(matches[j])
+	})
+	for i := range matches {
+		if i > 0 {
+			matches[i].Rank = matches[i-1].Rank
+			if matches[i-1].better(matches[i]) {
+				matches[i].Rank++
+			}
+		}
+	}
+	return matches
+}
+
+// better returns whether the match is preferred over another match
+func (m VirtualHostMatch) better(o VirtualHostMatch) bool {
+	if m.Type != o.Type {
+		return m.Type < o.Type
+	}
+	if m.Type == MatchWildcard {
+		return len(m.Name) > len(o.Name)
+	}
+	return false
+}
+
+// matchName checks whether the ServerName, or a ServerAlias, matches the lower case domain
+func matchName(name, domain string, serverName bool) (VirtualHostMatch, bool) {
+	m := VirtualHostMatch{Name: name, Type: MatchServerAlias}
+	if serverName {
+		m.Type = MatchServerName
+	}
+	if !serverName && strings.ContainsAny(name, "*?") {
+		m.Type = MatchWildcard
+		return m, wildcardMatch(strings.ToLower(name), domain)
+	}
+	return m, strings.ToLower(name) == domain
+}
+
+// wildcardMatch matches the name against a pattern where * matches any characters, including dots, and ? matches
+// a single character, like apache does for ServerAlias
+func wildcardMatch(pattern, name string) bool {
+	for pattern != "" {
+		switch pattern[0] {
+		case '*':
+			for i := len(name); i >= 0; i-- {
+				if wildcardMatch(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if name == "" {
+				return false
+			}
+		default:
+			if name == "" || name[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return name == ""
+}
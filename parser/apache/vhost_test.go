@@ -0,0 +1,120 @@
+package apache
+
+import (
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestConfig_MatchVirtualHosts(t *testing.T) {
+	cfg, err := Load(filepath.Join("testdata", "debian_apache_2_4", "apache2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		domain string
+		want   []string // location, type and rank of each match
+	}{
+		{
+			domain: "example.com",
+			want:   []string{"example.com.conf:1 ServerName 0"},
+		},
+		{
+			domain: "WWW.Example.com.",
+			want:   []string{"example.com.conf:1 ServerAlias 0"},
+		},
+		{
+			domain: "foo.example.net",
+			want:   []string{"example.net.conf:1 wildcard ServerAlias 0"},
+		},
+		{
+			domain: "example.org",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			var got []string
+			for _, m := range cfg.MatchVirtualHosts(tt.domain) {
+				got = append(got, filepath.Base(m.VirtualHost.Location())+" "+m.Type.String()+" "+strconv.Itoa(m.Rank))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("MatchVirtualHosts() got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("MatchVirtualHosts() got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchVirtualHosts_Rank(t *testing.T) {
+	input := `<VirtualHost *:80>
+    ServerName www.example.com
+    ServerAlias *.example.com
+</VirtualHost>
+<VirtualHost *:80>
+    ServerName example.net
+    ServerAlias *.com
+</VirtualHost>
+<VirtualHost *:80>
+    ServerAlias www.example.com
+</VirtualHost>
+<VirtualHost *:443>
+    ServerName https://www.example.com:443
+</VirtualHost>
+<VirtualHost *:80>
+    ServerAlias w?w.example.com
+</VirtualHost>
+`
+	output, err := Parse("", []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &File{Directives: toDirectiveSlice(output)}
+	cfg := &Config{Main: f, Files: []*File{f}}
+
+	matches := cfg.MatchVirtualHosts("www.example.com")
+	want := []struct {
+		line int
+		typ  MatchType
+		name string
+		rank int
+	}{
+		{1, MatchServerName, "www.example.com", 0},
+		{12, MatchServerName, "www.example.com", 0},
+		{9, MatchServerAlias, "www.example.com", 1},
+		{15, MatchWildcard, "w?w.example.com", 2},
+		{5, MatchWildcard, "*.com", 3},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("MatchVirtualHosts() got %d matches, want %d", len(matches), len(want))
+	}
+	for i, w := range want {
+		m := matches[i]
+		if m.VirtualHost.Start.Line != w.line || m.Type != w.typ || m.Name != w.name || m.Rank != w.rank {
+			t.Errorf("match %d got line %d, %s %s, rank %d, want %+v", i, m.VirtualHost.Start.Line, m.Type, m.Name, m.Rank, w)
+		}
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"www?.example.com", "www1.example.com", true},
+		{"www?.example.com", "www.example.com", false},
+		{"*", "anything", true},
+		{"www.*", "www.example.com", true},
+	}
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("wildcardMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package nginx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MainFile is the name of the main config file in the nginx server root
+const MainFile = "nginx.conf"
+
+// Config is an nginx config loaded from a server root, made up of the main config file and every file it includes
+type Config struct {
+	// Root is the directory the config was loaded from
+	Root string
+	// Prefix is the config prefix used by nginx, which relative include paths are resolved from
+	Prefix string
+	// Main is the main config file
+	Main *File
+	// Files are all the loaded files, in the order they were first included, starting with the main file
+	Files []*File
+}
+
+// File is a single parsed config file
+type File struct {
+	Path       string
+	Directives []Directive
+}
+
+// Load loads the main config file in the server root, and every file it includes
+func Load(root string) (*Config, error) {
+	return LoadPrefix(root, root)
+}
+
+// LoadPrefix loads the main config file in root, and every file it includes, where nginx itself uses prefix as its
+// config prefix, eg /etc/nginx. Absolute include paths inside the prefix are loaded from root instead, so a copy of
+// a config can be loaded from another directory.
+func LoadPrefix(root, prefix string) (*Config, error) {
+	cfg := &Config{
+		Root:   filepath.Clean(root),
+		Prefix: filepath.Clean(prefix),
+	}
+	l := &loader{
+		cfg:     cfg,
+		files:   map[string]*File{},
+		loading: map[string]bool{},
+	}
+	main, err := l.load(filepath.Join(cfg.Root, MainFile))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Main = main
+	return cfg, nil
+}
+
+// File returns the loaded file with the path, or nil if it wasn't loaded
+func (c *Config) File(path string) *File {
+	path = filepath.Clean(path)
+	for _, f := range c.Files {
+		if f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+type loader struct {
+	cfg   *Config
+	files map[string]*File
+	// loading are the files currently being loaded, to detect include cycles
+	loading map[string]bool
+}
+
+// load parses the file and resolves its includes, a file that is included more than once is only loaded once
+func (l *loader) load(path string) (*File, error) {
+	if f, ok := l.files[path]; ok {
+		return f, nil
+	}
+	dirs, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{
+		Path:       path,
+		Directives: dirs,
+	}
+	l.files[path] = f
+	l.cfg.Files = append(l.cfg.Files, f)
+
+	l.loading[path] = true
+	defer delete(l.loading, path)
+	if err := l.includes(f.Directives); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// includes loads the files matched by any include directives, setting the included files on the directive
+func (l *loader) includes(dirs []Directive) error {
+	for i := range dirs {
+		d := &dirs[i]
+		if d.Comment {
+			continue
+		}
+		if d.Name != "include" {
+			if err := l.includes(d.Children); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(d.Parameters) != 1 {
+			return d.Errorf("invalid number of arguments in include directive")
+		}
+
+		paths, err := l.resolve(unquote(d.Parameters[0]))
+		if err != nil {
+			return d.Errorf("%v", err)
+		}
+		d.Includes = nil
+		for _, path := range paths {
+			if l.loading[path] {
+				return d.Errorf("include cycle, %s is already being loaded", path)
+			}
+			f, err := l.load(path)
+			if err != nil {
+				return err
+			}
+			d.Includes = append(d.Includes, f)
+		}
+	}
+	return nil
+}
+
+// resolve returns the files an include parameter refers to, expanding any glob pattern like nginx
+func (l *loader) resolve(include string) ([]string, error) {
+	path := filepath.FromSlash(include)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(l.cfg.Prefix, path)
+	}
+	// load files from the root instead of where nginx sees them
+	if rel, err := filepath.Rel(l.cfg.Prefix, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		path = filepath.Join(l.cfg.Root, rel)
+	}
+
+	if !strings.ContainsAny(path, "*?[") {
+		if _, err := os.Stat(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("included file %s does not exist", path)
+			}
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include pattern %s: %v", include, err)
+	}
+	hidden := strings.HasPrefix(filepath.Base(path), ".")
+	var paths []string
+	for _, m := range matches {
+		// like glob(3), wildcards don't match hidden files
+		if !hidden && strings.HasPrefix(filepath.Base(m), ".") {
+			continue
+		}
+		if fi, err := os.Stat(m); err != nil || fi.IsDir() {
+			continue
+		}
+		paths = append(paths, m)
+	}
+	return paths, nil
+}
+
+// unquote removes any quotes around a parameter
+func unquote(param string) string {
+	if len(param) >= 2 && (param[0] == '"' || param[0] == '\'') && param[len(param)-1] == param[0] {
+		return param[1 : len(param)-1]
+	}
+	return param
+}
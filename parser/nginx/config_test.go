@@ -0,0 +1,182 @@
+package nginx
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load("testdata")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var got []string
+	for _, f := range cfg.Files {
+		got = append(got, filepath.ToSlash(f.Path))
+	}
+	want := []string{
+		"testdata/nginx.conf",
+		"testdata/foo.conf",
+		"testdata/mime.types",
+		"testdata/sites-enabled/default",
+		"testdata/sites-enabled/example.com",
+		"testdata/sites-enabled/example.net",
+		"testdata/sites-enabled/globalssl.com",
+		"testdata/sites-enabled/headers.com",
+		"testdata/sites-enabled/ipv6.com",
+		"testdata/sites-enabled/ipv6ssl.com",
+		"testdata/sites-enabled/migration.com",
+		"testdata/sites-enabled/sslon.com",
+		"testdata/server.conf",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Load() files got = %v, want %v", got, want)
+	}
+	if cfg.Main != cfg.Files[0] {
+		t.Errorf("Load() main file is not the first file")
+	}
+
+	// every directive keeps the file it was loaded from
+	for _, f := range cfg.Files {
+		var check func(dirs []Directive)
+		check = func(dirs []Directive) {
+			for _, d := range dirs {
+				if d.File != f.Path {
+					t.Errorf("directive %s at %s has file %s, want %s", d.Name, d.Location(), d.File, f.Path)
+				}
+				check(d.Children)
+			}
+		}
+		check(f.Directives)
+	}
+
+	// the include directive points at the included files
+	var sitesInclude *Directive
+	for _, d := range cfg.Main.Directives {
+		for i, child := range d.Children {
+			if child.Name == "include" && child.Parameters[0] == "sites-enabled/*" {
+				sitesInclude = &d.Children[i]
+			}
+		}
+	}
+	if sitesInclude == nil {
+		t.Fatal("sites-enabled include not found")
+	}
+	if len(sitesInclude.Includes) != 9 || sitesInclude.Includes[0] != cfg.File(filepath.Join("testdata", "sites-enabled", "default")) {
+		t.Errorf("include directive has wrong files: %v", sitesInclude.Includes)
+	}
+}
+
+func TestLoadPrefix(t *testing.T) {
+	root := filepath.Join("testdata", "ubuntu_nginx_1_4_6", "default_vhost", "nginx")
+	cfg, err := LoadPrefix(root, "/etc/nginx")
+	if err != nil {
+		t.Fatalf("LoadPrefix() error = %v", err)
+	}
+	var got []string
+	for _, f := range cfg.Files {
+		got = append(got, filepath.Base(f.Path))
+	}
+	// conf.d and sites-enabled don't exist, so their globs don't match anything
+	if want := []string{"nginx.conf", "mime.types"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadPrefix() files got = %v, want %v", got, want)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "missing include",
+			files: map[string]string{
+				"nginx.conf": "events {}\ninclude missing.conf;",
+			},
+			wantErr: "nginx.conf:2: included file",
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"nginx.conf": "include a.conf;",
+				"a.conf":     "http {\n    include b/*.conf;\n}",
+				"b/b.conf":   "include a.conf;",
+			},
+			wantErr: "b.conf:1: include cycle",
+		},
+		{
+			name: "invalid include",
+			files: map[string]string{
+				"nginx.conf": "include a.conf b.conf;",
+			},
+			wantErr: "nginx.conf:1: invalid number of arguments",
+		},
+		{
+			name: "unparsable include",
+			files: map[string]string{
+				"nginx.conf": "include a.conf;",
+				"a.conf":     "broken",
+			},
+			wantErr: "a.conf:1:7",
+		},
+		{
+			name: "no main file",
+			files: map[string]string{
+				"a.conf": "",
+			},
+			wantErr: "nginx.conf",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+			_, err := Load(root)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_IncludedTwice(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"nginx.conf":      "http {\n    include common.conf;\n    server {\n        include common.conf;\n    }\n}\ninclude sites/*.conf;\n",
+		"common.conf":     "gzip on;\n",
+		"sites/a.conf":    "server {}\n",
+		"sites/.swp.conf": "broken",
+	}
+	writeFiles(t, root, files)
+	cfg, err := Load(root)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Files) != 3 {
+		t.Fatalf("Load() got %d files, want 3", len(cfg.Files))
+	}
+	http := cfg.Main.Directives[0]
+	if http.Children[0].Includes[0] != http.Children[1].Children[0].Includes[0] {
+		t.Errorf("file included twice was loaded twice")
+	}
+}
+
+// writeFiles writes the files, keyed by slash separated paths relative to root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
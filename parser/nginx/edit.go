@@ -0,0 +1,74 @@
+package nginx
+
+import (
+	"errors"
+)
+
+// Copy returns a deep copy of the directive, which prints the same as the original until it is modified
+func (d Directive) Copy() Directive {
+	c := d
+	c.Parameters = append([]string(nil), d.Parameters...)
+	if d.Children != nil {
+		c.Children = make([]Directive, len(d.Children))
+		for i, child := range d.Children {
+			c.Children[i] = child.Copy()
+		}
+	}
+	c.Includes = append([]*File(nil), d.Includes...)
+	return c
+}
+
+// AddChild appends the directive to the block, returning the node for it
+// Any other nodes for the children of the block are no longer valid, as the children may have moved.
+func (n *Node) AddChild(d Directive) *Node {
+	n.Directive.Children = append(n.Directive.Children, d)
+	return &Node{
+		Directive: &n.Directive.Children[len(n.Directive.Children)-1],
+		Parent:    n,
+		siblings:  &n.Directive.Children,
+	}
+}
+
+// InsertAfter inserts the directive after the node, in the same block or file, returning the node for it
+// Any other nodes for directives in the same block or file, including this node, are no longer valid.
+func (n *Node) InsertAfter(d Directive) (*Node, error) {
+	idx, err := n.index()
+	if err != nil {
+		return nil, err
+	}
+	dirs := *n.siblings
+	dirs = append(dirs, Directive{})
+	copy(dirs[idx+2:], dirs[idx+1:])
+	dirs[idx+1] = d
+	*n.siblings = dirs
+	return &Node{
+		Directive: &dirs[idx+1],
+		Parent:    n.Parent,
+		siblings:  n.siblings,
+	}, nil
+}
+
+// Remove removes the directive from the block or file it's in
+// Any other nodes for directives in the same block or file, including this node, are no longer valid.
+func (n *Node) Remove() error {
+	idx, err := n.index()
+	if err != nil {
+		return err
+	}
+	dirs := *n.siblings
+	*n.siblings = append(dirs[:idx:idx], dirs[idx+1:]...)
+	return nil
+}
+
+// index returns the index of the directive in the slice it's in
+func (n *Node) index() (int, error) {
+	if n.siblings == nil {
+		return 0, errors.New("directive is not in a block or file")
+	}
+	for i := range *n.siblings {
+		if &(*n.siblings)[i] == n.Directive {
+			return i, nil
+		}
+	}
+	return 0, errors.New("directive has been moved or removed")
+}
@@ -0,0 +1,65 @@
+package nginx
+
+import (
+	"testing"
+)
+
+func TestNode_Edit(t *testing.T) {
+	input := `http {
+    # site
+    server {
+        listen 80;
+        server_name example.com;
+        ssl_certificate old.pem;
+    }
+}
+`
+	output, err := Parse("", []byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := NewRoot(toDirectiveSlice(output))
+	server := root.FindAll("server")[0]
+
+	copied := server.Directive.Copy()
+	copied.Children[0].Parameters = []string{"443", "ssl"}
+	ssl, err := server.InsertAfter(copied)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ssl.Child("ssl_certificate").Remove(); err != nil {
+		t.Fatal(err)
+	}
+	ssl.AddChild(Directive{Name: "ssl_certificate", Parameters: []string{"new.pem"}})
+
+	want := `http {
+    # site
+    server {
+        listen 80;
+        server_name example.com;
+        ssl_certificate old.pem;
+    }
+    server {
+        listen 443 ssl;
+        server_name example.com;
+        ssl_certificate new.pem;
+    }
+}
+`
+	if got := string(Format(root.Directive.Children)); got != want {
+		t.Errorf("Format() got = %q, want %q", got, want)
+	}
+
+	if err := ssl.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(Format(root.Directive.Children)); got != input {
+		t.Errorf("Format() after Remove() got = %q, want %q", got, input)
+	}
+	if err := ssl.Remove(); err == nil {
+		t.Errorf("Remove() of a removed directive did not error")
+	}
+	if _, err := root.InsertAfter(Directive{Name: "events"}); err == nil {
+		t.Errorf("InsertAfter() the root did not error")
+	}
+}
@@ -37,8 +37,15 @@ var g = &grammar{
 								name: "Directives",
 							},
 						},
+						&zeroOrMoreExpr{
+							pos: position{line: 5, col: 32, offset: 50},
+							expr: &ruleRefExpr{
+								pos:  position{line: 5, col: 32, offset: 50},
+								name: "whitespace",
+							},
+						},
 						&ruleRefExpr{
-							pos:  position{line: 5, col: 32, offset: 50},
+							pos:  position{line: 5, col: 44, offset: 62},
 							name: "EOF",
 						},
 					},
@@ -671,7 +678,7 @@ var g = &grammar{
 }
 
 func (c *current) onMainDirective1(val interface{}) (interface{}, error) {
-	return val, nil
+	return formatDirectives(val, c.text), nil
 }
 
 func (p *parser) callonMainDirective1() (interface{}, error) {
@@ -694,6 +701,7 @@ func (c *current) onCommentDirective1(comment interface{}) (interface{}, error)
 	return Directive{
 		Name:    (strings.TrimSpace(comment.(string))),
 		Comment: true,
+		format:  newFormat(c.pos.offset, c.text),
 	}, nil
 }
 
@@ -708,6 +716,7 @@ func (c *current) onBlockDirective1(name, param, dirs interface{}) (interface{},
 		Name:       toString(name),
 		Parameters: toStringSlice(param),
 		Children:   toDirectiveSlice(dirs),
+		format:     newFormat(c.pos.offset, c.text),
 	}, nil
 }
 
@@ -721,6 +730,7 @@ func (c *current) onSimpleDirective1(name, param interface{}) (interface{}, erro
 	return Directive{
 		Name:       toString(name),
 		Parameters: toStringSlice(param),
+		format:     newFormat(c.pos.offset, c.text),
 	}, nil
 }
 
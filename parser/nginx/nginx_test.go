@@ -207,7 +207,7 @@ func TestParse(t *testing.T) {
 			t.Fatalf("test %q: expects directive %t, got: %t", currentTest.testName, currentTest.expectsDir, ok)
 		}
 		if currentTest.equalCheck != nil {
-			if !reflect.DeepEqual(directives, currentTest.equalCheck) {
+			if !reflect.DeepEqual(withoutSource(directives), currentTest.equalCheck) {
 				t.Fatalf("test %q: directive mismatch\n expects: %+v\n got: %+v",
 					currentTest.testName, currentTest.equalCheck, directives)
 			}
@@ -394,10 +394,39 @@ func TestParseFile(t *testing.T) {
 				currentTest.fileName, currentTest.expectsDir, len(directives), directives)
 		}
 		if currentTest.equalCheck != nil {
-			if !reflect.DeepEqual(directives, currentTest.equalCheck) {
+			if !reflect.DeepEqual(withoutSource(directives), currentTest.equalCheck) {
 				t.Fatalf("test %q: directive mismatch\n expects: %#v\n got: %#v",
 					currentTest.fileName, currentTest.equalCheck, directives)
 			}
 		}
 	}
 }
+
+// withoutSource removes the original formatting and positions from parsed directives, so they can be compared to
+// expected values
+func withoutSource(v interface{}) interface{} {
+	switch dirs := v.(type) {
+	case []interface{}:
+		var out []interface{}
+		for _, d := range dirs {
+			out = append(out, withoutSource(d))
+		}
+		return out
+	case []Directive:
+		var out []Directive
+		for _, d := range dirs {
+			out = append(out, withoutSource(d).(Directive))
+		}
+		return out
+	case Directive:
+		dirs.format = nil
+		dirs.File = ""
+		dirs.Start = Position{}
+		dirs.End = Position{}
+		if dirs.Children != nil {
+			dirs.Children = withoutSource(dirs.Children).([]Directive)
+		}
+		return dirs
+	}
+	return v
+}
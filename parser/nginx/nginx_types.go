@@ -2,12 +2,72 @@
 
 package nginx
 
+import (
+	"fmt"
+	"strconv"
+)
+
 type Directive struct {
 	Name       string
 	Parameters []string
 	Comment    bool
 	Blank      bool
 	Children   []Directive
+
+	// File is the path of the file the directive was parsed from, if read from a file
+	File string
+	// Start and End are the positions of the first character and just after the last character of the directive,
+	// excluding any surrounding whitespace. Both are zero for directives that weren't parsed.
+	Start Position
+	End   Position
+
+	// Includes are the files matched by an include directive, set when loaded with Load
+	Includes []*File
+
+	// format is the original text of a parsed directive, used to print it back unchanged
+	format *directiveFormat
+}
+
+// Position is a location in a config file
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // starting at 1
+	Column int // in characters, starting at 1
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Location returns the file and line the directive starts at, eg sites-enabled/default:12
+func (d Directive) Location() string {
+	if d.File == "" {
+		return strconv.Itoa(d.Start.Line)
+	}
+	return d.File + ":" + strconv.Itoa(d.Start.Line)
+}
+
+// Errorf returns an error prefixed with the location of the directive
+func (d Directive) Errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("%s: %s", d.Location(), fmt.Sprintf(format, a...))
+}
+
+// ReadFile parses the file, setting the file of every directive
+func ReadFile(filename string) ([]Directive, error) {
+	v, err := ParseFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	dirs := toDirectiveSlice(v)
+	setFile(dirs, filename)
+	return dirs, nil
+}
+
+func setFile(dirs []Directive, filename string) {
+	for i := range dirs {
+		dirs[i].File = filename
+		setFile(dirs[i].Children, filename)
+	}
 }
 
 func toString(v interface{}) string {
@@ -1 +1,91 @@
 package nginx
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile_Positions(t *testing.T) {
+	fileName := filepath.Join("testdata", "edge_cases.conf")
+	dirs, err := ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		dir      Directive
+		start    Position
+		end      Position
+		location string
+	}{
+		{
+			name:     "comment",
+			dir:      dirs[0],
+			start:    Position{Offset: 0, Line: 1, Column: 1},
+			end:      Position{Offset: 85, Line: 1, Column: 86},
+			location: fileName + ":1",
+		},
+		{
+			name:     "block",
+			dir:      dirs[1],
+			start:    Position{Offset: 87, Line: 3, Column: 1},
+			end:      Position{Offset: 119, Line: 5, Column: 2},
+			location: fileName + ":3",
+		},
+		{
+			name:     "child",
+			dir:      dirs[1].Children[0],
+			start:    Position{Offset: 98, Line: 4, Column: 3},
+			end:      Position{Offset: 117, Line: 4, Column: 22},
+			location: fileName + ":4",
+		},
+		{
+			name:     "nested",
+			dir:      dirs[2].Children[1].Children[0],
+			start:    Position{Offset: 191, Line: 10, Column: 9},
+			end:      Position{Offset: 297, Line: 12, Column: 6},
+			location: fileName + ":10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.dir.File != fileName {
+				t.Errorf("File got = %q, want %q", tt.dir.File, fileName)
+			}
+			if tt.dir.Start != tt.start {
+				t.Errorf("Start got = %#v, want %#v", tt.dir.Start, tt.start)
+			}
+			if tt.dir.End != tt.end {
+				t.Errorf("End got = %#v, want %#v", tt.dir.End, tt.end)
+			}
+			if got := tt.dir.Location(); got != tt.location {
+				t.Errorf("Location() got = %q, want %q", got, tt.location)
+			}
+		})
+	}
+
+	err = dirs[1].Errorf("server block has no %s", "listen")
+	if want := fileName + ":3: server block has no listen"; err.Error() != want {
+		t.Errorf("Errorf() got = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestParse_PositionColumns(t *testing.T) {
+	output, err := Parse("", []byte("# héllo wörld\nfoo 'bär'; bar;"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := toDirectiveSlice(output)
+	if len(dirs) != 3 {
+		t.Fatalf("expected 3 directives, got %d", len(dirs))
+	}
+	// columns count characters, offsets count bytes
+	want := Position{Offset: 28, Line: 2, Column: 12}
+	if dirs[2].Start != want {
+		t.Errorf("Start got = %#v, want %#v", dirs[2].Start, want)
+	}
+	if got := dirs[2].Location(); got != "2" {
+		t.Errorf("Location() got = %q, want %q", got, "2")
+	}
+}
@@ -0,0 +1,178 @@
+package nginx
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormat_RoundTrip(t *testing.T) {
+	err := filepath.Walk("testdata", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		input, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		output, err := Parse(path, input)
+		if err != nil {
+			// not every test file is valid
+			return nil
+		}
+		t.Run(path, func(t *testing.T) {
+			if got := Format(toDirectiveSlice(output)); !bytes.Equal(got, input) {
+				t.Errorf("Format() mismatch\n got: %q\n want: %q", got, input)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFormat_RoundTripInput(t *testing.T) {
+	inputs := []string{
+		"",
+		"hello;",
+		" hello_world ; ",
+		"hello 'foo bar'   world;\r\nfoo bar;",
+		"hello world { location { foo bar; } }",
+		"hello {}",
+		" hello { } ",
+		"# hello world",
+		" # \r\n hello world;",
+		exampleConfig,
+	}
+	for _, input := range inputs {
+		output, err := Parse("", []byte(input))
+		if err != nil {
+			t.Fatalf("error parsing %q: %v", input, err)
+		}
+		if got := string(Format(toDirectiveSlice(output))); got != input {
+			t.Errorf("Format() got = %q, want %q", got, input)
+		}
+	}
+}
+
+func TestFormat_Edit(t *testing.T) {
+	input := `# server
+server {
+    listen 80;   # plain http
+    server_name  example.com;
+
+    location / {
+        root /var/www;
+    }
+}
+`
+	tests := []struct {
+		name string
+		edit func(dirs []Directive) []Directive
+		want string
+	}{
+		{
+			name: "change parameters",
+			edit: func(dirs []Directive) []Directive {
+				dirs[1].Children[0].Parameters = []string{"443", "ssl"}
+				return dirs
+			},
+			want: `# server
+server {
+    listen 443 ssl;   # plain http
+    server_name  example.com;
+
+    location / {
+        root /var/www;
+    }
+}
+`,
+		},
+		{
+			name: "remove directive",
+			edit: func(dirs []Directive) []Directive {
+				dirs[1].Children = append(dirs[1].Children[:2], dirs[1].Children[3:]...)
+				return dirs
+			},
+			want: `# server
+server {
+    listen 80;   # plain http
+
+    location / {
+        root /var/www;
+    }
+}
+`,
+		},
+		{
+			name: "add directives",
+			edit: func(dirs []Directive) []Directive {
+				loc := &dirs[1].Children[3]
+				loc.Children = append(loc.Children, Directive{Name: "index", Parameters: []string{"index.html"}})
+				dirs[1].Children = append(dirs[1].Children,
+					Directive{Blank: true},
+					Directive{Name: "location", Parameters: []string{"/api"}, Children: []Directive{
+						{Name: "proxy_pass", Parameters: []string{"http://127.0.0.1:8080"}},
+					}},
+				)
+				return dirs
+			},
+			want: `# server
+server {
+    listen 80;   # plain http
+    server_name  example.com;
+
+    location / {
+        root /var/www;
+        index index.html;
+    }
+
+    location /api {
+        proxy_pass http://127.0.0.1:8080;
+    }
+}
+`,
+		},
+		{
+			name: "add block",
+			edit: func(dirs []Directive) []Directive {
+				return append(dirs, Directive{Name: "server", Children: []Directive{
+					{Name: "listen", Parameters: []string{"443", "ssl"}},
+					{Name: "ssl_certificate", Parameters: []string{`"/etc/letsencrypt/live/example.com/fullchain.pem"`}},
+				}})
+			},
+			want: input + `server {
+    listen 443 ssl;
+    ssl_certificate "/etc/letsencrypt/live/example.com/fullchain.pem";
+}
+`,
+		},
+		{
+			name: "change comment",
+			edit: func(dirs []Directive) []Directive {
+				dirs[0].Name = "managed server"
+				return dirs
+			},
+			want: "# managed server" + input[len("# server"):],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Parse("", []byte(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dirs := tt.edit(toDirectiveSlice(output))
+			var buf bytes.Buffer
+			if err := Print(&buf, dirs); err != nil {
+				t.Fatalf("Print() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Print() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
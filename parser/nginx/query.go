@@ -0,0 +1,354 @@
+package nginx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Node is a directive in a config tree, linked to the block it's in
+// The directive points into the parsed directives, so changes to it are made in place. Nodes are only valid until
+// the slice of directives they point into is modified.
+type Node struct {
+	*Directive
+	Parent *Node
+
+	// siblings is the slice the directive is in, either the parent's children or an included file
+	siblings *[]Directive
+}
+
+// NewRoot returns the root node of a tree of directives, which has an empty directive with the directives as children
+func NewRoot(dirs []Directive) *Node {
+	return &Node{Directive: &Directive{Children: dirs}}
+}
+
+// Tree returns the root node of the config, whose children are the directives in the main file
+func (c *Config) Tree() *Node {
+	return NewRoot(c.Main.Directives)
+}
+
+// Select returns the directives in the config matching the path, as per Node.Select
+func (c *Config) Select(path string) ([]*Node, error) {
+	return c.Tree().Select(path)
+}
+
+// FindAll returns every directive in the config with the name
+func (c *Config) FindAll(name string) []*Node {
+	return c.Tree().FindAll(name)
+}
+
+// ServerBlocks returns every server block in the http block
+func (c *Config) ServerBlocks() []*Node {
+	return c.Tree().query([]selector{{name: "http"}, {name: "server"}})
+}
+
+// Children returns the directives in the block, excluding comments. The directives in any included files follow
+// the include directive, with the block as their parent.
+func (n *Node) Children() []*Node {
+	return children(&n.Directive.Children, n)
+}
+
+func children(dirs *[]Directive, parent *Node) []*Node {
+	var nodes []*Node
+	for i := range *dirs {
+		d := &(*dirs)[i]
+		if d.Comment || d.Blank {
+			continue
+		}
+		nodes = append(nodes, &Node{Directive: d, Parent: parent, siblings: dirs})
+		for _, f := range d.Includes {
+			nodes = append(nodes, children(&f.Directives, parent)...)
+		}
+	}
+	return nodes
+}
+
+// Child returns the first child directive with the name, or nil if there isn't one
+func (n *Node) Child(name string) *Node {
+	for _, c := range n.Children() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// FindAll returns every descendant directive with the name, in the order they appear in the config
+func (n *Node) FindAll(name string) []*Node {
+	var nodes []*Node
+	for _, c := range n.Children() {
+		if c.Name == name {
+			nodes = append(nodes, c)
+		}
+		nodes = append(nodes, c.FindAll(name)...)
+	}
+	return nodes
+}
+
+// Select returns the descendant directives matching the path, a list of directive names separated by slashes, eg
+// http/server/location. Each name may be * to match any directive, and may be followed by conditions on the
+// directive's children in square brackets:
+//
+//	[name]        has a child directive with the name
+//	[name=value]  has a child directive with the name and a parameter equal to the value
+//	[name~regexp] has a child directive with the name and a parameter matching the regular expression
+//
+// eg http/server[server_name~example.com]/location
+func (n *Node) Select(path string) ([]*Node, error) {
+	sels, err := parseSelectors(path)
+	if err != nil {
+		return nil, err
+	}
+	return n.query(sels), nil
+}
+
+func (n *Node) query(sels []selector) []*Node {
+	nodes := []*Node{n}
+	for _, sel := range sels {
+		var next []*Node
+		for _, node := range nodes {
+			for _, c := range node.Children() {
+				if sel.match(c) {
+					next = append(next, c)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+type selector struct {
+	name       string
+	conditions []condition
+}
+
+type condition struct {
+	name  string
+	value string
+	re    *regexp.Regexp
+}
+
+func (s selector) match(n *Node) bool {
+	if s.name != "*" && s.name != n.Name {
+		return false
+	}
+	for _, cond := range s.conditions {
+		if !cond.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) match(n *Node) bool {
+	for _, child := range n.Children() {
+		if child.Name != c.name {
+			continue
+		}
+		if c.value == "" && c.re == nil {
+			return true
+		}
+		for _, param := range child.Parameters {
+			param = unquote(param)
+			if (c.re != nil && c.re.MatchString(param)) || (c.re == nil && param == c.value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseSelectors(path string) ([]selector, error) {
+	var sels []selector
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		idx := strings.IndexByte(part, '[')
+		if idx < 0 {
+			idx = len(part)
+		}
+		sel := selector{name: part[:idx]}
+		if sel.name == "" {
+			return nil, fmt.Errorf("invalid path %q: missing directive name", path)
+		}
+		for rest := part[idx:]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("invalid path %q: bad condition %q", path, rest)
+			}
+			cond, err := parseCondition(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %v", path, err)
+			}
+			sel.conditions = append(sel.conditions, cond)
+			rest = rest[end+1:]
+		}
+		sels = append(sels, sel)
+	}
+	return sels, nil
+}
+
+func parseCondition(s string) (condition, error) {
+	idx := strings.IndexAny(s, "=~")
+	if idx < 0 {
+		return condition{name: s}, nil
+	}
+	cond := condition{name: s[:idx], value: s[idx+1:]}
+	if cond.name == "" {
+		return cond, fmt.Errorf("missing directive name in condition %q", s)
+	}
+	if s[idx] == '~' {
+		re, err := regexp.Compile(cond.value)
+		if err != nil {
+			return cond, err
+		}
+		cond.re = re
+	}
+	return cond, nil
+}
+
+// ServerNames returns the names in any server_name directives in the block, without quotes
+func (n *Node) ServerNames() []string {
+	var names []string
+	for _, c := range n.Children() {
+		if c.Name != "server_name" {
+			continue
+		}
+		for _, param := range c.Parameters {
+			names = append(names, unquote(param))
+		}
+	}
+	return names
+}
+
+// ListenAddresses returns the parsed listen directives in the block
+// A server block without any listen directives listens on port 80, as per nginx.
+func (n *Node) ListenAddresses() ([]Listen, error) {
+	var listens []Listen
+	for _, c := range n.Children() {
+		if c.Name != "listen" {
+			continue
+		}
+		l, err := ParseListen(c.Parameters)
+		if err != nil {
+			return nil, c.Errorf("%v", err)
+		}
+		listens = append(listens, l)
+	}
+	if len(listens) == 0 {
+		listens = append(listens, Listen{Port: defaultPort})
+	}
+	return listens, nil
+}
+
+// IsSSL returns whether the server block serves https, either with the ssl option on a listen directive or the
+// deprecated ssl on directive
+func (n *Node) IsSSL() bool {
+	for _, c := range n.Children() {
+		switch c.Name {
+		case "listen":
+			if l, err := ParseListen(c.Parameters); err == nil && l.SSL {
+				return true
+			}
+		case "ssl":
+			if len(c.Parameters) == 1 && unquote(c.Parameters[0]) == "on" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+const defaultPort = 80
+
+// Listen is a parsed listen directive
+type Listen struct {
+	// Address is the ip address, host name or unix socket (prefixed with unix:) to listen on, empty for all
+	// addresses. IPv6 addresses don't include the square brackets.
+	Address string
+	// Port is the port to listen on, 0 for unix sockets
+	Port int
+	// IPv6 is whether the address is an IPv6 address
+	IPv6 bool
+
+	SSL           bool
+	HTTP2         bool
+	DefaultServer bool
+	// Options are any other parameters, eg proxy_protocol or ipv6only=on
+	Options []string
+}
+
+// ParseListen parses the parameters of a listen directive
+func ParseListen(params []string) (Listen, error) {
+	var l Listen
+	if len(params) == 0 {
+		return l, fmt.Errorf("invalid number of arguments in listen directive")
+	}
+
+	addr := unquote(params[0])
+	port := ""
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		l.Address = addr
+	case strings.HasPrefix(addr, "["):
+		end := strings.IndexByte(addr, ']')
+		if end < 0 {
+			return l, fmt.Errorf("invalid listen address %q", addr)
+		}
+		l.Address = addr[1:end]
+		l.IPv6 = true
+		if rest := addr[end+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return l, fmt.Errorf("invalid listen address %q", addr)
+			}
+			port = rest[1:]
+		}
+	case strings.Contains(addr, ":"):
+		idx := strings.LastIndexByte(addr, ':')
+		l.Address, port = addr[:idx], addr[idx+1:]
+	case isDigits(addr):
+		port = addr
+	default:
+		l.Address = addr
+	}
+	if l.Address == "*" {
+		l.Address = ""
+	}
+
+	if !strings.HasPrefix(l.Address, "unix:") {
+		l.Port = defaultPort
+		if port != "" {
+			p, err := strconv.Atoi(port)
+			if err != nil || p < 1 || p > 65535 {
+				return l, fmt.Errorf("invalid port in listen address %q", addr)
+			}
+			l.Port = p
+		}
+	}
+
+	for _, param := range params[1:] {
+		switch param = unquote(param); param {
+		case "ssl":
+			l.SSL = true
+		case "http2":
+			l.HTTP2 = true
+		case "default_server", "default":
+			l.DefaultServer = true
+		default:
+			l.Options = append(l.Options, param)
+		}
+	}
+	return l, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
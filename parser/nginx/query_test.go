@@ -0,0 +1,207 @@
+package nginx
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_ServerBlocks(t *testing.T) {
+	cfg, err := Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers := cfg.ServerBlocks()
+	if len(servers) != 14 {
+		t.Fatalf("ServerBlocks() got %d servers, want 14", len(servers))
+	}
+
+	tests := []struct {
+		server  *Node
+		file    string
+		names   []string
+		listens []Listen
+		ssl     bool
+	}{
+		{
+			server:  servers[0],
+			file:    "foo.conf",
+			names:   []string{"*.www.foo.com", "*.www.example.com"},
+			listens: []Listen{{Port: 80, SSL: true, DefaultServer: true}},
+			ssl:     true,
+		},
+		{
+			server: servers[1],
+			file:   "default",
+			names:  []string{"www.example.org"},
+			listens: []Listen{
+				{Address: "myhost", Port: 80, DefaultServer: true},
+				{Address: "otherhost", Port: 80, DefaultServer: true},
+			},
+		},
+		{
+			server: servers[2],
+			file:   "example.com",
+			names:  []string{".example.com", "example.*"},
+			listens: []Listen{
+				{Address: "69.50.225.155", Port: 9000},
+				{Address: "127.0.0.1", Port: 80},
+			},
+		},
+		{
+			server:  servers[6],
+			file:    "headers.com",
+			names:   []string{"headers.com"},
+			listens: []Listen{{Port: 80}},
+		},
+		{
+			server: servers[8],
+			file:   "ipv6ssl.com",
+			names:  []string{"ipv6ssl.com"},
+			listens: []Listen{
+				{Port: 443, SSL: true},
+				{Address: "::", Port: 443, IPv6: true, SSL: true, Options: []string{"ipv6only=on"}},
+				{Port: 5001, SSL: true},
+				{Address: "::", Port: 5001, IPv6: true, SSL: true, Options: []string{"ipv6only=on"}},
+			},
+			ssl: true,
+		},
+		{
+			server:  servers[11],
+			file:    "sslon.com",
+			names:   []string{"sslon.com"},
+			listens: []Listen{{Port: 80}},
+			ssl:     true,
+		},
+		{
+			server: servers[13],
+			file:   "nginx.conf",
+			// server_name is in an included file
+			names: []string{"somename", "alias", "another.alias"},
+			listens: []Listen{
+				{Port: 8000},
+				{Address: "somename", Port: 8080},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := filepath.Base(tt.server.File); got != tt.file {
+				t.Errorf("File got = %s, want %s", got, tt.file)
+			}
+			if tt.server.Parent == nil || tt.server.Parent.Name != "http" {
+				t.Errorf("Parent is not the http block")
+			}
+			if got := tt.server.ServerNames(); !reflect.DeepEqual(got, tt.names) {
+				t.Errorf("ServerNames() got = %v, want %v", got, tt.names)
+			}
+			listens, err := tt.server.ListenAddresses()
+			if err != nil {
+				t.Fatalf("ListenAddresses() error = %v", err)
+			}
+			if !reflect.DeepEqual(listens, tt.listens) {
+				t.Errorf("ListenAddresses() got = %+v, want %+v", listens, tt.listens)
+			}
+			if got := tt.server.IsSSL(); got != tt.ssl {
+				t.Errorf("IsSSL() got = %v, want %v", got, tt.ssl)
+			}
+		})
+	}
+}
+
+func TestNode_Select(t *testing.T) {
+	cfg, err := Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    int
+		wantErr bool
+	}{
+		{path: "http", want: 2},
+		{path: "/http/server/", want: 14},
+		{path: "http/server[server_name=migration.com]", want: 2},
+		{path: "http/server[server_name=migration.com][listen]", want: 1},
+		{path: "http/server[server_name~^(www\\.)?example\\.]/location", want: 1},
+		{path: "http/server[server_name~example]", want: 4},
+		{path: "http/server[ssl=on]", want: 1},
+		{path: "*/server/location[fastcgi_pass]", want: 1},
+		{path: "http/server/missing", want: 0},
+		{path: "http//server", wantErr: true},
+		{path: "http/server[listen", wantErr: true},
+		{path: "http/server[~80]", wantErr: true},
+		{path: "http/server[listen~(]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := cfg.Select(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Select() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("Select() got %d directives, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_FindAll(t *testing.T) {
+	cfg, err := Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listens := cfg.FindAll("listen")
+	if len(listens) != 19 {
+		t.Errorf("FindAll() got %d directives, want 19", len(listens))
+	}
+	for _, l := range listens {
+		if l.Parent == nil || l.Parent.Name != "server" {
+			t.Errorf("listen directive at %s is not in a server block", l.Location())
+		}
+	}
+
+	// changes through a node are made to the loaded directives
+	server := cfg.ServerBlocks()[0]
+	server.Child("listen").Parameters = []string{"443", "ssl"}
+	if got := cfg.ServerBlocks()[0].Child("listen").Parameters; !reflect.DeepEqual(got, []string{"443", "ssl"}) {
+		t.Errorf("Parameters got = %v after change", got)
+	}
+	if server.Child("missing") != nil {
+		t.Errorf("Child() found a missing directive")
+	}
+}
+
+func TestParseListen(t *testing.T) {
+	tests := []struct {
+		params  []string
+		want    Listen
+		wantErr bool
+	}{
+		{params: []string{"8080"}, want: Listen{Port: 8080}},
+		{params: []string{"*:443", "ssl", "http2"}, want: Listen{Port: 443, SSL: true, HTTP2: true}},
+		{params: []string{"127.0.0.1"}, want: Listen{Address: "127.0.0.1", Port: 80}},
+		{params: []string{"localhost:8000", "default"}, want: Listen{Address: "localhost", Port: 8000, DefaultServer: true}},
+		{params: []string{"[::1]"}, want: Listen{Address: "::1", Port: 80, IPv6: true}},
+		{params: []string{"[::]:443", "ssl", "proxy_protocol"}, want: Listen{Address: "::", Port: 443, IPv6: true, SSL: true, Options: []string{"proxy_protocol"}}},
+		{params: []string{`"unix:/var/run/nginx.sock"`}, want: Listen{Address: "unix:/var/run/nginx.sock"}},
+		{params: nil, wantErr: true},
+		{params: []string{"[::1"}, wantErr: true},
+		{params: []string{"[::1]80"}, wantErr: true},
+		{params: []string{"host:http"}, wantErr: true},
+		{params: []string{"70000"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.params), func(t *testing.T) {
+			got, err := ParseListen(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseListen() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseListen() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package nginx
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MatchType is how a server block matched a domain, in the order of precedence nginx uses to pick a server
+type MatchType int
+
+const (
+	// MatchExact is a server name equal to the domain
+	MatchExact MatchType = iota
+	// MatchLeadingWildcard is a server name starting with an asterisk, eg *.example.com or .example.com
+	MatchLeadingWildcard
+	// MatchTrailingWildcard is a server name ending with an asterisk, eg www.example.*
+	MatchTrailingWildcard
+	// MatchRegex is a server name that is a regular expression, eg ~^www\.example\.com$
+	MatchRegex
+	// MatchDefault is a server block that is the default_server for any of its listen addresses
+	MatchDefault
+)
+
+func (t MatchType) String() string {
+	switch t {
+	case MatchExact:
+		return "exact"
+	case MatchLeadingWildcard:
+		return "leading wildcard"
+	case MatchTrailingWildcard:
+		return "trailing wildcard"
+	case MatchRegex:
+		return "regex"
+	case MatchDefault:
+		return "default server"
+	}
+	return "unknown"
+}
+
+// ServerMatch is a server block that could serve a domain
+type ServerMatch struct {
+	Server *Node
+	Type   MatchType
+	// Name is the server name that matched, empty for default servers
+	Name string
+	// Rank orders the matches, starting at 0 for the best. Matches with the same rank are equally good, so it's
+	// ambiguous which server should be used.
+	Rank int
+
+	// index is the order of the server in the config, regexes are checked in this order
+	index int
+}
+
+// MatchServers returns the server blocks in the config that could serve the domain, as per MatchServers
+func (c *Config) MatchServers(domain string) []ServerMatch {
+	return MatchServers(c.ServerBlocks(), domain)
+}
+
+// MatchServers returns the server blocks that could serve the domain, ranked in the order nginx uses: an exact name,
+// then the longest wildcard name starting with an asterisk, then the longest wildcard name ending with an asterisk,
+// then the first matching regular expression, and lastly any default servers.
+// Each server block is only returned once, with its best match.
+func MatchServers(servers []*Node, domain string) []ServerMatch {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	var matches []ServerMatch
+	for i, server := range servers {
+		var best *ServerMatch
+		for _, name := range server.ServerNames() {
+			m, ok := matchServerName(name, domain)
+			if !ok {
+				continue
+			}
+			if best == nil || m.better(*best) {
+				m.Server = server
+				m.index = i
+				best = &m
+			}
+		}
+		if best == nil && isDefaultServer(server) {
+			best = &ServerMatch{Server: server, Type: MatchDefault, index: i}
+		}
+		if best != nil {
+			matches = append(matches, *best)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].better(matches[j])
+	})
+	for i := range matches {
+		if i > 0 {
+			matches[i].Rank = matches[i-1].Rank
+			if matches[i-1].better(matches[i]) {
+				matches[i].Rank++
+			}
+		}
+	}
+	return matches
+}
+
+// better returns whether the match takes precedence over another match
+func (m ServerMatch) better(o ServerMatch) bool {
+	if m.Type != o.Type {
+		return m.Type < o.Type
+	}
+	switch m.Type {
+	case MatchLeadingWildcard, MatchTrailingWildcard:
+		return len(m.Name) > len(o.Name)
+	case MatchRegex:
+		return m.index < o.index
+	}
+	return false
+}
+
+// matchServerName checks whether the server name matches the lower case domain
+func matchServerName(name, domain string) (ServerMatch, bool) {
+	m := ServerMatch{Name: name}
+	switch {
+	case strings.HasPrefix(name, "~"):
+		re, err := regexp.Compile(name[1:])
+		if err != nil {
+			// not every pcre regex is valid in go, these can't be matched
+			return m, false
+		}
+		m.Type = MatchRegex
+		return m, re.MatchString(domain)
+	case strings.HasPrefix(name, "*."):
+		m.Type = MatchLeadingWildcard
+		suffix := strings.ToLower(name[1:])
+		return m, len(domain) > len(suffix) && strings.HasSuffix(domain, suffix)
+	case strings.HasPrefix(name, "."):
+		// .example.com matches both example.com and *.example.com
+		m.Type = MatchLeadingWildcard
+		suffix := strings.ToLower(name)
+		return m, domain == suffix[1:] || strings.HasSuffix(domain, suffix)
+	case strings.HasSuffix(name, ".*"):
+		m.Type = MatchTrailingWildcard
+		prefix := strings.ToLower(name[:len(name)-1])
+		return m, len(domain) > len(prefix) && strings.HasPrefix(domain, prefix)
+	case strings.Contains(name, "*"):
+		// nginx only allows asterisks at the start or end of a name
+		return m, false
+	}
+	m.Type = MatchExact
+	return m, strings.ToLower(name) == domain
+}
+
+// isDefaultServer returns whether any of the server's listen directives are marked default_server
+func isDefaultServer(server *Node) bool {
+	listens, err := server.ListenAddresses()
+	if err != nil {
+		return false
+	}
+	for _, l := range listens {
+		if l.DefaultServer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package nginx
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_MatchServers(t *testing.T) {
+	cfg, err := Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type match struct {
+		file string
+		typ  MatchType
+		name string
+		rank int
+	}
+	tests := []struct {
+		domain string
+		want   []match
+	}{
+		{
+			domain: "migration.com",
+			want: []match{
+				{"migration.com", MatchExact, "migration.com", 0},
+				{"migration.com", MatchExact, "migration.com", 0},
+				{"foo.conf", MatchDefault, "", 1},
+				{"default", MatchDefault, "", 1},
+			},
+		},
+		{
+			domain: "WWW.Example.Org.",
+			want: []match{
+				{"default", MatchExact, "www.example.org", 0},
+				{"nginx.conf", MatchRegex, `~^(www\.)?(example|bar)\.`, 1},
+				{"foo.conf", MatchDefault, "", 2},
+			},
+		},
+		{
+			domain: "www.example.com",
+			want: []match{
+				{"example.com", MatchLeadingWildcard, ".example.com", 0},
+				{"nginx.conf", MatchRegex, `~^(www\.)?(example|bar)\.`, 1},
+				{"foo.conf", MatchDefault, "", 2},
+				{"default", MatchDefault, "", 2},
+			},
+		},
+		{
+			// the longest leading wildcard wins
+			domain: "a.www.example.com",
+			want: []match{
+				{"foo.conf", MatchLeadingWildcard, "*.www.example.com", 0},
+				{"example.com", MatchLeadingWildcard, ".example.com", 1},
+				{"default", MatchDefault, "", 2},
+			},
+		},
+		{
+			domain: "example.co.uk",
+			want: []match{
+				{"example.com", MatchTrailingWildcard, "example.*", 0},
+				{"nginx.conf", MatchRegex, `~^(www\.)?(example|bar)\.`, 1},
+				{"foo.conf", MatchDefault, "", 2},
+				{"default", MatchDefault, "", 2},
+			},
+		},
+		{
+			domain: "unknown.net",
+			want: []match{
+				{"foo.conf", MatchDefault, "", 0},
+				{"default", MatchDefault, "", 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			var got []match
+			for _, m := range cfg.MatchServers(tt.domain) {
+				if m.Server.Name != "server" {
+					t.Errorf("MatchServers() returned a %s directive", m.Server.Name)
+				}
+				got = append(got, match{filepath.Base(m.Server.File), m.Type, m.Name, m.Rank})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchServers() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchServers_Regex(t *testing.T) {
+	dirs, err := Parse("", []byte(`http {
+    server {
+        server_name ~^www\d+\.example\.com$ "~^(?!www)(.+)\.example\.com$";
+    }
+    server {
+        server_name ~^www\d+\.;
+    }
+    server {
+        server_name www.*.example.com;
+        listen 80;
+    }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	servers, err := NewRoot(toDirectiveSlice(dirs)).Select("http/server")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first matching regex wins, even if a later one is more specific
+	matches := MatchServers(servers, "www1.example.com")
+	if len(matches) != 2 || matches[0].Server != servers[0] || matches[1].Server != servers[1] || matches[1].Rank != 1 {
+		t.Errorf("MatchServers() got = %+v", matches)
+	}
+	if matches[0].Name != `~^www\d+\.example\.com$` {
+		t.Errorf("MatchServers() matched name %s", matches[0].Name)
+	}
+
+	// asterisks in the middle of a name never match
+	if matches := MatchServers(servers, "www.a.example.com"); len(matches) != 0 {
+		t.Errorf("MatchServers() got = %+v, want no matches", matches)
+	}
+}
@@ -0,0 +1,422 @@
+package storage
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/eggsampler/certgot/log"
+)
+
+const (
+	accountsDirName = "accounts"
+
+	regrFileName       = "regr.json"
+	privateKeyFileName = "private_key.json"
+	metaFileName       = "meta.json"
+
+	// creationDTFormat is the format certbot writes the account creation time in, always utc
+	creationDTFormat = "2006-01-02T15:04:05Z"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+// Account is an acme account saved in a certbot compatible config directory, ie
+//
+//	accounts/<server host and path>/<id>/{regr,private_key,meta}.json
+type Account struct {
+	// ID is the md5 hash of the public key, as used in the account directory name
+	ID string
+
+	// Server is the acme directory url the account is registered with
+	Server string
+
+	// URI is the account url returned by the acme server
+	URI string
+
+	// Key is the account private key
+	Key crypto.Signer
+
+	// CreationDT and CreationHost are when and where the account was registered
+	CreationDT   time.Time
+	CreationHost string
+
+	// RegisterToEFF is the email address shared with the EFF, if any
+	RegisterToEFF string
+}
+
+// NewAccount returns a new account for the key registered with the server at the account uri, to be saved
+func NewAccount(server, uri string, key crypto.Signer) (*Account, error) {
+	id, err := accountID(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	return &Account{
+		ID:           id,
+		Server:       server,
+		URI:          uri,
+		Key:          key,
+		CreationDT:   time.Now().UTC().Truncate(time.Second),
+		CreationHost: host,
+	}, nil
+}
+
+// accountID returns the id certbot uses for an account, being the hex md5 of the pem encoded public key
+// certbot checks the id matches the key when loading an account, so it must be hashed exactly the same way.
+func accountID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", fmt.Errorf("error encoding account public key: %v", err)
+	}
+	sum := md5.Sum(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AccountsDir returns the directory holding the accounts for the server, named after the host and path of the
+// server url like certbot, eg accounts/acme-v02.api.letsencrypt.org/directory
+func (s *Storage) AccountsDir(server string) (string, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("invalid server url %q: %v", server, err)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid server url %q: no host", server)
+	}
+	return filepath.Join(s.ConfigDir, accountsDirName, u.Host, filepath.FromSlash(u.Path)), nil
+}
+
+// AccountDir returns the directory holding the files for an account
+func (s *Storage) AccountDir(server, id string) (string, error) {
+	dir, err := s.AccountsDir(server)
+	if err != nil {
+		return "", err
+	}
+	if err := checkName(id); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id), nil
+}
+
+// AccountIDs returns the ids of all the accounts saved for the server, sorted
+func (s *Storage) AccountIDs(server string) ([]string, error) {
+	dir, err := s.AccountsDir(server)
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading accounts directory %s: %v", dir, err)
+	}
+	var ids []string
+	for _, f := range files {
+		if f.IsDir() {
+			ids = append(ids, f.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Accounts loads all the accounts for the server, any that fail to load are logged and skipped
+func (s *Storage) Accounts(server string) ([]*Account, error) {
+	ids, err := s.AccountIDs(server)
+	if err != nil {
+		return nil, err
+	}
+	var accounts []*Account
+	for _, id := range ids {
+		acct, err := s.Account(server, id)
+		if err != nil {
+			log.WithError(err).WithFields("server", server, "id", id).Error("skipping account")
+			continue
+		}
+		accounts = append(accounts, acct)
+	}
+	return accounts, nil
+}
+
+// Account loads the account with the id for the server, returning ErrAccountNotFound if it doesn't exist
+func (s *Storage) Account(server, id string) (*Account, error) {
+	dir, err := s.AccountDir(server, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+	}
+
+	acct := &Account{
+		ID:     id,
+		Server: server,
+	}
+
+	var regr struct {
+		URI string `json:"uri"`
+	}
+	if err := readJSON(filepath.Join(dir, regrFileName), &regr); err != nil {
+		return nil, err
+	}
+	acct.URI = regr.URI
+
+	var meta struct {
+		CreationDT    string      `json:"creation_dt"`
+		CreationHost  string      `json:"creation_host"`
+		RegisterToEFF interface{} `json:"register_to_eff"`
+	}
+	if err := readJSON(filepath.Join(dir, metaFileName), &meta); err != nil {
+		return nil, err
+	}
+	if meta.CreationDT != "" {
+		acct.CreationDT, err = time.Parse(time.RFC3339, meta.CreationDT)
+		if err != nil {
+			return nil, fmt.Errorf("invalid creation_dt in account %s: %v", id, err)
+		}
+	}
+	acct.CreationHost = meta.CreationHost
+	if eff, ok := meta.RegisterToEFF.(string); ok {
+		acct.RegisterToEFF = eff
+	}
+
+	var jwk privateJWK
+	if err := readJSON(filepath.Join(dir, privateKeyFileName), &jwk); err != nil {
+		return nil, err
+	}
+	acct.Key, err = jwk.key()
+	if err != nil {
+		return nil, fmt.Errorf("error loading private key for account %s: %v", id, err)
+	}
+
+	return acct, nil
+}
+
+// SaveAccount writes the account files, the private key is only written if it doesn't exist yet as it never changes
+func (s *Storage) SaveAccount(acct *Account) error {
+	dir, err := s.AccountDir(acct.Server, acct.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error making account directory %s: %v", dir, err)
+	}
+
+	keyPath := filepath.Join(dir, privateKeyFileName)
+	if _, err := os.Stat(keyPath); errors.Is(err, os.ErrNotExist) {
+		key, err := marshalPrivateJWK(acct.Key)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(keyPath, key, 0400); err != nil {
+			return fmt.Errorf("error writing account private key: %v", err)
+		}
+	}
+
+	// certbot only stores the uri in the registration resource, the body is fetched from the server when needed
+	var regr bytes.Buffer
+	writeJSONObject(&regr, "body", "{}", "uri", jsonString(acct.URI))
+	if err := ioutil.WriteFile(filepath.Join(dir, regrFileName), regr.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing account registration: %v", err)
+	}
+
+	var meta bytes.Buffer
+	fields := []string{
+		"creation_dt", jsonString(acct.CreationDT.UTC().Format(creationDTFormat)),
+		"creation_host", jsonString(acct.CreationHost),
+	}
+	if acct.RegisterToEFF != "" {
+		fields = append(fields, "register_to_eff", jsonString(acct.RegisterToEFF))
+	}
+	writeJSONObject(&meta, fields...)
+	if err := ioutil.WriteFile(filepath.Join(dir, metaFileName), meta.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing account meta: %v", err)
+	}
+
+	log.WithFields("server", acct.Server, "id", acct.ID, "path", dir).Debug("saved account")
+	return nil
+}
+
+// DeleteAccount removes the account files, as done by certbot when deactivating an account
+func (s *Storage) DeleteAccount(acct *Account) error {
+	dir, err := s.AccountDir(acct.Server, acct.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error deleting account directory %s: %v", dir, err)
+	}
+	log.WithFields("server", acct.Server, "id", acct.ID, "path", dir).Debug("deleted account")
+	return nil
+}
+
+func readJSON(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("error decoding %s: %v", path, err)
+	}
+	return nil
+}
+
+// writeJSONObject writes the keys and already encoded values in order, with the same separators as python's
+// json.dumps so the files are identical to those written by certbot
+func writeJSONObject(b *bytes.Buffer, keyValues ...string) {
+	b.WriteString("{")
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(b, "%s: %s", jsonString(keyValues[i]), keyValues[i+1])
+	}
+	b.WriteString("}")
+}
+
+func jsonString(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return string(bytes.TrimSpace(b.Bytes()))
+}
+
+// privateJWK is a json web key including the private key parts, as written by certbot (josepy)
+type privateJWK struct {
+	Kty string `json:"kty"`
+
+	// rsa
+	N  string `json:"n"`
+	E  string `json:"e"`
+	D  string `json:"d"`
+	P  string `json:"p"`
+	Q  string `json:"q"`
+	DP string `json:"dp"`
+	DQ string `json:"dq"`
+	QI string `json:"qi"`
+
+	// ecdsa, also uses D
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (jwk privateJWK) key() (crypto.Signer, error) {
+	var err error
+	decode := func(name, value string) *big.Int {
+		if err != nil {
+			return nil
+		}
+		b, decodeErr := base64.RawURLEncoding.DecodeString(value)
+		if decodeErr != nil || len(b) == 0 {
+			err = fmt.Errorf("invalid or missing %q parameter", name)
+			return nil
+		}
+		return new(big.Int).SetBytes(b)
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, e, d := decode("n", jwk.N), decode("e", jwk.E), decode("d", jwk.D)
+		p, q := decode("p", jwk.P), decode("q", jwk.Q)
+		if err != nil {
+			return nil, err
+		}
+		key := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p, q},
+		}
+		if err := key.Validate(); err != nil {
+			return nil, err
+		}
+		key.Precompute()
+		return key, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, y, d := decode("x", jwk.X), decode("y", jwk.Y), decode("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("public key is not on the curve")
+		}
+		return &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+			D:         d,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+// marshalPrivateJWK encodes the key as a json web key with the members in the same order as certbot (josepy)
+func marshalPrivateJWK(key crypto.Signer) ([]byte, error) {
+	b64 := func(i *big.Int) string {
+		return jsonString(base64.RawURLEncoding.EncodeToString(i.Bytes()))
+	}
+	var b bytes.Buffer
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if len(k.Primes) != 2 {
+			return nil, errors.New("unsupported multi-prime rsa key")
+		}
+		k.Precompute()
+		writeJSONObject(&b,
+			"n", b64(k.N),
+			"e", b64(big.NewInt(int64(k.E))),
+			"d", b64(k.D),
+			"p", b64(k.Primes[0]),
+			"q", b64(k.Primes[1]),
+			"dp", b64(k.Precomputed.Dp),
+			"dq", b64(k.Precomputed.Dq),
+			"qi", b64(k.Precomputed.Qinv),
+			"kty", jsonString("RSA"))
+	case *ecdsa.PrivateKey:
+		// ec parameters are padded to the curve size
+		size := (k.Curve.Params().BitSize + 7) / 8
+		pad := func(i *big.Int) string {
+			return jsonString(base64.RawURLEncoding.EncodeToString(i.FillBytes(make([]byte, size))))
+		}
+		writeJSONObject(&b,
+			"d", pad(k.D),
+			"x", pad(k.X),
+			"y", pad(k.Y),
+			"crv", jsonString(k.Curve.Params().Name),
+			"kty", jsonString("EC"))
+	default:
+		return nil, fmt.Errorf("unsupported account key type: %T", key)
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testServer = "https://acme-v02.api.letsencrypt.org/directory"
+
+func TestStorage_AccountsDir(t *testing.T) {
+	s := New("/etc/letsencrypt")
+	tests := []struct {
+		server  string
+		want    string
+		wantErr bool
+	}{
+		{
+			server: testServer,
+			want:   "/etc/letsencrypt/accounts/acme-v02.api.letsencrypt.org/directory",
+		},
+		{
+			server: "https://localhost:14000/dir",
+			want:   "/etc/letsencrypt/accounts/localhost:14000/dir",
+		},
+		{
+			server:  "not a url",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.server, func(t *testing.T) {
+			got, err := s.AccountsDir(tt.server)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AccountsDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("AccountsDir() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage_SaveAccount(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []crypto.Signer{rsaKey, ecKey} {
+		t.Run(fmt.Sprintf("%T", key), func(t *testing.T) {
+			s := tempStorage(t)
+			acct, err := NewAccount(testServer, "https://example.com/acct/1", key)
+			if err != nil {
+				t.Fatalf("NewAccount() error = %v", err)
+			}
+			acct.CreationDT = time.Date(2021, 5, 6, 1, 2, 3, 0, time.UTC)
+			acct.CreationHost = "host.example.com"
+			if err := s.SaveAccount(acct); err != nil {
+				t.Fatalf("SaveAccount() error = %v", err)
+			}
+
+			dir := filepath.Join(s.ConfigDir, "accounts", "acme-v02.api.letsencrypt.org", "directory", acct.ID)
+			wantFiles := map[string]string{
+				"regr.json": `{"body": {}, "uri": "https://example.com/acct/1"}`,
+				"meta.json": `{"creation_dt": "2021-05-06T01:02:03Z", "creation_host": "host.example.com"}`,
+			}
+			for name, want := range wantFiles {
+				b, err := ioutil.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != want {
+					t.Errorf("%s contents = %s, want %s", name, string(b), want)
+				}
+			}
+			fi, err := os.Stat(filepath.Join(dir, "private_key.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Mode().Perm() != 0400 {
+				t.Errorf("private_key.json mode = %v, want 0400", fi.Mode().Perm())
+			}
+
+			ids, err := s.AccountIDs(testServer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{acct.ID}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("AccountIDs() got = %v, want %v", ids, want)
+			}
+
+			loaded, err := s.Account(testServer, acct.ID)
+			if err != nil {
+				t.Fatalf("Account() error = %v", err)
+			}
+			if !reflect.DeepEqual(loaded.Key.Public(), key.Public()) {
+				t.Errorf("Account() key does not match")
+			}
+			loaded.Key = acct.Key
+			if !reflect.DeepEqual(loaded, acct) {
+				t.Errorf("Account() got = %+v, want %+v", loaded, acct)
+			}
+
+			// saving again must not fail on the read only private key
+			acct.URI = "https://example.com/acct/2"
+			if err := s.SaveAccount(acct); err != nil {
+				t.Fatalf("SaveAccount() error = %v", err)
+			}
+
+			if err := s.DeleteAccount(acct); err != nil {
+				t.Fatalf("DeleteAccount() error = %v", err)
+			}
+			if _, err := s.Account(testServer, acct.ID); !errors.Is(err, ErrAccountNotFound) {
+				t.Errorf("Account() error = %v, want ErrAccountNotFound", err)
+			}
+		})
+	}
+}
+
+func TestStorage_Account_Certbot(t *testing.T) {
+	// accounts as written by certbot, in directories named after the md5 of the pem encoded public key
+	s := New("testdata")
+	accounts, err := s.Accounts(testServer)
+	if err != nil {
+		t.Fatalf("Accounts() error = %v", err)
+	}
+	want := []struct {
+		id            string
+		uri           string
+		creationDT    time.Time
+		registerToEFF string
+	}{
+		{
+			id:         "15e7020cff76be87d1adaafa291f3d36",
+			uri:        "https://acme-v02.api.letsencrypt.org/acme/acct/12345",
+			creationDT: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			id:            "6f5b32d56ee1c4231f8752f080baa2a7",
+			uri:           "https://acme-v02.api.letsencrypt.org/acme/acct/67890",
+			creationDT:    time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC),
+			registerToEFF: "user@example.com",
+		},
+	}
+	if len(accounts) != len(want) {
+		t.Fatalf("Accounts() got %d accounts, want %d", len(accounts), len(want))
+	}
+	for i, w := range want {
+		acct := accounts[i]
+		if acct.ID != w.id || acct.URI != w.uri || !acct.CreationDT.Equal(w.creationDT) || acct.RegisterToEFF != w.registerToEFF {
+			t.Errorf("Account() got %+v, want %+v", acct, w)
+		}
+		// certbot refuses to load an account whose id doesn't match its key
+		id, err := accountID(acct.Key.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != w.id {
+			t.Errorf("accountID() got = %v, want %v", id, w.id)
+		}
+	}
+}
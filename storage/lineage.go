@@ -0,0 +1,372 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+	"github.com/eggsampler/certgot/util"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	// ConfigVersion is the certbot version written to renewal config files, matching the format being written
+	ConfigVersion = "1.14.0"
+
+	KindCert      = "cert"
+	KindPrivKey   = "privkey"
+	KindChain     = "chain"
+	KindFullChain = "fullchain"
+
+	renewalParamsSection = "renewalparams"
+)
+
+var (
+	// Kinds are the kinds of files stored for each version of a lineage
+	Kinds = []string{KindCert, KindPrivKey, KindChain, KindFullChain}
+
+	archiveFileRegex = regexp.MustCompile(`^(cert|privkey|chain|fullchain)(\d+)\.pem$`)
+)
+
+// Lineage is a certificate and all the versions of it, along with the settings used to obtain it
+type Lineage struct {
+	// Name is the certificate name, as used in file paths
+	Name string
+
+	// ConfigPath is the path to the renewal config file
+	ConfigPath string
+
+	// Version is the version of certbot that last wrote the renewal config file
+	Version string
+
+	// ArchiveDir is the directory holding every version of the files
+	ArchiveDir string
+
+	// CertPath, PrivKeyPath, ChainPath and FullChainPath are the symlinks in the live directory
+	// to the current version of each file
+	CertPath      string
+	PrivKeyPath   string
+	ChainPath     string
+	FullChainPath string
+
+	// RenewBeforeExpiry is how long before expiry the certificate should be renewed, eg "30 days"
+	// Empty means the default is used
+	RenewBeforeExpiry string
+
+	// RenewalParams are the options used to obtain the certificate, to be used again when renewing
+	RenewalParams map[string]string
+
+	// Subsections are nested sections of the renewal params, eg the webroot_map
+	Subsections map[string]map[string]string
+}
+
+func loadLineage(name, path string) (*Lineage, error) {
+	f, err := ini.LoadSources(ini.LoadOptions{IgnoreInlineComment: true}, path)
+	if err != nil {
+		// ini errors can end in a newline
+		return nil, fmt.Errorf("error loading renewal config %s: %s", path, strings.TrimSpace(err.Error()))
+	}
+	top := f.Section("")
+	// checked before reading any keys, as reading a key creates it
+	for _, kind := range Kinds {
+		if !top.HasKey(kind) {
+			return nil, fmt.Errorf("renewal config %s is missing required key %q", path, kind)
+		}
+	}
+	l := &Lineage{
+		Name:              name,
+		ConfigPath:        path,
+		Version:           top.Key("version").String(),
+		ArchiveDir:        top.Key("archive_dir").String(),
+		CertPath:          top.Key(KindCert).String(),
+		PrivKeyPath:       top.Key(KindPrivKey).String(),
+		ChainPath:         top.Key(KindChain).String(),
+		FullChainPath:     top.Key(KindFullChain).String(),
+		RenewBeforeExpiry: top.Key("renew_before_expiry").String(),
+		RenewalParams:     map[string]string{},
+		Subsections:       map[string]map[string]string{},
+	}
+	if l.ArchiveDir == "" {
+		// older certbot versions didn't store the archive dir, it's always relative to the live dir
+		l.ArchiveDir = filepath.Join(filepath.Dir(filepath.Dir(l.LiveDir())), archiveDirName, name)
+	}
+
+	for _, section := range f.Sections() {
+		switch {
+		case section.Name() == renewalParamsSection:
+			l.RenewalParams = section.KeysHash()
+		case strings.HasPrefix(section.Name(), "[") && strings.HasSuffix(section.Name(), "]"):
+			// nested sections are written like [[webroot_map]], which ini parses as a section named [webroot_map]
+			l.Subsections[strings.Trim(section.Name(), "[]")] = section.KeysHash()
+		}
+	}
+
+	return l, nil
+}
+
+// LiveDir returns the directory holding the symlinks to the current version
+func (l *Lineage) LiveDir() string {
+	return filepath.Dir(l.CertPath)
+}
+
+// Path returns the live path for the kind of file
+func (l *Lineage) Path(kind string) string {
+	switch kind {
+	case KindCert:
+		return l.CertPath
+	case KindPrivKey:
+		return l.PrivKeyPath
+	case KindChain:
+		return l.ChainPath
+	case KindFullChain:
+		return l.FullChainPath
+	}
+	return ""
+}
+
+// archivePath returns the path to a version of a kind of file in the archive
+func (l *Lineage) archivePath(kind string, version int) string {
+	return filepath.Join(l.ArchiveDir, kind+strconv.Itoa(version)+".pem")
+}
+
+// RenewalParam returns the value of the renewal param, or an empty string if not set
+func (l *Lineage) RenewalParam(key string) string {
+	return l.RenewalParams[key]
+}
+
+// SetRenewalParam sets a renewal param, removing it if the value is empty
+func (l *Lineage) SetRenewalParam(key, value string) {
+	if value == "" {
+		delete(l.RenewalParams, key)
+		return
+	}
+	l.RenewalParams[key] = value
+}
+
+// SetSubsection replaces a nested section of the renewal params, removing it if values is empty
+func (l *Lineage) SetSubsection(name string, values map[string]string) {
+	if len(values) == 0 {
+		delete(l.Subsections, name)
+		return
+	}
+	l.Subsections[name] = values
+}
+
+// Certificate reads the current certificate
+func (l *Lineage) Certificate() (*x509.Certificate, error) {
+	return util.ReadCertificate(l.CertPath)
+}
+
+// Names returns the names on the current certificate, the common name first if it is one of the names
+func (l *Lineage) Names() ([]string, error) {
+	cert, err := l.Certificate()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, n := range cert.DNSNames {
+		if strings.EqualFold(n, cert.Subject.CommonName) {
+			names = append([]string{n}, names...)
+		} else {
+			names = append(names, n)
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names, nil
+}
+
+// Versions returns all the versions of the kind of file in the archive, sorted
+func (l *Lineage) Versions(kind string) ([]int, error) {
+	files, err := ioutil.ReadDir(l.ArchiveDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive directory %s: %v", l.ArchiveDir, err)
+	}
+	var versions []int
+	for _, f := range files {
+		m := archiveFileRegex.FindStringSubmatch(f.Name())
+		if m == nil || m[1] != kind {
+			continue
+		}
+		v, _ := strconv.Atoi(m[2])
+		versions = append(versions, v)
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// LatestVersion returns the highest version of certificate in the archive
+func (l *Lineage) LatestVersion() (int, error) {
+	versions, err := l.Versions(KindCert)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, fmt.Errorf("no certificates in archive directory %s", l.ArchiveDir)
+	}
+	return versions[len(versions)-1], nil
+}
+
+// CurrentVersion returns the version the live symlink of the kind points to
+func (l *Lineage) CurrentVersion(kind string) (int, error) {
+	p := l.Path(kind)
+	target, err := os.Readlink(p)
+	if err != nil {
+		return 0, fmt.Errorf("error reading symlink %s: %v", p, err)
+	}
+	m := archiveFileRegex.FindStringSubmatch(filepath.Base(target))
+	if m == nil || m[1] != kind {
+		return 0, fmt.Errorf("symlink %s points to unexpected file %s", p, target)
+	}
+	v, _ := strconv.Atoi(m[2])
+	return v, nil
+}
+
+// SaveVersion saves a new version of the certificate, chain and key (all pem encoded) and points the live symlinks
+// to it, returning the new version
+// If key is nil, the current key is reused
+func (l *Lineage) SaveVersion(cert, chain, key []byte) (int, error) {
+	latest, err := l.LatestVersion()
+	if err != nil {
+		return 0, err
+	}
+	if key == nil {
+		if key, err = ioutil.ReadFile(l.PrivKeyPath); err != nil {
+			return 0, fmt.Errorf("error reading current private key: %v", err)
+		}
+	}
+	return l.writeVersion(latest+1, cert, chain, key)
+}
+
+func (l *Lineage) writeVersion(version int, cert, chain, key []byte) (int, error) {
+	contents := map[string][]byte{
+		KindCert:      cert,
+		KindPrivKey:   key,
+		KindChain:     chain,
+		KindFullChain: append(append([]byte{}, cert...), chain...),
+	}
+	for _, kind := range Kinds {
+		p := l.archivePath(kind, version)
+		mode := os.FileMode(0644)
+		if kind == KindPrivKey {
+			mode = 0600
+		}
+		log.WithFields("path", p, "mode", mode).Trace("writing archive file")
+		// O_EXCL so an existing version is never overwritten
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+		if err != nil {
+			return 0, fmt.Errorf("error creating %s: %v", p, err)
+		}
+		_, err = f.Write(contents[kind])
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error writing %s: %v", p, err)
+		}
+	}
+	if err := l.UpdateLinks(version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// UpdateLinks points all the live symlinks to the version in the archive, using relative paths
+func (l *Lineage) UpdateLinks(version int) error {
+	for _, kind := range Kinds {
+		link := l.Path(kind)
+		target, err := filepath.Rel(filepath.Dir(link), l.archivePath(kind, version))
+		if err != nil {
+			return fmt.Errorf("error finding relative path to archive: %v", err)
+		}
+		// create the new link alongside and rename it over the old one, so the live file always exists
+		tmp := link + ".new"
+		if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing %s: %v", tmp, err)
+		}
+		if err := os.Symlink(target, tmp); err != nil {
+			return fmt.Errorf("error creating symlink %s: %v", tmp, err)
+		}
+		if err := os.Rename(tmp, link); err != nil {
+			return fmt.Errorf("error renaming symlink %s: %v", tmp, err)
+		}
+		log.WithFields("link", link, "target", target).Trace("updated symlink")
+	}
+	return nil
+}
+
+// Save writes the renewal config file in the same format as certbot
+func (l *Lineage) Save() error {
+	var b bytes.Buffer
+	if l.RenewBeforeExpiry == "" {
+		b.WriteString("# renew_before_expiry = 30 days\n")
+	} else {
+		fmt.Fprintf(&b, "renew_before_expiry = %s\n", l.RenewBeforeExpiry)
+	}
+	version := l.Version
+	if version == "" {
+		version = ConfigVersion
+	}
+	fmt.Fprintf(&b, "version = %s\n", version)
+	fmt.Fprintf(&b, "archive_dir = %s\n", l.ArchiveDir)
+	for _, kind := range Kinds {
+		fmt.Fprintf(&b, "%s = %s\n", kind, l.Path(kind))
+	}
+
+	b.WriteString("\n# Options used in the renewal process\n")
+	fmt.Fprintf(&b, "[%s]\n", renewalParamsSection)
+	writeSorted(&b, l.RenewalParams)
+
+	var subsections []string
+	for name := range l.Subsections {
+		subsections = append(subsections, name)
+	}
+	sort.Strings(subsections)
+	for _, name := range subsections {
+		fmt.Fprintf(&b, "[[%s]]\n", name)
+		writeSorted(&b, l.Subsections[name])
+	}
+
+	dir := filepath.Dir(l.ConfigPath)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(l.ConfigPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating renewal config file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(b.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing renewal config file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("error setting permissions on renewal config file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), l.ConfigPath); err != nil {
+		return fmt.Errorf("error saving renewal config file %s: %v", l.ConfigPath, err)
+	}
+	log.WithField("path", l.ConfigPath).Debug("saved renewal config")
+	return nil
+}
+
+func writeSorted(b *bytes.Buffer, values map[string]string) {
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s = %s\n", k, values[k])
+	}
+}
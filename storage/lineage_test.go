@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// certbotConf is a renewal config file as written by certbot
+const certbotConf = `# renew_before_expiry = 30 days
+version = 1.14.0
+archive_dir = /etc/letsencrypt/archive/example.com
+cert = /etc/letsencrypt/live/example.com/cert.pem
+privkey = /etc/letsencrypt/live/example.com/privkey.pem
+chain = /etc/letsencrypt/live/example.com/chain.pem
+fullchain = /etc/letsencrypt/live/example.com/fullchain.pem
+
+# Options used in the renewal process
+[renewalparams]
+account = 0123456789abcdef0123456789abcdef
+authenticator = webroot
+manual_auth_hook = echo "#1" > /tmp/hook
+server = https://acme-v02.api.letsencrypt.org/directory
+webroot_path = /var/www/example,
+[[webroot_map]]
+example.com = /var/www/example
+www.example.com = /var/www/example
+`
+
+func Test_loadLineage(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    string
+		want    *Lineage
+		wantErr bool
+	}{
+		{
+			name: "certbot",
+			conf: certbotConf,
+			want: &Lineage{
+				Name:          "example.com",
+				Version:       "1.14.0",
+				ArchiveDir:    "/etc/letsencrypt/archive/example.com",
+				CertPath:      "/etc/letsencrypt/live/example.com/cert.pem",
+				PrivKeyPath:   "/etc/letsencrypt/live/example.com/privkey.pem",
+				ChainPath:     "/etc/letsencrypt/live/example.com/chain.pem",
+				FullChainPath: "/etc/letsencrypt/live/example.com/fullchain.pem",
+				RenewalParams: map[string]string{
+					"account":          "0123456789abcdef0123456789abcdef",
+					"authenticator":    "webroot",
+					"manual_auth_hook": `echo "#1" > /tmp/hook`,
+					"server":           "https://acme-v02.api.letsencrypt.org/directory",
+					"webroot_path":     "/var/www/example,",
+				},
+				Subsections: map[string]map[string]string{
+					"webroot_map": {
+						"example.com":     "/var/www/example",
+						"www.example.com": "/var/www/example",
+					},
+				},
+			},
+		},
+		{
+			name: "renew before expiry",
+			conf: "renew_before_expiry = 10 days\n" +
+				"cert = /live/example.com/cert.pem\n" +
+				"privkey = /live/example.com/privkey.pem\n" +
+				"chain = /live/example.com/chain.pem\n" +
+				"fullchain = /live/example.com/fullchain.pem\n",
+			want: &Lineage{
+				Name:              "example.com",
+				ArchiveDir:        filepath.Join("/", "archive", "example.com"),
+				CertPath:          "/live/example.com/cert.pem",
+				PrivKeyPath:       "/live/example.com/privkey.pem",
+				ChainPath:         "/live/example.com/chain.pem",
+				FullChainPath:     "/live/example.com/fullchain.pem",
+				RenewBeforeExpiry: "10 days",
+				RenewalParams:     map[string]string{},
+				Subsections:       map[string]map[string]string{},
+			},
+		},
+		{
+			name:    "missing key",
+			conf:    "cert = /live/example.com/cert.pem\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tempStorage(t)
+			p := filepath.Join(s.ConfigDir, "example.com.conf")
+			if err := ioutil.WriteFile(p, []byte(tt.conf), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadLineage("example.com", p)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadLineage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			tt.want.ConfigPath = p
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadLineage() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineage_Save(t *testing.T) {
+	s := tempStorage(t)
+	p := filepath.Join(s.ConfigDir, "example.com.conf")
+	if err := ioutil.WriteFile(p, []byte(certbotConf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := loadLineage("example.com", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != certbotConf {
+		t.Errorf("Save() got:\n%s\nwant:\n%s", string(b), certbotConf)
+	}
+}
+
+func TestLineage_SaveVersion(t *testing.T) {
+	s := tempStorage(t)
+	l, err := s.NewLineage("example.com", []byte("cert1"), []byte("chain1"), []byte("key1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		key         []byte
+		wantVersion int
+		wantKey     string
+	}{
+		{
+			name:        "new key",
+			key:         []byte("key2"),
+			wantVersion: 2,
+			wantKey:     "key2",
+		},
+		{
+			name:        "reuse key",
+			wantVersion: 3,
+			wantKey:     "key2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.SaveVersion([]byte("cert"), []byte("chain"), tt.key)
+			if err != nil {
+				t.Fatalf("SaveVersion() error = %v", err)
+			}
+			if got != tt.wantVersion {
+				t.Errorf("SaveVersion() got = %v, want %v", got, tt.wantVersion)
+			}
+			for _, kind := range Kinds {
+				current, err := l.CurrentVersion(kind)
+				if err != nil {
+					t.Fatalf("CurrentVersion() error = %v", err)
+				}
+				if current != tt.wantVersion {
+					t.Errorf("CurrentVersion(%s) got = %v, want %v", kind, current, tt.wantVersion)
+				}
+			}
+			latest, err := l.LatestVersion()
+			if err != nil {
+				t.Fatalf("LatestVersion() error = %v", err)
+			}
+			if latest != tt.wantVersion {
+				t.Errorf("LatestVersion() got = %v, want %v", latest, tt.wantVersion)
+			}
+			key, err := ioutil.ReadFile(l.PrivKeyPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(key) != tt.wantKey {
+				t.Errorf("key got = %q, want %q", string(key), tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eggsampler/certgot/log"
+)
+
+const (
+	liveDirName    = "live"
+	archiveDirName = "archive"
+	renewalDirName = "renewal"
+
+	renewalConfExt = ".conf"
+
+	// maxNameSuffix is the maximum number appended to a lineage name to make it unique
+	maxNameSuffix = 9999
+)
+
+var (
+	ErrNotFound = errors.New("certificate not found")
+)
+
+// Storage manages certificate lineages in a certbot compatible config directory, ie
+//
+//	live/<name>/{cert,chain,fullchain,privkey}.pem -> ../../archive/<name>/{cert,chain,fullchain,privkey}N.pem
+//	renewal/<name>.conf
+type Storage struct {
+	ConfigDir string
+}
+
+// New returns storage for the provided config directory
+func New(configDir string) *Storage {
+	return &Storage{
+		ConfigDir: configDir,
+	}
+}
+
+func (s *Storage) LiveDir() string {
+	return filepath.Join(s.ConfigDir, liveDirName)
+}
+
+func (s *Storage) ArchiveDir() string {
+	return filepath.Join(s.ConfigDir, archiveDirName)
+}
+
+func (s *Storage) RenewalDir() string {
+	return filepath.Join(s.ConfigDir, renewalDirName)
+}
+
+// RenewalConfPath returns the path to the renewal config file for the lineage name
+func (s *Storage) RenewalConfPath(name string) string {
+	return filepath.Join(s.RenewalDir(), name+renewalConfExt)
+}
+
+// makeDirs makes the top level directories, the live and archive directories hold private keys so are only
+// accessible by the owner
+func (s *Storage) makeDirs() error {
+	dirs := []struct {
+		path string
+		mode os.FileMode
+	}{
+		{s.LiveDir(), 0700},
+		{s.ArchiveDir(), 0700},
+		{s.RenewalDir(), 0755},
+	}
+	for _, d := range dirs {
+		if err := os.MkdirAll(d.path, d.mode); err != nil {
+			return fmt.Errorf("error making directory %s: %v", d.path, err)
+		}
+	}
+	return nil
+}
+
+// exists returns whether any of the files or directories for a lineage name exist
+func (s *Storage) exists(name string) (bool, error) {
+	for _, p := range []string{s.RenewalConfPath(name), filepath.Join(s.LiveDir(), name), filepath.Join(s.ArchiveDir(), name)} {
+		_, err := os.Lstat(p)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("error checking %s: %v", p, err)
+		}
+	}
+	return false, nil
+}
+
+// UniqueName returns the name, or if it is already in use, the name with the first free number appended,
+// eg example.com-0001
+func (s *Storage) UniqueName(name string) (string, error) {
+	if err := checkName(name); err != nil {
+		return "", err
+	}
+	candidate := name
+	for i := 1; i <= maxNameSuffix; i++ {
+		exists, err := s.exists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%04d", name, i)
+	}
+	return "", fmt.Errorf("unable to find a free certificate name for %s", name)
+}
+
+// checkName makes sure the lineage name can be safely used as a file name
+func checkName(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("invalid certificate name %q", name)
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("certificate name %q contains a path separator", name)
+	}
+	return nil
+}
+
+// Names returns the names of all the lineages with a renewal config, sorted
+func (s *Storage) Names() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.RenewalDir(), "*"+renewalConfExt))
+	if err != nil {
+		return nil, fmt.Errorf("error finding renewal config files: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(f), renewalConfExt))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Lineage loads the lineage with the name, returning ErrNotFound if it doesn't exist
+func (s *Storage) Lineage(name string) (*Lineage, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+	p := s.RenewalConfPath(name)
+	if _, err := os.Stat(p); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return loadLineage(name, p)
+}
+
+// Lineages loads all the lineages, any that fail to load are logged and skipped
+func (s *Storage) Lineages() ([]*Lineage, error) {
+	names, err := s.Names()
+	if err != nil {
+		return nil, err
+	}
+	var lineages []*Lineage
+	for _, name := range names {
+		l, err := s.Lineage(name)
+		if err != nil {
+			log.WithError(err).WithField("name", name).Error("skipping certificate lineage")
+			continue
+		}
+		lineages = append(lineages, l)
+	}
+	return lineages, nil
+}
+
+// NewLineage creates a new lineage with the first version of the certificate, chain and key (all pem encoded)
+// The name is made unique if already in use, so the returned lineage name may differ
+func (s *Storage) NewLineage(name string, cert, chain, key []byte) (*Lineage, error) {
+	if err := s.makeDirs(); err != nil {
+		return nil, err
+	}
+	unique, err := s.UniqueName(name)
+	if err != nil {
+		return nil, err
+	}
+	if unique != name {
+		log.WithFields("name", name, "unique", unique).Debug("certificate name in use")
+	}
+
+	liveDir := filepath.Join(s.LiveDir(), unique)
+	l := &Lineage{
+		Name:          unique,
+		ConfigPath:    s.RenewalConfPath(unique),
+		Version:       ConfigVersion,
+		ArchiveDir:    filepath.Join(s.ArchiveDir(), unique),
+		CertPath:      filepath.Join(liveDir, KindCert+".pem"),
+		PrivKeyPath:   filepath.Join(liveDir, KindPrivKey+".pem"),
+		ChainPath:     filepath.Join(liveDir, KindChain+".pem"),
+		FullChainPath: filepath.Join(liveDir, KindFullChain+".pem"),
+		RenewalParams: map[string]string{},
+		Subsections:   map[string]map[string]string{},
+	}
+
+	for _, dir := range []string{l.ArchiveDir, liveDir} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return nil, fmt.Errorf("error making directory %s: %v", dir, err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(liveDir, "README"), []byte(lineageReadme), 0644); err != nil {
+		return nil, fmt.Errorf("error writing readme: %v", err)
+	}
+	readme := filepath.Join(s.LiveDir(), "README")
+	if _, err := os.Stat(readme); errors.Is(err, os.ErrNotExist) {
+		if err := ioutil.WriteFile(readme, []byte(liveReadme), 0644); err != nil {
+			return nil, fmt.Errorf("error writing readme: %v", err)
+		}
+	}
+
+	if _, err := l.writeVersion(1, cert, chain, key); err != nil {
+		return nil, err
+	}
+	if err := l.Save(); err != nil {
+		return nil, err
+	}
+
+	log.WithFields("name", l.Name, "archive", l.ArchiveDir, "live", liveDir).Debug("created certificate lineage")
+	return l, nil
+}
+
+// DeleteLineage removes the renewal config file, live and archive directories of the lineage
+func (s *Storage) DeleteLineage(l *Lineage) error {
+	for _, p := range []string{l.ConfigPath, l.LiveDir(), l.ArchiveDir} {
+		if err := os.RemoveAll(p); err != nil {
+			return fmt.Errorf("error deleting %s: %v", p, err)
+		}
+	}
+	log.WithFields("name", l.Name, "archive", l.ArchiveDir, "live", l.LiveDir()).Debug("deleted certificate lineage")
+	return nil
+}
+
+const (
+	liveReadme = "This directory contains your keys and certificates.\n" +
+		"\n" +
+		"`[cert name]/privkey.pem`  : the private key for your certificate.\n" +
+		"`[cert name]/fullchain.pem`: the certificate file used in most server software.\n" +
+		"`[cert name]/chain.pem`    : used for OCSP stapling in Nginx >=1.3.7.\n" +
+		"`[cert name]/cert.pem`     : will break many server configurations, and should not be used\n" +
+		"                 without reading further documentation (see link below).\n" +
+		"\n" +
+		"WARNING: DO NOT MOVE OR RENAME THESE FILES!\n" +
+		"         Certbot expects these files to remain in this location in order\n" +
+		"         to function properly!\n" +
+		"\n" +
+		"We recommend not moving these files. For more information, see the Certbot\n" +
+		"User Guide at https://certbot.eff.org/docs/using.html#where-are-my-certificates.\n"
+
+	lineageReadme = "This directory contains your keys and certificates.\n" +
+		"\n" +
+		"`privkey.pem`  : the private key for your certificate.\n" +
+		"`fullchain.pem`: the certificate file used in most server software.\n" +
+		"`chain.pem`    : used for OCSP stapling in Nginx >=1.3.7.\n" +
+		"`cert.pem`     : will break many server configurations, and should not be used\n" +
+		"                 without reading further documentation (see link below).\n" +
+		"\n" +
+		"WARNING: DO NOT MOVE OR RENAME THESE FILES!\n" +
+		"         Certbot expects these files to remain in this location in order\n" +
+		"         to function properly!\n" +
+		"\n" +
+		"We recommend not moving these files. For more information, see the Certbot\n" +
+		"User Guide at https://certbot.eff.org/docs/using.html#where-are-my-certificates.\n"
+)
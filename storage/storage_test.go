@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempStorage(t *testing.T) *Storage {
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return New(dir)
+}
+
+func TestStorage_UniqueName(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		certName string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "invalid",
+			certName: "../example.com",
+			wantErr:  true,
+		},
+		{
+			name:     "free",
+			certName: "example.com",
+			want:     "example.com",
+		},
+		{
+			name:     "renewal conf exists",
+			existing: []string{"renewal/example.com.conf"},
+			certName: "example.com",
+			want:     "example.com-0001",
+		},
+		{
+			name:     "archive exists",
+			existing: []string{"archive/example.com/"},
+			certName: "example.com",
+			want:     "example.com-0001",
+		},
+		{
+			name:     "live and first suffix exist",
+			existing: []string{"live/example.com/", "live/example.com-0001/", "renewal/example.com-0003.conf"},
+			certName: "example.com",
+			want:     "example.com-0002",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tempStorage(t)
+			if err := s.makeDirs(); err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range tt.existing {
+				p := filepath.Join(s.ConfigDir, e)
+				var err error
+				if e[len(e)-1] == '/' {
+					err = os.MkdirAll(p, 0755)
+				} else {
+					err = ioutil.WriteFile(p, nil, 0644)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := s.UniqueName(tt.certName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UniqueName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("UniqueName() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStorage_NewLineage(t *testing.T) {
+	s := tempStorage(t)
+
+	l, err := s.NewLineage("example.com", []byte("cert"), []byte("chain"), []byte("key"))
+	if err != nil {
+		t.Fatalf("NewLineage() error = %v", err)
+	}
+	if l.Name != "example.com" {
+		t.Errorf("NewLineage() name = %v, want example.com", l.Name)
+	}
+
+	wantFiles := map[string]string{
+		"cert":      "cert",
+		"chain":     "chain",
+		"fullchain": "certchain",
+		"privkey":   "key",
+	}
+	for kind, want := range wantFiles {
+		p := filepath.Join(s.ConfigDir, "live", "example.com", kind+".pem")
+		target, err := os.Readlink(p)
+		if err != nil {
+			t.Fatalf("error reading link %s: %v", p, err)
+		}
+		wantTarget := filepath.Join("..", "..", "archive", "example.com", kind+"1.pem")
+		if target != wantTarget {
+			t.Errorf("link %s target = %v, want %v", p, target, wantTarget)
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s contents = %q, want %q", kind, string(b), want)
+		}
+	}
+
+	fi, err := os.Stat(filepath.Join(s.ConfigDir, "archive", "example.com", "privkey1.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("privkey mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	for _, p := range []string{"live/README", "live/example.com/README"} {
+		if _, err := os.Stat(filepath.Join(s.ConfigDir, p)); err != nil {
+			t.Errorf("missing %s: %v", p, err)
+		}
+	}
+
+	// a second lineage with the same name gets a suffix
+	l2, err := s.NewLineage("example.com", []byte("cert2"), []byte("chain2"), []byte("key2"))
+	if err != nil {
+		t.Fatalf("NewLineage() error = %v", err)
+	}
+	if l2.Name != "example.com-0001" {
+		t.Errorf("NewLineage() name = %v, want example.com-0001", l2.Name)
+	}
+
+	names, err := s.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com", "example.com-0001"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Names() got = %v, want %v", names, want)
+	}
+
+	loaded, err := s.Lineage("example.com-0001")
+	if err != nil {
+		t.Fatalf("Lineage() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, l2) {
+		t.Errorf("Lineage() got = %+v, want %+v", loaded, l2)
+	}
+
+	if _, err := s.Lineage("missing.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lineage() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestStorage_DeleteLineage(t *testing.T) {
+	s := tempStorage(t)
+
+	l, err := s.NewLineage("example.com", []byte("cert"), []byte("chain"), []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.NewLineage("example.net", []byte("cert"), []byte("chain"), []byte("key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteLineage(l); err != nil {
+		t.Fatalf("DeleteLineage() error = %v", err)
+	}
+	for _, p := range []string{"renewal/example.com.conf", "live/example.com", "archive/example.com"} {
+		if _, err := os.Lstat(filepath.Join(s.ConfigDir, p)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %s to be deleted, got: %v", p, err)
+		}
+	}
+	if _, err := s.Lineage(other.Name); err != nil {
+		t.Errorf("Lineage() error = %v, other lineage should not be deleted", err)
+	}
+	if _, err := s.Lineage(l.Name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lineage() error = %v, want ErrNotFound", err)
+	}
+}
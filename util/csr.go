@@ -0,0 +1,122 @@
+package util
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+)
+
+var (
+	// OIDMustStaple is the tls feature extension, as per https://tools.ietf.org/html/rfc7633
+	OIDMustStaple = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+	// mustStapleValue is the der encoded tls feature sequence containing status_request(5)
+	mustStapleValue = []byte{0x30, 0x03, 0x02, 0x01, 0x05}
+)
+
+// CreateCSR creates a certificate signing request signed by key for the names, which may be dns names or ip
+// addresses. The first dns name is used as the common name if it's short enough to fit.
+// If mustStaple is set the OCSP Must-Staple extension is also requested.
+func CreateCSR(key crypto.Signer, names []string, mustStaple bool) (*x509.CertificateRequest, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no names provided for csr")
+	}
+	tmpl := &x509.CertificateRequest{}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+			continue
+		}
+		if tmpl.Subject.CommonName == "" && len(name) <= 64 {
+			tmpl.Subject = pkix.Name{CommonName: name}
+		}
+		tmpl.DNSNames = append(tmpl.DNSNames, name)
+	}
+	if mustStaple {
+		tmpl.ExtraExtensions = append(tmpl.ExtraExtensions, pkix.Extension{
+			Id:    OIDMustStaple,
+			Value: mustStapleValue,
+		})
+	}
+
+	csrDer, err := x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating csr: %v", err)
+	}
+	return x509.ParseCertificateRequest(csrDer)
+}
+
+// ReadCSR reads a pem or der encoded certificate signing request from a file and checks its signature
+func ReadCSR(path string) (*x509.CertificateRequest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading csr file %s: %v", path, err)
+	}
+	csr, err := ParseCSR(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading csr file %s: %v", path, err)
+	}
+	return csr, nil
+}
+
+// ParseCSR parses a pem or der encoded certificate signing request and checks its signature
+func ParseCSR(data []byte) (*x509.CertificateRequest, error) {
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST" {
+			return nil, fmt.Errorf("not a certificate request: %s", block.Type)
+		}
+		der = block.Bytes
+	} else if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----")) {
+		return nil, errors.New("invalid pem data")
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("invalid csr signature: %v", err)
+	}
+	return csr, nil
+}
+
+// CSRNames returns the dns names and ip addresses the csr requests, including the common name if it isn't also
+// present as a dns name
+func CSRNames(csr *x509.CertificateRequest) []string {
+	var names []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	add(strings.ToLower(csr.Subject.CommonName))
+	for _, name := range csr.DNSNames {
+		add(strings.ToLower(name))
+	}
+	for _, ip := range csr.IPAddresses {
+		add(ip.String())
+	}
+	return names
+}
+
+// HasMustStaple returns whether the csr requests the OCSP Must-Staple extension
+func HasMustStaple(csr *x509.CertificateRequest) bool {
+	for _, ext := range csr.Extensions {
+		if ext.Id.Equal(OIDMustStaple) {
+			return true
+		}
+	}
+	return false
+}
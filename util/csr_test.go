@@ -0,0 +1,106 @@
+package util
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"reflect"
+	"testing"
+)
+
+func TestCreateCSR(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		names      []string
+		mustStaple bool
+		wantCN     string
+		wantNames  []string
+		wantErr    bool
+	}{
+		{
+			name:    "no names",
+			wantErr: true,
+		},
+		{
+			name:      "dns",
+			names:     []string{"example.com", "www.example.com"},
+			wantCN:    "example.com",
+			wantNames: []string{"example.com", "www.example.com"},
+		},
+		{
+			name:       "ip and dns with must staple",
+			names:      []string{"192.0.2.1", "2001:db8::1", "Example.com"},
+			mustStaple: true,
+			wantCN:     "Example.com",
+			wantNames:  []string{"example.com", "192.0.2.1", "2001:db8::1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csr, err := CreateCSR(key, tt.names, tt.mustStaple)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateCSR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if csr.Subject.CommonName != tt.wantCN {
+				t.Errorf("CreateCSR() common name = %v, want %v", csr.Subject.CommonName, tt.wantCN)
+			}
+			if got := CSRNames(csr); !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("CSRNames() got = %v, want %v", got, tt.wantNames)
+			}
+			if got := HasMustStaple(csr); got != tt.mustStaple {
+				t.Errorf("HasMustStaple() got = %v, want %v", got, tt.mustStaple)
+			}
+
+			// both der and pem encodings must parse back to the same csr
+			pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})
+			for _, data := range [][]byte{csr.Raw, pemData} {
+				parsed, err := ParseCSR(data)
+				if err != nil {
+					t.Fatalf("ParseCSR() error = %v", err)
+				}
+				if !reflect.DeepEqual(parsed.Raw, csr.Raw) {
+					t.Errorf("ParseCSR() did not match created csr")
+				}
+			}
+		})
+	}
+}
+
+func TestParseCSR(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "not a csr",
+			data: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}}),
+		},
+		{
+			name: "bad pem",
+			data: []byte("-----BEGIN CERTIFICATE REQUEST-----\nnope\n"),
+		},
+		{
+			name: "bad der",
+			data: []byte{0x30, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseCSR(tt.data); err == nil {
+				t.Errorf("ParseCSR() expected error")
+			}
+		})
+	}
+}
@@ -14,3 +14,17 @@ func CheckUID(dir string, sys interface{}, uid int) error {
 	}
 	return nil
 }
+
+// CopyOwner changes the owner and group of path to match the owner and group in info
+func CopyOwner(path string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	return os.Lchown(path, int(stat.Uid), int(stat.Gid))
+}
+
+// UnsafePermissions returns whether the file is accessible by anyone other than the owner
+func UnsafePermissions(info os.FileInfo) bool {
+	return info.Mode().Perm()&0077 != 0
+}
@@ -1,7 +1,20 @@
 package util
 
-import "errors"
+import (
+	"errors"
+	"os"
+)
 
 func CheckUID(dir string, sys interface{}, uid int) error {
 	return errors.New("NOT YET IMPLEMENTED")
 }
+
+// CopyOwner is a no-op on windows, files inherit permissions from their parent directory
+func CopyOwner(path string, info os.FileInfo) error {
+	return nil
+}
+
+// UnsafePermissions always returns false on windows, as file modes do not reflect the file acl
+func UnsafePermissions(info os.FileInfo) bool {
+	return false
+}
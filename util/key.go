@@ -0,0 +1,138 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+const (
+	KeyTypeRSA   = "rsa"
+	KeyTypeECDSA = "ecdsa"
+
+	// MinRSAKeySize is the smallest rsa key size accepted by acme servers like Let's Encrypt
+	MinRSAKeySize = 2048
+)
+
+var (
+	// Curves are the elliptic curves that can be used for ecdsa keys, as named by certbot
+	Curves = map[string]elliptic.Curve{
+		"secp256r1": elliptic.P256(),
+		"secp384r1": elliptic.P384(),
+		"secp521r1": elliptic.P521(),
+	}
+)
+
+// CurveNames returns the names of the supported curves, sorted
+func CurveNames() []string {
+	var names []string
+	for name := range Curves {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateKey generates a new private key of the key type, using rsaKeySize for rsa keys and the named curve for
+// ecdsa keys
+func GenerateKey(keyType string, rsaKeySize int, curveName string) (crypto.Signer, error) {
+	switch keyType {
+	case KeyTypeRSA:
+		if rsaKeySize < MinRSAKeySize {
+			return nil, fmt.Errorf("rsa key size %d is too small, must be at least %d", rsaKeySize, MinRSAKeySize)
+		}
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("error generating rsa key: %v", err)
+		}
+		return key, nil
+	case KeyTypeECDSA:
+		curve, ok := Curves[curveName]
+		if !ok {
+			return nil, fmt.Errorf("unsupported elliptic curve %q", curveName)
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("error generating ecdsa key: %v", err)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", keyType)
+}
+
+// KeyType returns the key type of a private key
+func KeyType(key crypto.Signer) string {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return KeyTypeRSA
+	case *ecdsa.PrivateKey:
+		return KeyTypeECDSA
+	}
+	return ""
+}
+
+// EncodePrivateKey pem encodes a private key in the same format as certbot, ie pkcs#8 for rsa keys and the
+// traditional sec 1 format for ecdsa keys
+func EncodePrivateKey(key crypto.Signer) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding private key: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding private key: %v", err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	}
+	return nil, fmt.Errorf("unsupported private key type: %T", key)
+}
+
+// ReadPrivateKey reads a pem encoded private key, in either pkcs#8, pkcs#1 (rsa) or sec 1 (ecdsa) format
+func ReadPrivateKey(path string) (crypto.Signer, error) {
+	keyPem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading private key file %s: %v", path, err)
+	}
+	key, err := ParsePrivateKey(keyPem)
+	if err != nil {
+		return nil, fmt.Errorf("error reading private key file %s: %v", path, err)
+	}
+	return key, nil
+}
+
+// ParsePrivateKey parses a pem encoded private key, in either pkcs#8, pkcs#1 (rsa) or sec 1 (ecdsa) format
+func ParsePrivateKey(pemData []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no private key present")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type: %T", key)
+		}
+		return signer, nil
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("not a private key: %s", block.Type)
+}
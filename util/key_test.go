@@ -0,0 +1,172 @@
+package util
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+// equalKey is implemented by all the standard library private keys
+type equalKey interface {
+	Equal(x crypto.PrivateKey) bool
+}
+
+func TestGenerateKey(t *testing.T) {
+	type test struct {
+		name       string
+		keyType    string
+		rsaKeySize int
+		curveName  string
+		wantPEM    string
+		wantErr    bool
+	}
+	tests := []test{
+		{
+			name:       "rsa too small",
+			keyType:    KeyTypeRSA,
+			rsaKeySize: MinRSAKeySize - 1,
+			wantErr:    true,
+		},
+		{
+			name:       "rsa",
+			keyType:    KeyTypeRSA,
+			rsaKeySize: MinRSAKeySize,
+			wantPEM:    "PRIVATE KEY",
+		},
+		{
+			name:      "unsupported curve",
+			keyType:   KeyTypeECDSA,
+			curveName: "secp192r1",
+			wantErr:   true,
+		},
+		{
+			name:    "unsupported key type",
+			keyType: "dsa",
+			wantErr: true,
+		},
+	}
+	for _, name := range CurveNames() {
+		tests = append(tests, test{
+			name:      "ecdsa " + name,
+			keyType:   KeyTypeECDSA,
+			curveName: name,
+			wantPEM:   "EC PRIVATE KEY",
+		})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := GenerateKey(tt.keyType, tt.rsaKeySize, tt.curveName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := KeyType(key); got != tt.keyType {
+				t.Errorf("KeyType() got = %v, want %v", got, tt.keyType)
+			}
+			switch k := key.(type) {
+			case *rsa.PrivateKey:
+				if got := k.N.BitLen(); got != tt.rsaKeySize {
+					t.Errorf("GenerateKey() rsa key size = %d, want %d", got, tt.rsaKeySize)
+				}
+			case *ecdsa.PrivateKey:
+				if k.Curve != Curves[tt.curveName] {
+					t.Errorf("GenerateKey() curve = %s, want %s", k.Curve.Params().Name, tt.curveName)
+				}
+			}
+
+			// keys are written in the same format as certbot, and must read back as the same key
+			pemData, err := EncodePrivateKey(key)
+			if err != nil {
+				t.Fatalf("EncodePrivateKey() error = %v", err)
+			}
+			if block, _ := pem.Decode(pemData); block == nil || block.Type != tt.wantPEM {
+				t.Errorf("EncodePrivateKey() got = %s, want a %s block", pemData, tt.wantPEM)
+			}
+			parsed, err := ParsePrivateKey(pemData)
+			if err != nil {
+				t.Fatalf("ParsePrivateKey() error = %v", err)
+			}
+			if !key.(equalKey).Equal(parsed) {
+				t.Errorf("ParsePrivateKey() did not match generated key")
+			}
+		})
+	}
+}
+
+func TestEncodePrivateKey_Unsupported(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EncodePrivateKey(key); err == nil {
+		t.Errorf("EncodePrivateKey() expected error")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := GenerateKey(KeyTypeRSA, MinRSAKeySize, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := GenerateKey(KeyTypeECDSA, 0, "secp256r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    equalKey
+		wantErr bool
+	}{
+		{
+			name: "pkcs#1 rsa",
+			data: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey.(*rsa.PrivateKey))}),
+			want: rsaKey.(*rsa.PrivateKey),
+		},
+		{
+			name: "pkcs#8 ecdsa",
+			data: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecPKCS8}),
+			want: ecKey.(*ecdsa.PrivateKey),
+		},
+		{
+			name:    "empty",
+			wantErr: true,
+		},
+		{
+			name:    "not a private key",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte{1, 2, 3}}),
+			wantErr: true,
+		},
+		{
+			name:    "bad der",
+			data:    pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte{0x30, 0x01}}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePrivateKey(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePrivateKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !tt.want.Equal(got) {
+				t.Errorf("ParsePrivateKey() did not match the encoded key")
+			}
+		})
+	}
+}